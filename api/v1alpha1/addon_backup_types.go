@@ -0,0 +1,119 @@
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AddonBackup schedules logical backups of a postgres addon to a volume or
+// an S3 compatible bucket.
+// +kubebuilder:validation:XValidation:rule="has(self.volume) != has(self.s3)",message="exactly one of volume or s3 must be set"
+type AddonBackup struct {
+	// Schedule in cron format, e.g. "0 3 * * *" to back up daily at 3am.
+	//+kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+
+	// TimeZone of the schedule, e.g. "Europe/Berlin". Defaults to the time
+	// zone of the cluster.
+	//+optional
+	TimeZone *string `json:"timeZone,omitempty"`
+
+	// Retain is the number of backups recorded in the addon status. Older
+	// backups are deleted from volumes, buckets should expire them with a
+	// lifecycle rule instead. Defaults to 7.
+	//+optional
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:default=7
+	Retain int32 `json:"retain,omitempty"`
+
+	// Volume stores the backups in a volume of the namespace of the addon.
+	//+optional
+	Volume *BackupVolumeTarget `json:"volume,omitempty"`
+
+	// S3 uploads the backups to an S3 compatible bucket, such as AWS S3 or
+	// MinIO.
+	//+optional
+	S3 *BackupS3Target `json:"s3,omitempty"`
+}
+
+// BackupVolumeTarget is a volume storing the backups of an addon. It is
+// deleted along with the addon.
+type BackupVolumeTarget struct {
+	// Size of the volume.
+	Size resource.Quantity `json:"size"`
+
+	// StorageClassName of the volume. Defaults to the default storage class
+	// of the cluster.
+	//+optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+}
+
+// BackupS3Target is an S3 compatible bucket storing the backups of an
+// addon. Backups are stored at <prefix>/<addon name>/<backup name>.sql.gz.
+type BackupS3Target struct {
+	// Bucket the backups are uploaded to.
+	//+kubebuilder:validation:MinLength=1
+	Bucket string `json:"bucket"`
+
+	// Prefix of the keys of the backups in the bucket.
+	//+optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// Endpoint of the S3 compatible API, e.g. http://minio.minio.svc:9000.
+	// Defaults to AWS S3.
+	//+optional
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Region of the bucket. Defaults to us-east-1.
+	//+optional
+	Region string `json:"region,omitempty"`
+
+	// SecretName is the name of the Secret holding the AWS_ACCESS_KEY_ID and
+	// AWS_SECRET_ACCESS_KEY used to access the bucket.
+	//+kubebuilder:validation:MinLength=1
+	SecretName string `json:"secretName"`
+}
+
+// AddonRestore restores a backup of another addon of the namespace into a
+// new addon. The backup is read from where it is stored, so the addon it was
+// taken from doesn't need to exist anymore.
+type AddonRestore struct {
+	// Addon is the name of the addon the backup was taken from.
+	//+kubebuilder:validation:MinLength=1
+	Addon string `json:"addon"`
+
+	// Backup is the name of the backup in the status of the addon.
+	//+kubebuilder:validation:MinLength=1
+	Backup string `json:"backup"`
+
+	// S3 is the bucket the backup was uploaded to. Defaults to the bucket
+	// the addon is backed up to while it exists, otherwise the backup is read
+	// from the backup volume of the addon.
+	//+optional
+	S3 *BackupS3Target `json:"s3,omitempty"`
+
+	// Checksum of the backup, e.g. sha256:<hex digest>. Defaults to the
+	// checksum recorded in the status of the addon, or stored along with the
+	// backup.
+	//+kubebuilder:validation:Pattern=`^sha256:[a-f0-9]{64}$`
+	//+optional
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// AddonBackupStatus is a backup taken of an addon.
+type AddonBackupStatus struct {
+	// Name of the backup, which is the name of the Job that took it.
+	Name string `json:"name"`
+
+	// Time is when the backup completed.
+	Time metav1.Time `json:"time"`
+
+	// SizeBytes is the size of the compressed backup.
+	SizeBytes int64 `json:"sizeBytes"`
+
+	// Checksum of the compressed backup, e.g. sha256:<hex digest>.
+	Checksum string `json:"checksum"`
+
+	// Location of the backup, e.g. s3://<bucket>/<key>.
+	Location string `json:"location"`
+}
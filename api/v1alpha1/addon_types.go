@@ -0,0 +1,160 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AddonType is the kind of backing service an addon provisions.
+// +kubebuilder:validation:Enum=postgres;redis
+type AddonType string
+
+const (
+	// AddonPostgres provisions a PostgreSQL database.
+	AddonPostgres AddonType = "postgres"
+
+	// AddonRedis provisions a Redis instance persisting to disk.
+	AddonRedis AddonType = "redis"
+)
+
+// AddonSpec defines the desired state of Addon
+// +kubebuilder:validation:XValidation:rule="!has(self.backup) || self.type == 'postgres'",message="backups are only supported for postgres addons"
+// +kubebuilder:validation:XValidation:rule="!has(self.restoreFrom) || self.type == 'postgres'",message="restores are only supported for postgres addons"
+// +kubebuilder:validation:XValidation:rule="has(self.restoreFrom) == has(oldSelf.restoreFrom) && (!has(self.restoreFrom) || self.restoreFrom == oldSelf.restoreFrom)",message="restoreFrom can only be set when the addon is created"
+type AddonSpec struct {
+	// Type of the addon. It can't be changed once the addon is created.
+	//+kubebuilder:validation:XValidation:rule="self == oldSelf",message="type is immutable"
+	Type AddonType `json:"type"`
+
+	// Plan is the RuntimeSize the addon runs on. Defaults to basic.
+	//+optional
+	//+kubebuilder:default=basic
+	Plan RuntimeSizeName `json:"plan,omitempty"`
+
+	// Version of the addon, which is the tag of the official image of its
+	// type, e.g. 16 for postgres or 7 for redis. Defaults to the latest
+	// major version supported by the operator. Changing the major version
+	// of postgres requires migrating its data first.
+	//+optional
+	//+kubebuilder:validation:MaxLength=128
+	//+kubebuilder:validation:Pattern=`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`
+	Version string `json:"version,omitempty"`
+
+	// Storage is the size of the volume holding the data of the addon. It
+	// can be increased later if the storage class allows volume expansion.
+	//+optional
+	//+kubebuilder:default="1Gi"
+	Storage resource.Quantity `json:"storage,omitempty"`
+
+	// StorageClassName of the volume holding the data of the addon.
+	// Defaults to the default storage class of the cluster.
+	//+optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+
+	// Backup schedules logical backups of the addon. Only postgres addons
+	// can be backed up.
+	//+optional
+	Backup *AddonBackup `json:"backup,omitempty"`
+
+	// RestoreFrom restores a backup of another addon once the addon is
+	// created. It can't be changed afterwards.
+	//+optional
+	RestoreFrom *AddonRestore `json:"restoreFrom,omitempty"`
+}
+
+// AddonStatus defines the observed state of Addon
+type AddonStatus struct {
+	// Conditions store the status conditions of the addon.
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+
+	// ObservedGeneration is the generation of the addon last reconciled by
+	// the operator.
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	//+optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// SecretName is the name of the Secret holding the credentials of the
+	// addon. Its URL key holds the URL applications connect with.
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	//+optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// Version is the version the addon runs.
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	//+optional
+	Version string `json:"version,omitempty"`
+
+	// BackupSchedule is the observed state of the backup schedule.
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	//+optional
+	BackupSchedule *ScheduleStatus `json:"backupSchedule,omitempty"`
+
+	// Backups are the most recent backups of the addon, oldest first.
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	//+optional
+	//+listType=map
+	//+listMapKey=name
+	Backups []AddonBackupStatus `json:"backups,omitempty"`
+}
+
+// AddonReference references an addon in the namespace of an application,
+// whose URL is injected into the processes of the application.
+type AddonReference struct {
+	// Name of the addon.
+	Name string `json:"name"`
+
+	// Env is the name of the environment variable holding the URL of the
+	// addon. Defaults to DATABASE_URL for postgres and REDIS_URL for redis.
+	//+optional
+	//+kubebuilder:validation:Pattern=`^[A-Za-z_][A-Za-z0-9_]*$`
+	Env string `json:"env,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Type",type=string,JSONPath=`.spec.type`
+//+kubebuilder:printcolumn:name="Plan",type=string,JSONPath=`.spec.plan`
+//+kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.status.version`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// Addon is the Schema for the addons API.
+// Addons are backing services, such as databases, that the operator runs
+// in the namespace of the applications using them.
+type Addon struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AddonSpec   `json:"spec,omitempty"`
+	Status AddonStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// AddonList contains a list of Addon
+type AddonList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Addon `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Addon{}, &AddonList{})
+}
@@ -17,24 +17,41 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type ApplicationRuntime struct {
 	// Size is the type of resources required to the application should run on.
-	// Possible values are from the predefined types.
-	Size RuntimeSize `json:"size,omitempty"`
+	// It is the name of a RuntimeSize of the cluster, such as the built-in
+	// basic, standard or performance sizes.
+	Size RuntimeSizeName `json:"size,omitempty"`
 
 	// Container image to use for this application.
 	Image RuntimeImage `json:"image,omitempty"`
+
+	// Resources overrides or extends the requests and limits of the size
+	// for all the processes. Raising a request above the limit of the size
+	// raises the limit too, unless the limit is overridden as well.
+	// Overrides must stay within the LimitRanges of the namespace.
+	//+optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
 }
 
 // ApplicationSpec defines the desired state of Application
 type ApplicationSpec struct {
 	// Replicas is the number of instances of this application that should be created.
+	// Ignored when Processes are defined or Autoscale is set.
+	// It is the replicas field of the scale subresource.
 	// +operator-sdk:csv:customresourcedefinitions:type=spec
 	Replicas int32 `json:"replicas,omitempty"`
 
+	// Autoscale scales the number of instances of this application based
+	// on its resource usage. Ignored when Processes are defined.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	//+optional
+	Autoscale *Autoscale `json:"autoscale,omitempty"`
+
 	// Runtime configuration to run this application.
 	// +operator-sdk:csv:customresourcedefinitions:type=spec
 	Runtime ApplicationRuntime `json:"runtime,omitempty"`
@@ -42,11 +59,116 @@ type ApplicationSpec struct {
 	// Endpoints is the list of ports and domains that this application should expose.
 	// It can be left empty for workers that don't need to expose an endpoint.
 	// Metrics endpoints should be exposed using this as well.
+	// Ignored when Processes are defined.
 	// +operator-sdk:csv:customresourcedefinitions:type=spec
 	Endpoints ApplicationEndpoints `json:"endpoints,omitempty"`
 
+	// TLS settings applied to the domains of all the endpoints of this
+	// application, including the endpoints of its processes.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	//+optional
+	TLS *EndpointTLS `json:"tls,omitempty"`
+
+	// HealthCheck configures the health check of the application.
+	// By default, the first endpoint port is checked over TCP.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	//+optional
+	HealthCheck *HealthCheck `json:"healthcheck,omitempty"`
+
 	// Command to launch or startup the application.
+	// It is the default command for Processes that don't specify one.
 	LaunchCommand []string `json:"command,omitempty"`
+
+	// Processes are the process types of this application keyed by name,
+	// e.g. web, worker and clock. Each process runs in its own Deployment.
+	// When empty, the application runs as a single process using the
+	// Replicas, Endpoints and Command defined above.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	//+optional
+	Processes map[string]ApplicationProcess `json:"processes,omitempty"`
+
+	// Release is the command of the release phase. When set, it runs as a
+	// one-shot Job with the application image and config before a new image
+	// or config is rolled out, e.g. to run database migrations. The rollout
+	// only happens when the Job succeeds.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	//+optional
+	Release []string `json:"release,omitempty"`
+
+	// ReleaseTimeout is how long the release phase may run before it is
+	// stopped and reported as failed, so a hung release command does not
+	// block later releases. Defaults to 30m.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	//+optional
+	//+kubebuilder:default="30m"
+	ReleaseTimeout *metav1.Duration `json:"releaseTimeout,omitempty"`
+
+	// Schedules are the tasks that run on a schedule, keyed by name. Each
+	// task runs in its own CronJob with the application image and config.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	//+optional
+	Schedules map[string]ApplicationSchedule `json:"schedules,omitempty"`
+
+	// Workload defines how the processes of this application are run.
+	// Defaults to stateless.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	//+optional
+	//+kubebuilder:default=stateless
+	Workload WorkloadType `json:"workload,omitempty"`
+
+	// Volumes are the persistent disks mounted into all the processes of
+	// this application. Stateful applications get a volume per replica.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	//+optional
+	//+listType=map
+	//+listMapKey=name
+	Volumes []ApplicationVolume `json:"volumes,omitempty"`
+
+	// Addons are the addons of the namespace this application uses. Their
+	// URLs are exposed to the application as environment variables, unless
+	// the config sets the same variables.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	//+optional
+	//+listType=map
+	//+listMapKey=name
+	Addons []AddonReference `json:"addons,omitempty"`
+
+	// Bindings are the other applications this application calls. Their
+	// in-cluster addresses are exposed to the application as environment
+	// variables, unless the config sets the same variables.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	//+optional
+	//+listType=map
+	//+listMapKey=name
+	Bindings []ApplicationBinding `json:"bindings,omitempty"`
+
+	// Config vars exposed to the application as environment variables.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	//+optional
+	Config ApplicationConfig `json:"config,omitempty"`
+
+	// RollbackTo is the version of a previous Release to run instead of the
+	// image and config defined in this spec. Clear it to roll forward again.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	//+optional
+	//+kubebuilder:validation:Minimum=1
+	RollbackTo *int32 `json:"rollbackTo,omitempty"`
+
+	// ReleaseHistoryLimit is the number of Releases kept to roll back to.
+	// Older Releases are deleted, except the current one and the one
+	// rolled back to. Defaults to 10.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	//+optional
+	//+kubebuilder:default=10
+	//+kubebuilder:validation:Minimum=1
+	ReleaseHistoryLimit *int32 `json:"releaseHistoryLimit,omitempty"`
+
+	// DeletionPolicy defines what happens to the resources of this
+	// application when it is deleted. Defaults to Delete.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	//+optional
+	//+kubebuilder:default=Delete
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
 }
 
 // ApplicationStatus defines the observed state of Application
@@ -54,10 +176,72 @@ type ApplicationStatus struct {
 	// Conditions store the status conditions of the Memcached instances
 	// +operator-sdk:csv:customresourcedefinitions:type=status
 	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+
+	// CurrentRelease is the version of the Release the application runs.
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	//+optional
+	CurrentRelease int32 `json:"currentRelease,omitempty"`
+
+	// ObservedGeneration is the generation of the application last
+	// reconciled by the operator.
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	//+optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Image is the image the application deployments run.
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	//+optional
+	Image RuntimeImage `json:"image,omitempty"`
+
+	// Replicas is the number of pods of the application processes.
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	//+optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// ReadyReplicas is the number of ready pods of the application processes.
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	//+optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	// UpdatedReplicas is the number of pods of the application processes
+	// running the latest spec.
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	//+optional
+	UpdatedReplicas int32 `json:"updatedReplicas,omitempty"`
+
+	// AvailableReplicas is the number of available pods of the application
+	// processes.
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	//+optional
+	AvailableReplicas int32 `json:"availableReplicas,omitempty"`
+
+	// Selector is the label selector of the pods counted in Replicas,
+	// used by the scale subresource.
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	//+optional
+	Selector string `json:"selector,omitempty"`
+
+	// Schedules is the observed state of the scheduled tasks.
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	//+optional
+	//+listType=map
+	//+listMapKey=name
+	Schedules []ScheduleStatus `json:"schedules,omitempty"`
+
+	// URL of the first domain endpoint of the application.
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	//+optional
+	URL string `json:"url,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas,selectorpath=.status.selector
+//+kubebuilder:printcolumn:name="Image",type=string,JSONPath=`.spec.runtime.image`
+//+kubebuilder:printcolumn:name="Size",type=string,JSONPath=`.spec.runtime.size`
+//+kubebuilder:printcolumn:name="Ready",type=integer,JSONPath=`.status.readyReplicas`
+//+kubebuilder:printcolumn:name="URL",type=string,JSONPath=`.status.url`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // Application is the Schema for the applications API
 type Application struct {
@@ -0,0 +1,686 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var applicationlog = logf.Log.WithName("application-resource")
+
+func (r *Application) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-operators-k4indie-io-v1alpha1-application,mutating=true,failurePolicy=fail,sideEffects=None,groups=operators.k4indie.io,resources=applications,verbs=create;update,versions=v1alpha1,name=mapplication.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &Application{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+func (r *Application) Default() {
+	applicationlog.Info("default", "name", r.Name)
+
+	// Replicas can't be defaulted by the schema, since applications scaled
+	// to zero would then be scaled back to one. They are only defaulted when
+	// the application is created.
+	if r.CreationTimestamp.IsZero() && r.Spec.Replicas == 0 {
+		r.Spec.Replicas = 1
+	}
+
+	defaultEndpoints(r.Spec.Endpoints)
+	for _, process := range r.Spec.Processes {
+		defaultEndpoints(process.Endpoints)
+	}
+}
+
+func defaultEndpoints(endpoints ApplicationEndpoints) {
+	for i := range endpoints {
+		if endpoints[i].DomainPath == "" {
+			endpoints[i].DomainPath = "/"
+		}
+	}
+}
+
+//+kubebuilder:webhook:path=/validate-operators-k4indie-io-v1alpha1-application,mutating=false,failurePolicy=fail,sideEffects=None,groups=operators.k4indie.io,resources=applications,verbs=create;update,versions=v1alpha1,name=vapplication.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &Application{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *Application) ValidateCreate() error {
+	applicationlog.Info("validate create", "name", r.Name)
+
+	return r.validateApplication()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *Application) ValidateUpdate(old runtime.Object) error {
+	applicationlog.Info("validate update", "name", r.Name)
+
+	oldApp, ok := old.(*Application)
+	if !ok {
+		return r.validateApplication()
+	}
+	// Applications being deleted, or whose finalizers or status change,
+	// must not be blocked by validations added since they were created.
+	if r.DeletionTimestamp != nil || equality.Semantic.DeepEqual(r.Spec, oldApp.Spec) {
+		return nil
+	}
+	if err := r.validateUpdate(oldApp); err != nil {
+		return err
+	}
+
+	return r.validateApplication()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *Application) ValidateDelete() error {
+	return nil
+}
+
+func (r *Application) validateApplication() error {
+	specPath := field.NewPath("spec")
+	errs := field.ErrorList{}
+
+	if err := r.Spec.Runtime.Image.Validate(); err != nil {
+		errs = append(errs, field.Invalid(specPath.Child("runtime", "image"), r.Spec.Runtime.Image, err.Error()))
+	}
+	errs = append(errs, validateSize(specPath.Child("runtime", "size"), r.Spec.Runtime.Size)...)
+	errs = append(errs, validateResources(specPath.Child("runtime", "resources"), r.Spec.Runtime.Resources)...)
+	errs = append(errs, validateConfig(specPath.Child("config"), r.Spec.Config)...)
+	errs = append(errs, validateAddons(specPath.Child("addons"), r.Spec.Addons)...)
+	if len(r.Spec.Processes) == 0 {
+		errs = append(errs, validateHealthCheck(specPath.Child("healthCheck"), r.Spec.HealthCheck, r.Spec.Endpoints)...)
+	}
+
+	errs = append(errs, validateTLS(specPath.Child("tls"), r.Spec.TLS)...)
+
+	routes := map[string]string{}
+	issuers := map[string]string{}
+	errs = append(errs, validateEndpoints(specPath.Child("endpoints"), r.Spec.Endpoints, routes)...)
+	errs = append(errs, validateTLSIssuers(specPath.Child("endpoints"), r.Spec.Endpoints, r.Spec.TLS, issuers)...)
+
+	processNames := make([]string, 0, len(r.Spec.Processes))
+	for name := range r.Spec.Processes {
+		processNames = append(processNames, name)
+	}
+	sort.Strings(processNames)
+	for _, name := range processNames {
+		process := r.Spec.Processes[name]
+		processPath := specPath.Child("processes").Key(name)
+
+		errs = append(errs, validateProcessName(processPath, r.Name, name)...)
+		errs = append(errs, validateSize(processPath.Child("size"), process.Size)...)
+		if process.HealthCheck != nil {
+			errs = append(errs, validateHealthCheck(processPath.Child("healthCheck"), process.HealthCheck, process.Endpoints)...)
+		} else {
+			errs = append(errs, validateHealthCheck(specPath.Child("healthCheck"), r.Spec.HealthCheck, process.Endpoints)...)
+		}
+		errs = append(errs, validateEndpoints(processPath.Child("endpoints"), process.Endpoints, routes)...)
+		errs = append(errs, validateTLSIssuers(processPath.Child("endpoints"), process.Endpoints, r.Spec.TLS, issuers)...)
+	}
+
+	errs = append(errs, validateVolumes(specPath.Child("volumes"), r.Spec.Volumes)...)
+	errs = append(errs, r.validateVolumeAccessModes(specPath.Child("volumes"))...)
+	errs = append(errs, validateBindings(specPath.Child("bindings"), r.Spec.Bindings)...)
+
+	scheduleNames := make([]string, 0, len(r.Spec.Schedules))
+	for name := range r.Spec.Schedules {
+		scheduleNames = append(scheduleNames, name)
+	}
+	sort.Strings(scheduleNames)
+	for _, name := range scheduleNames {
+		schedulePath := specPath.Child("schedules").Key(name)
+		errs = append(errs, validateScheduleName(schedulePath, r.Name, name)...)
+		errs = append(errs, validateSize(schedulePath.Child("size"), r.Spec.Schedules[name].Size)...)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(
+		schema.GroupKind{Group: GroupVersion.Group, Kind: "Application"},
+		r.Name,
+		errs,
+	)
+}
+
+// validateUpdate checks the changes to the spec that would lose the data
+// of the application, or that can't be applied to its existing volumes.
+func (r *Application) validateUpdate(old *Application) error {
+	specPath := field.NewPath("spec")
+	errs := field.ErrorList{}
+	errs = append(errs, r.validateWorkloadUpdate(specPath.Child("workload"), old)...)
+	errs = append(errs, validateVolumesUpdate(specPath.Child("volumes"), r.Spec.Volumes, old.Spec.Volumes)...)
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(
+		schema.GroupKind{Group: GroupVersion.Group, Kind: "Application"},
+		r.Name,
+		errs,
+	)
+}
+
+// validateWorkloadUpdate checks that the workload of an application with
+// volumes is not changed. Stateless and stateful applications don't store
+// their data in the same volumes, so the data would be lost.
+func (r *Application) validateWorkloadUpdate(path *field.Path, old *Application) field.ErrorList {
+	if workloadOrDefault(r.Spec.Workload) == workloadOrDefault(old.Spec.Workload) {
+		return nil
+	}
+	if len(r.Spec.Volumes) == 0 && len(old.Spec.Volumes) == 0 {
+		return nil
+	}
+
+	return field.ErrorList{field.Forbidden(path, "may not be changed while the application has volumes")}
+}
+
+// validateVolumesUpdate checks that the volumes kept by an update are only
+// changed in ways their existing claims support: the storage class and
+// access mode of a claim are immutable, and a claim can't shrink.
+func validateVolumesUpdate(path *field.Path, volumes, oldVolumes []ApplicationVolume) field.ErrorList {
+	errs := field.ErrorList{}
+
+	oldVolumesByName := map[string]ApplicationVolume{}
+	for _, volume := range oldVolumes {
+		oldVolumesByName[volume.Name] = volume
+	}
+
+	for i, volume := range volumes {
+		oldVolume, exists := oldVolumesByName[volume.Name]
+		if !exists {
+			continue
+		}
+		volumePath := path.Index(i)
+
+		if !equality.Semantic.DeepEqual(volume.StorageClassName, oldVolume.StorageClassName) {
+			errs = append(errs, field.Forbidden(volumePath.Child("storageClassName"), "may not be changed"))
+		}
+		if accessModeOrDefault(volume.AccessMode) != accessModeOrDefault(oldVolume.AccessMode) {
+			errs = append(errs, field.Forbidden(volumePath.Child("accessMode"), "may not be changed"))
+		}
+		if volume.Size.Cmp(oldVolume.Size) < 0 {
+			errs = append(errs, field.Forbidden(
+				volumePath.Child("size"),
+				fmt.Sprintf("may not be less than the current size %s", oldVolume.Size.String()),
+			))
+		}
+	}
+
+	return errs
+}
+
+// validateVolumeAccessModes checks that the volumes of a stateless
+// application, which are shared by all its pods, can be mounted by several
+// pods when the application runs more than one pod.
+func (r *Application) validateVolumeAccessModes(path *field.Path) field.ErrorList {
+	if workloadOrDefault(r.Spec.Workload) == WorkloadStateful || !r.runsSeveralPods() {
+		return nil
+	}
+
+	errs := field.ErrorList{}
+	for i, volume := range r.Spec.Volumes {
+		switch accessModeOrDefault(volume.AccessMode) {
+		case corev1.ReadWriteMany, corev1.ReadOnlyMany:
+			continue
+		}
+
+		errs = append(errs, field.Invalid(
+			path.Index(i).Child("accessMode"),
+			accessModeOrDefault(volume.AccessMode),
+			"must be ReadWriteMany or ReadOnlyMany when the volume is shared by several replicas, "+
+				"processes or an autoscaled process, or the workload must be Stateful",
+		))
+	}
+
+	return errs
+}
+
+// runsSeveralPods reports whether the application may run more than one pod
+// at a time, besides the pods of a rollout.
+func (r *Application) runsSeveralPods() bool {
+	if len(r.Spec.Processes) == 0 {
+		return r.Spec.Replicas > 1 || autoscalesAboveOne(r.Spec.Autoscale)
+	}
+	if len(r.Spec.Processes) > 1 {
+		return true
+	}
+	for _, process := range r.Spec.Processes {
+		if process.Replicas > 1 || autoscalesAboveOne(process.Autoscale) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func autoscalesAboveOne(autoscale *Autoscale) bool {
+	return autoscale != nil && autoscale.Max > 1
+}
+
+func accessModeOrDefault(accessMode corev1.PersistentVolumeAccessMode) corev1.PersistentVolumeAccessMode {
+	if accessMode == "" {
+		return corev1.ReadWriteOnce
+	}
+
+	return accessMode
+}
+
+func workloadOrDefault(workload WorkloadType) WorkloadType {
+	if workload == "" {
+		return WorkloadStateless
+	}
+
+	return workload
+}
+
+// reservedProcessNames are the process names used by the pods of the
+// release phase, which are labeled as a process.
+var reservedProcessNames = map[string]struct{}{"release": {}}
+
+// validateProcessName checks that the process name can be used in the
+// labels of its pods and in the name of its Deployment and Service, which is
+// the application name followed by the process name.
+func validateProcessName(path *field.Path, appName, name string) field.ErrorList {
+	errs := field.ErrorList{}
+
+	for _, msg := range validation.IsDNS1123Label(name) {
+		errs = append(errs, field.Invalid(path, name, msg))
+	}
+	if _, reserved := reservedProcessNames[name]; reserved {
+		errs = append(errs, field.Invalid(path, name, "is reserved for the release phase"))
+	}
+	if resourceName := appName + "-" + name; len(resourceName) > validation.DNS1035LabelMaxLength {
+		errs = append(errs, field.Invalid(path, name, fmt.Sprintf(
+			"the resource name %s of the process must be no more than %d characters",
+			resourceName, validation.DNS1035LabelMaxLength,
+		)))
+	}
+
+	return errs
+}
+
+// cronJobNameMaxLength is the maximum length of the name of a CronJob, which
+// is suffixed with the 11 characters of the schedule time to name its Jobs.
+const cronJobNameMaxLength = 52
+
+// validateScheduleName checks that the name of a scheduled task is a valid
+// label, and that the name of its CronJob is not too long.
+func validateScheduleName(path *field.Path, appName, name string) field.ErrorList {
+	errs := field.ErrorList{}
+
+	for _, msg := range validation.IsDNS1123Label(name) {
+		errs = append(errs, field.Invalid(path, name, msg))
+	}
+	if resourceName := appName + "-" + name; len(resourceName) > cronJobNameMaxLength {
+		errs = append(errs, field.Invalid(path, name, fmt.Sprintf(
+			"the CronJob name %s of the scheduled task must be no more than %d characters",
+			resourceName, cronJobNameMaxLength,
+		)))
+	}
+
+	return errs
+}
+
+// validateHealthCheck checks that at most one check is set, and that the
+// ports it checks are endpoint ports of the process it applies to.
+func validateHealthCheck(path *field.Path, check *HealthCheck, endpoints ApplicationEndpoints) field.ErrorList {
+	if check == nil || check.Disabled {
+		return nil
+	}
+
+	errs := field.ErrorList{}
+	checks := 0
+	validatePort := func(portPath *field.Path, port int32) {
+		if port == 0 {
+			return
+		}
+		for _, endpoint := range endpoints {
+			if endpoint.Port == port {
+				return
+			}
+		}
+		errs = append(errs, field.Invalid(portPath, port, ErrInvalidHealthCheckPort.Error()))
+	}
+
+	if check.HTTP != nil {
+		checks++
+		validatePort(path.Child("http", "port"), check.HTTP.Port)
+	}
+	if check.TCP != nil {
+		checks++
+		validatePort(path.Child("tcp", "port"), check.TCP.Port)
+	}
+	if check.Exec != nil {
+		checks++
+		if len(check.Exec.Command) == 0 {
+			errs = append(errs, field.Required(path.Child("exec", "command"), "must not be empty"))
+		}
+	}
+	if checks > 1 {
+		errs = append(errs, field.Forbidden(path, "at most one of http, tcp or exec may be set"))
+	}
+
+	return errs
+}
+
+// validateConfig checks that the config vars are valid environment variable
+// names, and that the Secrets and ConfigMaps they reference have valid
+// names. Whether the referenced objects exist is only known to the
+// operator.
+func validateConfig(path *field.Path, config ApplicationConfig) field.ErrorList {
+	errs := field.ErrorList{}
+
+	names := make([]string, 0, len(config.Vars))
+	for name := range config.Vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, msg := range validation.IsEnvVarName(name) {
+			errs = append(errs, field.Invalid(path.Child("vars").Key(name), name, msg))
+		}
+	}
+
+	seen := make(map[string]struct{}, len(config.Vars)+len(config.Refs))
+	for name := range config.Vars {
+		seen[name] = struct{}{}
+	}
+	for i, ref := range config.Refs {
+		refPath := path.Child("refs").Index(i)
+		for _, msg := range validation.IsEnvVarName(ref.Name) {
+			errs = append(errs, field.Invalid(refPath.Child("name"), ref.Name, msg))
+		}
+		if _, exists := seen[ref.Name]; exists {
+			errs = append(errs, field.Duplicate(refPath.Child("name"), ref.Name))
+		}
+		seen[ref.Name] = struct{}{}
+
+		switch {
+		case ref.SecretKeyRef != nil && ref.ConfigMapKeyRef != nil:
+			errs = append(errs, field.Forbidden(refPath, "only one of secretKeyRef or configMapKeyRef may be set"))
+		case ref.SecretKeyRef != nil:
+			errs = append(errs, validateConfigKeySelector(refPath.Child("secretKeyRef"), ref.SecretKeyRef)...)
+		case ref.ConfigMapKeyRef != nil:
+			errs = append(errs, validateConfigKeySelector(refPath.Child("configMapKeyRef"), ref.ConfigMapKeyRef)...)
+		default:
+			errs = append(errs, field.Required(refPath, "one of secretKeyRef or configMapKeyRef must be set"))
+		}
+	}
+
+	for i, name := range config.Secrets {
+		for _, msg := range validation.IsDNS1123Subdomain(name) {
+			errs = append(errs, field.Invalid(path.Child("secrets").Index(i), name, msg))
+		}
+	}
+	for i, name := range config.ConfigMaps {
+		for _, msg := range validation.IsDNS1123Subdomain(name) {
+			errs = append(errs, field.Invalid(path.Child("configMaps").Index(i), name, msg))
+		}
+	}
+
+	return errs
+}
+
+// validateAddons checks that no two addon references set the same
+// environment variable. The names defaulted from the types of the addons
+// are only known to the operator, which rejects collisions among them.
+func validateAddons(path *field.Path, addons []AddonReference) field.ErrorList {
+	errs := field.ErrorList{}
+
+	envs := make(map[string]struct{}, len(addons))
+	for i, ref := range addons {
+		if ref.Env == "" {
+			continue
+		}
+		if _, exists := envs[ref.Env]; exists {
+			errs = append(errs, field.Duplicate(path.Index(i).Child("env"), ref.Env))
+		}
+		envs[ref.Env] = struct{}{}
+	}
+
+	return errs
+}
+
+func validateConfigKeySelector(path *field.Path, selector *ConfigKeySelector) field.ErrorList {
+	errs := field.ErrorList{}
+
+	for _, msg := range validation.IsDNS1123Subdomain(selector.Name) {
+		errs = append(errs, field.Invalid(path.Child("name"), selector.Name, msg))
+	}
+	for _, msg := range validation.IsConfigMapKey(selector.Key) {
+		errs = append(errs, field.Invalid(path.Child("key"), selector.Key, msg))
+	}
+
+	return errs
+}
+
+// validateSize checks that the size is a valid RuntimeSize name. Whether
+// the RuntimeSize exists is only known to the operator, which reports
+// missing sizes on the application status.
+func validateSize(path *field.Path, size RuntimeSizeName) field.ErrorList {
+	if size == "" {
+		return nil
+	}
+
+	errs := field.ErrorList{}
+	for _, msg := range validation.IsDNS1123Subdomain(string(size)) {
+		errs = append(errs, field.Invalid(path, size, msg))
+	}
+
+	return errs
+}
+
+// validateResources checks that the overridden quantities are not negative,
+// and that requests do not exceed the limits overridden along with them.
+// Whether they fit the namespace policy is checked by the operator, which
+// knows the LimitRanges of the namespace.
+func validateResources(path *field.Path, resources *corev1.ResourceRequirements) field.ErrorList {
+	if resources == nil {
+		return nil
+	}
+
+	errs := field.ErrorList{}
+	for _, name := range sortedResourceNames(resources.Limits) {
+		if quantity := resources.Limits[name]; quantity.Sign() < 0 {
+			errs = append(errs, field.Invalid(path.Child("limits").Key(string(name)), quantity.String(), "must not be negative"))
+		}
+	}
+	for _, name := range sortedResourceNames(resources.Requests) {
+		quantity := resources.Requests[name]
+		requestPath := path.Child("requests").Key(string(name))
+
+		if quantity.Sign() < 0 {
+			errs = append(errs, field.Invalid(requestPath, quantity.String(), "must not be negative"))
+			continue
+		}
+		if limit, exists := resources.Limits[name]; exists && quantity.Cmp(limit) > 0 {
+			errs = append(errs, field.Invalid(requestPath, quantity.String(), fmt.Sprintf("must be less than or equal to the %s limit", name)))
+		}
+	}
+
+	return errs
+}
+
+func sortedResourceNames(resources corev1.ResourceList) []corev1.ResourceName {
+	names := make([]corev1.ResourceName, 0, len(resources))
+	for name := range resources {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return names[i] < names[j]
+	})
+
+	return names
+}
+
+// validateVolumes checks that the volumes have a size and are not mounted
+// at the same path.
+func validateVolumes(path *field.Path, volumes []ApplicationVolume) field.ErrorList {
+	errs := field.ErrorList{}
+	mountPaths := map[string]struct{}{}
+
+	for i, volume := range volumes {
+		volumePath := path.Index(i)
+
+		if volume.Size.Sign() <= 0 {
+			errs = append(errs, field.Invalid(volumePath.Child("size"), volume.Size.String(), "must be greater than zero"))
+		}
+		if !strings.HasPrefix(volume.MountPath, "/") {
+			errs = append(errs, field.Invalid(volumePath.Child("mountPath"), volume.MountPath, "must be an absolute path"))
+		}
+		if _, exists := mountPaths[volume.MountPath]; exists {
+			errs = append(errs, field.Duplicate(volumePath.Child("mountPath"), volume.MountPath))
+			continue
+		}
+		mountPaths[volume.MountPath] = struct{}{}
+	}
+
+	return errs
+}
+
+// validateBindings checks that the ports of the bindings are valid. Whether
+// the bound applications exist is only known to the operator.
+func validateBindings(path *field.Path, bindings []ApplicationBinding) field.ErrorList {
+	errs := field.ErrorList{}
+
+	for i, binding := range bindings {
+		if binding.Port != 0 && (binding.Port < 1 || binding.Port > 65535) {
+			errs = append(errs, field.Invalid(path.Index(i).Child("port"), binding.Port, "must be between 1 and 65535"))
+		}
+	}
+
+	return errs
+}
+
+// validateEndpoints validates the endpoints and checks that every domain and
+// path is routed only once. routes holds the routes of the endpoints
+// validated before, keyed by domain and path, across all the processes.
+func validateEndpoints(path *field.Path, endpoints ApplicationEndpoints, routes map[string]string) field.ErrorList {
+	errs := field.ErrorList{}
+
+	for i, endpoint := range endpoints {
+		endpointPath := path.Index(i)
+
+		if endpoint.Port < 1 || endpoint.Port > 65535 {
+			errs = append(errs, field.Invalid(endpointPath.Child("port"), endpoint.Port, "must be between 1 and 65535"))
+		}
+
+		if endpoint.Domain == "" {
+			continue
+		}
+		errs = append(errs, validateTLS(endpointPath.Child("tls"), endpoint.TLS)...)
+
+		var domainErrs []string
+		if strings.HasPrefix(endpoint.Domain, "*.") {
+			domainErrs = validation.IsWildcardDNS1123Subdomain(endpoint.Domain)
+		} else {
+			domainErrs = validation.IsDNS1123Subdomain(endpoint.Domain)
+		}
+		for _, msg := range domainErrs {
+			errs = append(errs, field.Invalid(endpointPath.Child("domain"), endpoint.Domain, msg))
+		}
+
+		if endpoint.DomainPath != "" && !strings.HasPrefix(endpoint.DomainPath, "/") {
+			errs = append(errs, field.Invalid(endpointPath.Child("domain_path"), endpoint.DomainPath, "must start with '/'"))
+		}
+
+		domainPath := endpoint.DomainPath
+		if domainPath == "" {
+			domainPath = "/"
+		}
+		route := endpoint.Domain + domainPath
+		if routedBy, exists := routes[route]; exists {
+			errs = append(errs, field.Duplicate(
+				endpointPath,
+				fmt.Sprintf("%s is already routed by %s", route, routedBy),
+			))
+			continue
+		}
+		routes[route] = endpointPath.String()
+	}
+
+	return errs
+}
+
+// validateTLS checks that enabled TLS settings either issue a certificate or
+// reference an existing one.
+func validateTLS(path *field.Path, tls *EndpointTLS) field.ErrorList {
+	if tls == nil || tls.Disabled || tls.Issuer != "" || tls.SecretName != "" {
+		return nil
+	}
+
+	return field.ErrorList{field.Required(path, "issuer or secretName is required unless tls is disabled")}
+}
+
+// validateTLSIssuers checks that the certificates of all the domains are
+// issued by the same issuer, since an Ingress only supports a single one.
+// issuers holds the issuer validated before, keyed by kind and name, across
+// all the processes.
+func validateTLSIssuers(path *field.Path, endpoints ApplicationEndpoints, appTLS *EndpointTLS, issuers map[string]string) field.ErrorList {
+	errs := field.ErrorList{}
+
+	for i, endpoint := range endpoints {
+		tls := endpoint.TLS
+		if tls == nil {
+			tls = appTLS
+		}
+		if endpoint.Domain == "" || tls == nil || tls.Disabled || tls.SecretName != "" || tls.Issuer == "" {
+			continue
+		}
+
+		issuerKind := tls.IssuerKind
+		if issuerKind == "" {
+			issuerKind = "ClusterIssuer"
+		}
+		issuer := fmt.Sprintf("%s %s", issuerKind, tls.Issuer)
+		endpointPath := path.Index(i)
+		for other, issuedBy := range issuers {
+			if other != issuer {
+				errs = append(errs, field.Invalid(
+					endpointPath.Child("tls", "issuer"),
+					tls.Issuer,
+					fmt.Sprintf("must be the same issuer as %s (%s)", issuedBy, other),
+				))
+			}
+		}
+		if len(issuers) == 0 {
+			issuers[issuer] = endpointPath.String()
+		}
+	}
+
+	return errs
+}
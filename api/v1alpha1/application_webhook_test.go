@@ -0,0 +1,579 @@
+package v1alpha1
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestApplication_Default(t *testing.T) {
+	tests := []struct {
+		name string
+		app  *Application
+		want ApplicationSpec
+	}{
+		{
+			name: "defaults new application",
+			app: &Application{
+				Spec: ApplicationSpec{
+					Endpoints: ApplicationEndpoints{{Port: 8080, Domain: "shop.example.com"}},
+					Processes: map[string]ApplicationProcess{
+						"api": {Endpoints: ApplicationEndpoints{{Port: 3000, Domain: "api.example.com"}}},
+					},
+				},
+			},
+			want: ApplicationSpec{
+				Replicas: 1,
+				Endpoints: ApplicationEndpoints{
+					{Port: 8080, Domain: "shop.example.com", DomainPath: "/"},
+				},
+				Processes: map[string]ApplicationProcess{
+					"api": {Endpoints: ApplicationEndpoints{
+						{Port: 3000, Domain: "api.example.com", DomainPath: "/"},
+					}},
+				},
+			},
+		},
+		{
+			name: "keeps existing application scaled to zero",
+			app: &Application{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.Now()},
+				Spec: ApplicationSpec{
+					Runtime: ApplicationRuntime{Size: PerformanceMachineType},
+				},
+			},
+			want: ApplicationSpec{
+				Runtime: ApplicationRuntime{Size: PerformanceMachineType},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.app.Default()
+			if !reflect.DeepEqual(tt.app.Spec, tt.want) {
+				t.Errorf("Application.Default() = %+v, want %+v", tt.app.Spec, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplication_ValidateCreate(t *testing.T) {
+	valid := func() *Application {
+		return &Application{
+			ObjectMeta: metav1.ObjectMeta{Name: "shop"},
+			Spec: ApplicationSpec{
+				Runtime: ApplicationRuntime{Image: "shop:v1", Size: StandardMachineType},
+				Endpoints: ApplicationEndpoints{
+					{Port: 8080, Domain: "shop.example.com", DomainPath: "/"},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		mutate  func(app *Application)
+		wantErr string
+	}{
+		{
+			name:   "valid application",
+			mutate: func(app *Application) {},
+		},
+		{
+			name: "wildcard domain",
+			mutate: func(app *Application) {
+				app.Spec.Endpoints[0].Domain = "*.example.com"
+			},
+		},
+		{
+			name: "resource overrides",
+			mutate: func(app *Application) {
+				app.Spec.Runtime.Resources = &corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("768Mi")},
+				}
+			},
+		},
+		{
+			name: "invalid process name",
+			mutate: func(app *Application) {
+				app.Spec.Processes = map[string]ApplicationProcess{"Worker": {}}
+			},
+			wantErr: "spec.processes[Worker]",
+		},
+		{
+			name: "reserved process name",
+			mutate: func(app *Application) {
+				app.Spec.Processes = map[string]ApplicationProcess{"release": {}}
+			},
+			wantErr: "spec.processes[release]",
+		},
+		{
+			name: "process resource name too long",
+			mutate: func(app *Application) {
+				app.Spec.Processes = map[string]ApplicationProcess{strings.Repeat("w", 60): {}}
+			},
+			wantErr: "must be no more than 63 characters",
+		},
+		{
+			name: "health check port is not an endpoint",
+			mutate: func(app *Application) {
+				app.Spec.HealthCheck = &HealthCheck{HTTP: &HTTPHealthCheck{Path: "/up", Port: 9090}}
+			},
+			wantErr: "spec.healthCheck.http.port",
+		},
+		{
+			name: "inherited health check port is not a process endpoint",
+			mutate: func(app *Application) {
+				app.Spec.HealthCheck = &HealthCheck{TCP: &TCPHealthCheck{Port: 8080}}
+				app.Spec.Processes = map[string]ApplicationProcess{
+					"web": {Endpoints: ApplicationEndpoints{{Port: 3000}}},
+				}
+			},
+			wantErr: "spec.healthCheck.tcp.port",
+		},
+		{
+			name: "process health check on its endpoint",
+			mutate: func(app *Application) {
+				app.Spec.HealthCheck = &HealthCheck{TCP: &TCPHealthCheck{Port: 8080}}
+				app.Spec.Processes = map[string]ApplicationProcess{
+					"web": {
+						Endpoints:   ApplicationEndpoints{{Port: 3000}},
+						HealthCheck: &HealthCheck{HTTP: &HTTPHealthCheck{Path: "/up", Port: 3000}},
+					},
+				}
+			},
+		},
+		{
+			name: "multiple health checks",
+			mutate: func(app *Application) {
+				app.Spec.HealthCheck = &HealthCheck{
+					HTTP: &HTTPHealthCheck{Path: "/up"},
+					Exec: &ExecHealthCheck{Command: []string{"true"}},
+				}
+			},
+			wantErr: "spec.healthCheck",
+		},
+		{
+			name: "invalid config var name",
+			mutate: func(app *Application) {
+				app.Spec.Config.Vars = map[string]string{"DATABASE URL": "postgres://"}
+			},
+			wantErr: "spec.config.vars[DATABASE URL]",
+		},
+		{
+			name: "config ref without source",
+			mutate: func(app *Application) {
+				app.Spec.Config.Refs = []ConfigVarRef{{Name: "API_KEY"}}
+			},
+			wantErr: "spec.config.refs[0]",
+		},
+		{
+			name: "config ref with invalid secret name",
+			mutate: func(app *Application) {
+				app.Spec.Config.Refs = []ConfigVarRef{{
+					Name:         "API_KEY",
+					SecretKeyRef: &ConfigKeySelector{Name: "Shop_Secrets", Key: "api-key"},
+				}}
+			},
+			wantErr: "spec.config.refs[0].secretKeyRef.name",
+		},
+		{
+			name: "invalid config secret name",
+			mutate: func(app *Application) {
+				app.Spec.Config.Secrets = []string{"Shop_Secrets"}
+			},
+			wantErr: "spec.config.secrets[0]",
+		},
+		{
+			name: "malformed image",
+			mutate: func(app *Application) {
+				app.Spec.Runtime.Image = "Shop:v1"
+			},
+			wantErr: "spec.runtime.image",
+		},
+		{
+			name: "tls without issuer or secret",
+			mutate: func(app *Application) {
+				app.Spec.TLS = &EndpointTLS{IssuerKind: "ClusterIssuer"}
+			},
+			wantErr: "spec.tls",
+		},
+		{
+			name: "endpoint tls without issuer or secret",
+			mutate: func(app *Application) {
+				app.Spec.Endpoints[0].TLS = &EndpointTLS{}
+			},
+			wantErr: "spec.endpoints[0].tls",
+		},
+		{
+			name: "disabled endpoint tls",
+			mutate: func(app *Application) {
+				app.Spec.TLS = &EndpointTLS{Issuer: "letsencrypt"}
+				app.Spec.Endpoints[0].TLS = &EndpointTLS{Disabled: true}
+			},
+		},
+		{
+			name: "mixed tls issuers",
+			mutate: func(app *Application) {
+				app.Spec.TLS = &EndpointTLS{Issuer: "letsencrypt"}
+				app.Spec.Processes = map[string]ApplicationProcess{"admin": {
+					Endpoints: ApplicationEndpoints{{
+						Port:   8080,
+						Domain: "admin.example.com",
+						TLS:    &EndpointTLS{Issuer: "internal-ca", IssuerKind: "Issuer"},
+					}},
+				}}
+			},
+			wantErr: "spec.processes[admin].endpoints[0].tls.issuer",
+		},
+		{
+			name: "same tls issuer and existing certificate",
+			mutate: func(app *Application) {
+				app.Spec.TLS = &EndpointTLS{Issuer: "letsencrypt"}
+				app.Spec.Processes = map[string]ApplicationProcess{"admin": {
+					Endpoints: ApplicationEndpoints{
+						{Port: 8080, Domain: "admin.example.com"},
+						{Port: 8080, Domain: "legacy.example.com", TLS: &EndpointTLS{SecretName: "legacy-cert"}},
+					},
+				}}
+			},
+		},
+		{
+			name: "invalid size",
+			mutate: func(app *Application) {
+				app.Spec.Runtime.Size = "Huge_Size"
+			},
+			wantErr: "spec.runtime.size",
+		},
+		{
+			name: "invalid process size",
+			mutate: func(app *Application) {
+				app.Spec.Processes = map[string]ApplicationProcess{"worker": {Size: "Huge_Size"}}
+			},
+			wantErr: "spec.processes[worker].size",
+		},
+		{
+			name: "invalid hostname",
+			mutate: func(app *Application) {
+				app.Spec.Endpoints[0].Domain = "shop_example.com"
+			},
+			wantErr: "spec.endpoints[0].domain",
+		},
+		{
+			name: "path without leading slash",
+			mutate: func(app *Application) {
+				app.Spec.Endpoints[0].DomainPath = "api"
+			},
+			wantErr: "spec.endpoints[0].domain_path",
+		},
+		{
+			name: "same domain and path to different ports",
+			mutate: func(app *Application) {
+				app.Spec.Endpoints = append(app.Spec.Endpoints, ApplicationEndpoint{
+					Port: 9090, Domain: "shop.example.com", DomainPath: "/",
+				})
+			},
+			wantErr: "spec.endpoints[1]",
+		},
+		{
+			name: "same domain and path in different processes",
+			mutate: func(app *Application) {
+				app.Spec.Processes = map[string]ApplicationProcess{
+					"api": {Endpoints: ApplicationEndpoints{{Port: 3000, Domain: "api.example.com", DomainPath: "/"}}},
+					"web": {Endpoints: ApplicationEndpoints{{Port: 8080, Domain: "api.example.com"}}},
+				}
+			},
+			wantErr: "spec.processes[web].endpoints[0]",
+		},
+		{
+			name: "invalid schedule size",
+			mutate: func(app *Application) {
+				app.Spec.Schedules = map[string]ApplicationSchedule{
+					"digest": {Schedule: "0 3 * * *", Command: []string{"send-digest"}, Size: "Huge_Size"},
+				}
+			},
+			wantErr: "spec.schedules[digest].size",
+		},
+		{
+			name: "schedule CronJob name too long",
+			mutate: func(app *Application) {
+				app.Spec.Schedules = map[string]ApplicationSchedule{
+					strings.Repeat("d", 48): {Schedule: "0 3 * * *", Command: []string{"send-digest"}},
+				}
+			},
+			wantErr: "spec.schedules[" + strings.Repeat("d", 48) + "]",
+		},
+		{
+			name: "invalid schedule name",
+			mutate: func(app *Application) {
+				app.Spec.Schedules = map[string]ApplicationSchedule{
+					"Send_Digest": {Schedule: "0 3 * * *", Command: []string{"send-digest"}},
+				}
+			},
+			wantErr: "spec.schedules[Send_Digest]",
+		},
+		{
+			name: "request above overridden limit",
+			mutate: func(app *Application) {
+				app.Spec.Runtime.Resources = &corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+					Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+				}
+			},
+			wantErr: "spec.runtime.resources.requests[memory]",
+		},
+		{
+			name: "negative limit",
+			mutate: func(app *Application) {
+				app.Spec.Runtime.Resources = &corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("-1")},
+				}
+			},
+			wantErr: "spec.runtime.resources.limits[cpu]",
+		},
+		{
+			name: "volumes mounted at the same path",
+			mutate: func(app *Application) {
+				app.Spec.Volumes = []ApplicationVolume{
+					{Name: "data", MountPath: "/data", Size: resource.MustParse("1Gi")},
+					{Name: "uploads", MountPath: "/data", Size: resource.MustParse("1Gi")},
+				}
+			},
+			wantErr: "spec.volumes[1].mountPath",
+		},
+		{
+			name: "volume without size",
+			mutate: func(app *Application) {
+				app.Spec.Volumes = []ApplicationVolume{{Name: "data", MountPath: "/data"}}
+			},
+			wantErr: "spec.volumes[0].size",
+		},
+		{
+			name: "read write once volume shared by replicas",
+			mutate: func(app *Application) {
+				app.Spec.Replicas = 2
+				app.Spec.Volumes = []ApplicationVolume{{Name: "data", MountPath: "/data", Size: resource.MustParse("1Gi")}}
+			},
+			wantErr: "spec.volumes[0].accessMode",
+		},
+		{
+			name: "read write once volume shared by processes",
+			mutate: func(app *Application) {
+				app.Spec.Processes = map[string]ApplicationProcess{"web": {Replicas: 1}, "worker": {Replicas: 1}}
+				app.Spec.Volumes = []ApplicationVolume{
+					{Name: "data", MountPath: "/data", Size: resource.MustParse("1Gi"), AccessMode: corev1.ReadWriteOncePod},
+				}
+			},
+			wantErr: "spec.volumes[0].accessMode",
+		},
+		{
+			name: "read write once volume of autoscaled application",
+			mutate: func(app *Application) {
+				app.Spec.Replicas = 1
+				app.Spec.Autoscale = &Autoscale{Min: 1, Max: 3}
+				app.Spec.Volumes = []ApplicationVolume{{Name: "data", MountPath: "/data", Size: resource.MustParse("1Gi")}}
+			},
+			wantErr: "spec.volumes[0].accessMode",
+		},
+		{
+			name: "read write many volume shared by replicas",
+			mutate: func(app *Application) {
+				app.Spec.Replicas = 2
+				app.Spec.Volumes = []ApplicationVolume{
+					{Name: "data", MountPath: "/data", Size: resource.MustParse("1Gi"), AccessMode: corev1.ReadWriteMany},
+				}
+			},
+		},
+		{
+			name: "read write once volume of stateful replicas",
+			mutate: func(app *Application) {
+				app.Spec.Replicas = 2
+				app.Spec.Workload = WorkloadStateful
+				app.Spec.Volumes = []ApplicationVolume{{Name: "data", MountPath: "/data", Size: resource.MustParse("1Gi")}}
+			},
+		},
+		{
+			name: "invalid port",
+			mutate: func(app *Application) {
+				app.Spec.Endpoints[0].Port = 0
+			},
+			wantErr: "spec.endpoints[0].port",
+		},
+		{
+			name: "config ref repeating a var",
+			mutate: func(app *Application) {
+				app.Spec.Config.Vars = map[string]string{"DATABASE_URL": "postgres://external"}
+				app.Spec.Config.Refs = []ConfigVarRef{{
+					Name:         "DATABASE_URL",
+					SecretKeyRef: &ConfigKeySelector{Name: "database", Key: "url"},
+				}}
+			},
+			wantErr: "spec.config.refs[0].name",
+		},
+		{
+			name: "addons",
+			mutate: func(app *Application) {
+				app.Spec.Addons = []AddonReference{{Name: "db"}, {Name: "analytics", Env: "ANALYTICS_DATABASE_URL"}}
+			},
+		},
+		{
+			name: "addons setting the same env",
+			mutate: func(app *Application) {
+				app.Spec.Addons = []AddonReference{{Name: "db", Env: "DATABASE_URL"}, {Name: "analytics", Env: "DATABASE_URL"}}
+			},
+			wantErr: "spec.addons[1].env",
+		},
+		{
+			name: "bindings",
+			mutate: func(app *Application) {
+				app.Spec.Bindings = []ApplicationBinding{
+					{Name: "api", Application: "api"},
+					{Name: "payments", Application: "payments", Namespace: "billing", Port: 8080},
+				}
+			},
+		},
+		{
+			name: "invalid binding port",
+			mutate: func(app *Application) {
+				app.Spec.Bindings = []ApplicationBinding{{Name: "api", Application: "api", Port: 70000}}
+			},
+			wantErr: "spec.bindings[0].port",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := valid()
+			tt.mutate(app)
+
+			err := app.ValidateCreate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Application.ValidateCreate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Application.ValidateCreate() error = %v, want error on %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApplication_ValidateUpdate(t *testing.T) {
+	app := func(workload WorkloadType, volumes ...ApplicationVolume) *Application {
+		return &Application{
+			ObjectMeta: metav1.ObjectMeta{Name: "search"},
+			Spec: ApplicationSpec{
+				Runtime:  ApplicationRuntime{Image: "getmeili/meilisearch:v1.1", Size: StandardMachineType},
+				Workload: workload,
+				Volumes:  volumes,
+			},
+		}
+	}
+	data := ApplicationVolume{Name: "data", MountPath: "/meili_data", Size: resource.MustParse("1Gi")}
+	invalid := func(app *Application) *Application {
+		app.Spec.Config.Vars = map[string]string{"1_INVALID": "value"}
+		return app
+	}
+
+	volume := func(mutate func(volume *ApplicationVolume)) ApplicationVolume {
+		volume := data
+		mutate(&volume)
+		return volume
+	}
+
+	tests := []struct {
+		name    string
+		old     *Application
+		app     *Application
+		wantErr string
+	}{
+		{
+			name: "changes workload without volumes",
+			old:  app(WorkloadStateless),
+			app:  app(WorkloadStateful),
+		},
+		{
+			name: "keeps defaulted workload with volumes",
+			old:  app("", data),
+			app:  app(WorkloadStateless, data),
+		},
+		{
+			name:    "changes workload with volumes",
+			old:     app(WorkloadStateless, data),
+			app:     app(WorkloadStateful, data),
+			wantErr: "spec.workload",
+		},
+		{
+			name:    "changes workload while adding volumes",
+			old:     app(WorkloadStateful),
+			app:     app(WorkloadStateless, data),
+			wantErr: "spec.workload",
+		},
+		{
+			name: "grows volume",
+			old:  app(WorkloadStateless, data),
+			app:  app(WorkloadStateless, volume(func(v *ApplicationVolume) { v.Size = resource.MustParse("2Gi") })),
+		},
+		{
+			name:    "shrinks volume",
+			old:     app(WorkloadStateless, data),
+			app:     app(WorkloadStateless, volume(func(v *ApplicationVolume) { v.Size = resource.MustParse("512Mi") })),
+			wantErr: "spec.volumes[0].size",
+		},
+		{
+			name:    "changes volume storage class",
+			old:     app(WorkloadStateless, data),
+			app:     app(WorkloadStateless, volume(func(v *ApplicationVolume) { v.StorageClassName = &[]string{"fast"}[0] })),
+			wantErr: "spec.volumes[0].storageClassName",
+		},
+		{
+			name:    "changes volume access mode",
+			old:     app(WorkloadStateless, data),
+			app:     app(WorkloadStateless, volume(func(v *ApplicationVolume) { v.AccessMode = corev1.ReadWriteMany })),
+			wantErr: "spec.volumes[0].accessMode",
+		},
+		{
+			name: "sets default volume access mode",
+			old:  app(WorkloadStateless, data),
+			app:  app(WorkloadStateless, volume(func(v *ApplicationVolume) { v.AccessMode = corev1.ReadWriteOnce })),
+		},
+		{
+			name: "adds finalizer to application invalid since its creation",
+			old:  invalid(app(WorkloadStateless)),
+			app: func() *Application {
+				app := invalid(app(WorkloadStateless))
+				app.Finalizers = []string{"operators.k4indie.io/finalizer"}
+				return app
+			}(),
+		},
+		{
+			name: "deletes application invalid since its creation",
+			old:  invalid(app(WorkloadStateless)),
+			app: func() *Application {
+				app := invalid(app(WorkloadStateless))
+				app.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+				return app
+			}(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.app.ValidateUpdate(tt.old)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Application.ValidateUpdate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Application.ValidateUpdate() error = %v, want error on %s", err, tt.wantErr)
+			}
+		})
+	}
+}
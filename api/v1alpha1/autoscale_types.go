@@ -0,0 +1,29 @@
+package v1alpha1
+
+// Autoscale configures horizontal autoscaling of a process. When set, the
+// number of replicas is managed by a HorizontalPodAutoscaler instead of
+// the Replicas field.
+type Autoscale struct {
+	// Min is the lowest number of replicas.
+	//+optional
+	//+kubebuilder:default=1
+	//+kubebuilder:validation:Minimum=1
+	Min int32 `json:"min,omitempty"`
+
+	// Max is the highest number of replicas.
+	//+kubebuilder:validation:Minimum=1
+	Max int32 `json:"max"`
+
+	// TargetCPU is the average CPU utilization to scale at, as a
+	// percentage of the requested CPU. Defaults to 80 when neither
+	// TargetCPU nor TargetMemory are set.
+	//+optional
+	//+kubebuilder:validation:Minimum=1
+	TargetCPU *int32 `json:"targetCPU,omitempty"`
+
+	// TargetMemory is the average memory utilization to scale at, as a
+	// percentage of the requested memory.
+	//+optional
+	//+kubebuilder:validation:Minimum=1
+	TargetMemory *int32 `json:"targetMemory,omitempty"`
+}
@@ -0,0 +1,46 @@
+package v1alpha1
+
+import "strings"
+
+// AllowedBindingNamespacesAnnotation can be set on a namespace to the comma
+// separated list of namespaces whose applications may bind to the
+// applications of the namespace, e.g. "frontend,staging", or "*" to allow
+// all namespaces. Applications can always bind to applications of their own
+// namespace, while other namespaces are denied without the annotation.
+const AllowedBindingNamespacesAnnotation = "k4indie.io/allowed-binding-namespaces"
+
+// ApplicationBinding binds an application to the endpoint of another
+// application, whose in-cluster address is exposed as the <NAME>_URL,
+// <NAME>_HOST and <NAME>_PORT environment variables.
+type ApplicationBinding struct {
+	// Name of the binding, which prefixes the environment variables in upper
+	// case with dashes replaced by underscores, e.g. "payments-api" exposes
+	// PAYMENTS_API_URL.
+	//+kubebuilder:validation:Pattern=`^[a-z]([-a-z0-9]*[a-z0-9])?$`
+	//+kubebuilder:validation:MaxLength=63
+	Name string `json:"name"`
+
+	// Application is the name of the bound application.
+	//+kubebuilder:validation:MinLength=1
+	Application string `json:"application"`
+
+	// Namespace of the bound application. Defaults to the namespace of this
+	// application. The namespace must allow bindings from this namespace
+	// with the k4indie.io/allowed-binding-namespaces annotation.
+	//+optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Process of the bound application. Defaults to the first process with
+	// endpoints, by name.
+	//+optional
+	Process string `json:"process,omitempty"`
+
+	// Port of the bound process. Defaults to the port of its first endpoint.
+	//+optional
+	Port int32 `json:"port,omitempty"`
+}
+
+// EnvPrefix returns the prefix of the environment variables of the binding.
+func (b *ApplicationBinding) EnvPrefix() string {
+	return strings.ToUpper(strings.ReplaceAll(b.Name, "-", "_"))
+}
@@ -0,0 +1,190 @@
+package v1alpha1
+
+import (
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ConfigLabel can be set to "true" on the Secrets referenced by the config
+// of applications, so the applications are rolled out as soon as the
+// Secrets change. The operator only watches the Secrets with this label,
+// other Secrets are picked up on the next reconciliation of the
+// applications.
+const ConfigLabel = "k4indie.io/config"
+
+// ApplicationConfig is the set of config vars exposed to the application
+// as environment variables.
+type ApplicationConfig struct {
+	// Vars are literal config vars keyed by the environment variable name.
+	//+optional
+	Vars map[string]string `json:"vars,omitempty"`
+
+	// Refs are config vars whose values are read from a key on a Secret
+	// or ConfigMap in the application's namespace.
+	//+optional
+	Refs []ConfigVarRef `json:"refs,omitempty"`
+
+	// Secrets is a list of Secret names whose keys are all imported as
+	// config vars.
+	//+optional
+	Secrets []string `json:"secrets,omitempty"`
+
+	// ConfigMaps is a list of ConfigMap names whose keys are all imported
+	// as config vars.
+	//+optional
+	ConfigMaps []string `json:"configMaps,omitempty"`
+}
+
+// ConfigVarRef is a config var that is sourced from a Secret or ConfigMap key.
+// Exactly one of SecretKeyRef or ConfigMapKeyRef should be set.
+type ConfigVarRef struct {
+	// Name of the environment variable to expose the value as.
+	Name string `json:"name"`
+
+	// SecretKeyRef selects a key of a Secret.
+	//+optional
+	SecretKeyRef *ConfigKeySelector `json:"secretKeyRef,omitempty"`
+
+	// ConfigMapKeyRef selects a key of a ConfigMap.
+	//+optional
+	ConfigMapKeyRef *ConfigKeySelector `json:"configMapKeyRef,omitempty"`
+}
+
+// ConfigKeySelector selects a key on a Secret or ConfigMap.
+type ConfigKeySelector struct {
+	// Name of the Secret or ConfigMap.
+	Name string `json:"name"`
+
+	// Key to read the value from.
+	Key string `json:"key"`
+
+	// Optional allows the application to start even if the referenced
+	// object or key does not exist.
+	//+optional
+	Optional bool `json:"optional,omitempty"`
+}
+
+// AsEnvVars converts the literal vars and references to container
+// environment variables. Literal vars are sorted by name so the rendered
+// container is stable between reconciles, and references are appended
+// afterwards in the order they are declared.
+func (c *ApplicationConfig) AsEnvVars() []corev1.EnvVar {
+	envVars := make([]corev1.EnvVar, 0, len(c.Vars)+len(c.Refs))
+
+	names := make([]string, 0, len(c.Vars))
+	for name := range c.Vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		envVars = append(envVars, corev1.EnvVar{
+			Name:  name,
+			Value: c.Vars[name],
+		})
+	}
+
+	for _, ref := range c.Refs {
+		envVars = append(envVars, ref.AsEnvVar())
+	}
+
+	return envVars
+}
+
+// AsEnvVar converts the reference to a container environment variable.
+func (r *ConfigVarRef) AsEnvVar() corev1.EnvVar {
+	envVar := corev1.EnvVar{Name: r.Name}
+
+	switch {
+	case r.SecretKeyRef != nil:
+		envVar.ValueFrom = &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: r.SecretKeyRef.Name},
+				Key:                  r.SecretKeyRef.Key,
+				Optional:             &[]bool{r.SecretKeyRef.Optional}[0],
+			},
+		}
+	case r.ConfigMapKeyRef != nil:
+		envVar.ValueFrom = &corev1.EnvVarSource{
+			ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: r.ConfigMapKeyRef.Name},
+				Key:                  r.ConfigMapKeyRef.Key,
+				Optional:             &[]bool{r.ConfigMapKeyRef.Optional}[0],
+			},
+		}
+	}
+
+	return envVar
+}
+
+// AsEnvFromSources converts the whole Secret and ConfigMap imports to
+// container env sources. ConfigMaps are listed first so that values
+// from Secrets take precedence on conflicting keys.
+func (c *ApplicationConfig) AsEnvFromSources() []corev1.EnvFromSource {
+	sources := make([]corev1.EnvFromSource, 0, len(c.ConfigMaps)+len(c.Secrets))
+
+	for _, name := range c.ConfigMaps {
+		sources = append(sources, corev1.EnvFromSource{
+			ConfigMapRef: &corev1.ConfigMapEnvSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: name},
+			},
+		})
+	}
+
+	for _, name := range c.Secrets {
+		sources = append(sources, corev1.EnvFromSource{
+			SecretRef: &corev1.SecretEnvSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: name},
+			},
+		})
+	}
+
+	return sources
+}
+
+// SecretNames returns the unique names of all Secrets referenced by the
+// config, either through a key reference or a whole Secret import.
+func (c *ApplicationConfig) SecretNames() []string {
+	names := make([]string, 0, len(c.Secrets)+len(c.Refs))
+	names = append(names, c.Secrets...)
+
+	for _, ref := range c.Refs {
+		if ref.SecretKeyRef != nil {
+			names = append(names, ref.SecretKeyRef.Name)
+		}
+	}
+
+	return uniqueSortedNames(names)
+}
+
+// ConfigMapNames returns the unique names of all ConfigMaps referenced by
+// the config, either through a key reference or a whole ConfigMap import.
+func (c *ApplicationConfig) ConfigMapNames() []string {
+	names := make([]string, 0, len(c.ConfigMaps)+len(c.Refs))
+	names = append(names, c.ConfigMaps...)
+
+	for _, ref := range c.Refs {
+		if ref.ConfigMapKeyRef != nil {
+			names = append(names, ref.ConfigMapKeyRef.Name)
+		}
+	}
+
+	return uniqueSortedNames(names)
+}
+
+func uniqueSortedNames(names []string) []string {
+	result := make([]string, 0, len(names))
+	nameSet := map[string]struct{}{}
+
+	for _, name := range names {
+		if _, exists := nameSet[name]; exists {
+			continue
+		}
+		nameSet[name] = struct{}{}
+		result = append(result, name)
+	}
+	sort.Strings(result)
+
+	return result
+}
@@ -0,0 +1,167 @@
+package v1alpha1
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestApplicationConfig_AsEnvVars(t *testing.T) {
+	tests := []struct {
+		name string
+		c    *ApplicationConfig
+		want []corev1.EnvVar
+	}{
+		{
+			name: "empty",
+			c:    &ApplicationConfig{},
+			want: []corev1.EnvVar{},
+		},
+		{
+			name: "vars sorted before refs",
+			c: &ApplicationConfig{
+				Vars: map[string]string{
+					"PORT":     "8080",
+					"APP_NAME": "k4indie",
+				},
+				Refs: []ConfigVarRef{
+					{
+						Name: "DATABASE_URL",
+						SecretKeyRef: &ConfigKeySelector{
+							Name: "database",
+							Key:  "url",
+						},
+					},
+					{
+						Name: "LOG_LEVEL",
+						ConfigMapKeyRef: &ConfigKeySelector{
+							Name:     "logging",
+							Key:      "level",
+							Optional: true,
+						},
+					},
+				},
+			},
+			want: []corev1.EnvVar{
+				{
+					Name:  "APP_NAME",
+					Value: "k4indie",
+				},
+				{
+					Name:  "PORT",
+					Value: "8080",
+				},
+				{
+					Name: "DATABASE_URL",
+					ValueFrom: &corev1.EnvVarSource{
+						SecretKeyRef: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "database"},
+							Key:                  "url",
+							Optional:             &[]bool{false}[0],
+						},
+					},
+				},
+				{
+					Name: "LOG_LEVEL",
+					ValueFrom: &corev1.EnvVarSource{
+						ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "logging"},
+							Key:                  "level",
+							Optional:             &[]bool{true}[0],
+						},
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.c.AsEnvVars(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ApplicationConfig.AsEnvVars() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplicationConfig_AsEnvFromSources(t *testing.T) {
+	tests := []struct {
+		name string
+		c    *ApplicationConfig
+		want []corev1.EnvFromSource
+	}{
+		{
+			name: "empty",
+			c:    &ApplicationConfig{},
+			want: []corev1.EnvFromSource{},
+		},
+		{
+			name: "config maps before secrets",
+			c: &ApplicationConfig{
+				Secrets:    []string{"credentials"},
+				ConfigMaps: []string{"settings"},
+			},
+			want: []corev1.EnvFromSource{
+				{
+					ConfigMapRef: &corev1.ConfigMapEnvSource{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "settings"},
+					},
+				},
+				{
+					SecretRef: &corev1.SecretEnvSource{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "credentials"},
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.c.AsEnvFromSources(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ApplicationConfig.AsEnvFromSources() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplicationConfig_SecretNames(t *testing.T) {
+	tests := []struct {
+		name string
+		c    *ApplicationConfig
+		want []string
+	}{
+		{
+			name: "empty",
+			c:    &ApplicationConfig{},
+			want: []string{},
+		},
+		{
+			name: "imports and refs deduplicated",
+			c: &ApplicationConfig{
+				Secrets: []string{"credentials", "api-keys"},
+				Refs: []ConfigVarRef{
+					{
+						Name:         "DATABASE_URL",
+						SecretKeyRef: &ConfigKeySelector{Name: "database", Key: "url"},
+					},
+					{
+						Name:         "STRIPE_KEY",
+						SecretKeyRef: &ConfigKeySelector{Name: "api-keys", Key: "stripe"},
+					},
+					{
+						Name:            "LOG_LEVEL",
+						ConfigMapKeyRef: &ConfigKeySelector{Name: "logging", Key: "level"},
+					},
+				},
+			},
+			want: []string{"api-keys", "credentials", "database"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.c.SecretNames(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ApplicationConfig.SecretNames() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package v1alpha1
+
+// DeletionPolicy defines what happens to the resources of an application
+// when it is deleted.
+// +kubebuilder:validation:Enum=Delete;Orphan;RetainData
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete deletes all the resources of the application,
+	// including artifacts it does not own such as issued certificates.
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+
+	// DeletionPolicyOrphan keeps all the resources of the application
+	// running, e.g. to migrate it to another namespace.
+	DeletionPolicyOrphan DeletionPolicy = "Orphan"
+
+	// DeletionPolicyRetainData deletes the workloads of the application but
+	// keeps its data, such as its release history and volumes.
+	DeletionPolicyRetainData DeletionPolicy = "RetainData"
+)
@@ -0,0 +1,87 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AllowedDomainsAnnotation can be set on a namespace by cluster admins to
+// the comma separated list of domains the applications of the namespace
+// may claim, e.g. "shop.example.com,*.example.org". A wildcard allows all
+// the subdomains of a domain. Applications of namespaces without the
+// annotation may claim any domain that is not claimed yet. A domain listed
+// itself, rather than through a wildcard, is also granted to the namespace,
+// whose applications may then claim the paths of the domain left free by
+// the namespace owning it.
+const AllowedDomainsAnnotation = "k4indie.io/allowed-domains"
+
+// DomainClaimSpec records the application a domain belongs to.
+// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="domain claims are immutable"
+type DomainClaimSpec struct {
+	// Domain that is claimed, in lower case.
+	Domain string `json:"domain"`
+
+	// Path prefix of the domain that is claimed. Other applications of the
+	// namespace owning the domain, or of namespaces it is granted to, may
+	// claim the other paths of the domain.
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// ApplicationRef is the application that claimed the domain.
+	ApplicationRef ApplicationReference `json:"applicationRef"`
+}
+
+// ApplicationReference references an application in any namespace.
+type ApplicationReference struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Domain",type=string,JSONPath=`.spec.domain`
+//+kubebuilder:printcolumn:name="Path",type=string,JSONPath=`.spec.path`
+//+kubebuilder:printcolumn:name="Namespace",type=string,JSONPath=`.spec.applicationRef.namespace`
+//+kubebuilder:printcolumn:name="Application",type=string,JSONPath=`.spec.applicationRef.name`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// DomainClaim is the Schema for the domainclaims API.
+// Domain claims are created by the operator on a first come first served
+// basis, so a path of a domain is only routed to the application that
+// claimed it first. The oldest claim of a domain owns the domain for its
+// namespace. Claims are released when the application stops using
+// the path or is deleted.
+type DomainClaim struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec DomainClaimSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// DomainClaimList contains a list of DomainClaim
+type DomainClaimList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DomainClaim `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DomainClaim{}, &DomainClaimList{})
+}
@@ -0,0 +1,80 @@
+package v1alpha1
+
+import "errors"
+
+// HealthCheck configures how the health of the application is checked.
+// At most one of HTTP, TCP or Exec should be set. When none is set, the
+// first endpoint port is checked over TCP.
+// The check is rendered as the readiness, liveness and startup probes of
+// the application container.
+type HealthCheck struct {
+	// HTTP checks that a GET request to a path returns a successful status.
+	//+optional
+	HTTP *HTTPHealthCheck `json:"http,omitempty"`
+
+	// TCP checks that a port accepts connections.
+	//+optional
+	TCP *TCPHealthCheck `json:"tcp,omitempty"`
+
+	// Exec checks that a command run in the container exits with 0.
+	//+optional
+	Exec *ExecHealthCheck `json:"exec,omitempty"`
+
+	// Disabled turns off the health check, including the default one.
+	//+optional
+	Disabled bool `json:"disabled,omitempty"`
+
+	// StartupTimeoutSeconds is how long the application has to pass its
+	// first check before it is restarted.
+	//+optional
+	//+kubebuilder:default=60
+	//+kubebuilder:validation:Minimum=1
+	StartupTimeoutSeconds int32 `json:"startupTimeoutSeconds,omitempty"`
+
+	// PeriodSeconds is how often the check is performed.
+	//+optional
+	//+kubebuilder:default=10
+	//+kubebuilder:validation:Minimum=1
+	PeriodSeconds int32 `json:"periodSeconds,omitempty"`
+
+	// TimeoutSeconds is how long a single check can take.
+	//+optional
+	//+kubebuilder:default=1
+	//+kubebuilder:validation:Minimum=1
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+
+	// FailureThreshold is the number of consecutive failed checks after
+	// which the application stops receiving traffic and is restarted.
+	//+optional
+	//+kubebuilder:default=3
+	//+kubebuilder:validation:Minimum=1
+	FailureThreshold int32 `json:"failureThreshold,omitempty"`
+}
+
+// HTTPHealthCheck checks the application over HTTP.
+type HTTPHealthCheck struct {
+	// Path to request.
+	//+kubebuilder:default="/"
+	Path string `json:"path,omitempty"`
+
+	// Port to request. It must be the port of one of the endpoints and
+	// defaults to the first endpoint port.
+	//+optional
+	Port int32 `json:"port,omitempty"`
+}
+
+// TCPHealthCheck checks the application by opening a TCP connection.
+type TCPHealthCheck struct {
+	// Port to connect to. It must be the port of one of the endpoints and
+	// defaults to the first endpoint port.
+	//+optional
+	Port int32 `json:"port,omitempty"`
+}
+
+// ExecHealthCheck checks the application by running a command.
+type ExecHealthCheck struct {
+	// Command to run in the application container.
+	Command []string `json:"command"`
+}
+
+var ErrInvalidHealthCheckPort = errors.New("health check port is not an endpoint port")
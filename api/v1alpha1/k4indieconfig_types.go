@@ -0,0 +1,122 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// K4IndieConfigName is the name of the only K4IndieConfig read by the
+// operator.
+const K4IndieConfigName = "default"
+
+// K4IndieConfigSpec defines the defaults and policies the operator applies
+// to all the applications of the cluster.
+type K4IndieConfigSpec struct {
+	// Labels are added to all the resources generated by the operator.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Routing configures how the domains of the applications are routed.
+	// The Ingress router is used when the CRDs of the selected router are
+	// not installed.
+	// +optional
+	Routing *RoutingConfig `json:"routing,omitempty"`
+
+	// PodSecurityContext of the application pods. Defaults to running as
+	// non root with the runtime default seccomp profile.
+	// +optional
+	PodSecurityContext *corev1.PodSecurityContext `json:"podSecurityContext,omitempty"`
+
+	// SecurityContext of the application containers. Defaults to running as
+	// non root without privilege escalation or capabilities.
+	// +optional
+	SecurityContext *corev1.SecurityContext `json:"securityContext,omitempty"`
+
+	// ImagePullPolicy of the application containers. Defaults to
+	// IfNotPresent.
+	// +kubebuilder:validation:Enum=Always;IfNotPresent;Never
+	// +optional
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+
+	// DefaultSize is the RuntimeSize of the applications that don't set
+	// one. Defaults to basic.
+	// +optional
+	DefaultSize RuntimeSizeName `json:"defaultSize,omitempty"`
+}
+
+// RoutingConfig selects the router of the application domains. Fields that
+// are not set fall back to the flags of the operator.
+type RoutingConfig struct {
+	// Router is the kind of the generated routing objects.
+	// +kubebuilder:validation:Enum=ingress;gateway;traefik
+	// +optional
+	Router string `json:"router,omitempty"`
+
+	// IngressClassName is the class of the Ingresses generated by the
+	// ingress router.
+	// +optional
+	IngressClassName string `json:"ingressClassName,omitempty"`
+
+	// Gateway is the Gateway the HTTPRoutes generated by the gateway router
+	// attach to.
+	// +optional
+	Gateway *GatewayReference `json:"gateway,omitempty"`
+
+	// TraefikEntryPoints are the entry points of the IngressRoutes generated
+	// by the traefik router.
+	// +optional
+	TraefikEntryPoints []string `json:"traefikEntryPoints,omitempty"`
+}
+
+// GatewayReference references a Gateway API Gateway.
+type GatewayReference struct {
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Router",type=string,JSONPath=`.spec.routing.router`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// K4IndieConfig is the Schema for the k4indieconfigs API.
+// It lets cluster admins tune the operator without rebuilding its image.
+// Only the config named "default" is read, and changes are applied to all
+// the applications when it is updated.
+// +kubebuilder:validation:XValidation:rule="self.metadata.name == 'default'",message="the operator config must be named default"
+type K4IndieConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec K4IndieConfigSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// K4IndieConfigList contains a list of K4IndieConfig
+type K4IndieConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []K4IndieConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&K4IndieConfig{}, &K4IndieConfigList{})
+}
@@ -21,6 +21,37 @@ type ApplicationEndpoint struct {
 	//+optional
 	//+kubebuilder:default="/"
 	DomainPath string `json:"domain_path,omitempty"`
+
+	// TLS settings for the domain of this endpoint. It overrides the
+	// application TLS settings.
+	//+optional
+	TLS *EndpointTLS `json:"tls,omitempty"`
+}
+
+// EndpointTLS configures HTTPS for the domain of an endpoint.
+// Certificates are issued by cert-manager unless SecretName is set.
+type EndpointTLS struct {
+	// Disabled turns off TLS for the endpoint when it is enabled for the
+	// whole application.
+	//+optional
+	Disabled bool `json:"disabled,omitempty"`
+
+	// Issuer is the name of the cert-manager issuer used to issue the
+	// certificate of the domain.
+	//+optional
+	Issuer string `json:"issuer,omitempty"`
+
+	// IssuerKind is the kind of the cert-manager issuer.
+	//+optional
+	//+kubebuilder:default=ClusterIssuer
+	//+kubebuilder:validation:Enum=ClusterIssuer;Issuer
+	IssuerKind string `json:"issuerKind,omitempty"`
+
+	// SecretName is the name of an existing Secret of type kubernetes.io/tls
+	// holding the certificate of the domain. When set, no certificate is
+	// issued by cert-manager.
+	//+optional
+	SecretName string `json:"secretName,omitempty"`
 }
 
 type ApplicationEndpoints []ApplicationEndpoint
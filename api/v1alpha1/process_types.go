@@ -0,0 +1,36 @@
+package v1alpha1
+
+// ApplicationProcess is a single process type of an application, similar to
+// an entry in a Procfile. All processes share the application runtime image
+// and config but run their own command with their own scale.
+type ApplicationProcess struct {
+	// Command to launch the process. Defaults to the application command.
+	//+optional
+	Command []string `json:"command,omitempty"`
+
+	// Replicas is the number of instances of this process that should be created.
+	// Ignored when Autoscale is set.
+	//+optional
+	//+kubebuilder:default=1
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// Autoscale scales the number of instances of this process based on
+	// its resource usage.
+	//+optional
+	Autoscale *Autoscale `json:"autoscale,omitempty"`
+
+	// Size is the type of resources required to run this process.
+	// Defaults to the application runtime size.
+	//+optional
+	Size RuntimeSizeName `json:"size,omitempty"`
+
+	// Endpoints is the list of ports and domains that this process should expose.
+	// Only processes with endpoints receive traffic from the Service and Ingress.
+	//+optional
+	Endpoints ApplicationEndpoints `json:"endpoints,omitempty"`
+
+	// HealthCheck configures the health check of this process.
+	// Defaults to the application health check.
+	//+optional
+	HealthCheck *HealthCheck `json:"healthcheck,omitempty"`
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ReleaseSpec is an immutable snapshot of what an application ran at a
+// point in time. The time of the release is its creation timestamp.
+// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="releases are immutable"
+type ReleaseSpec struct {
+	// Application is the name of the application this release belongs to.
+	Application string `json:"application"`
+
+	// Version of the release. Versions increase by one for every new
+	// release of an application.
+	Version int32 `json:"version"`
+
+	// Image the application ran in this release.
+	Image RuntimeImage `json:"image"`
+
+	// Config of the application in this release. Only references to
+	// Secrets and ConfigMaps are recorded, not their content.
+	//+optional
+	Config ApplicationConfig `json:"config,omitempty"`
+
+	// ConfigHash is the hash of the config and the content of the Secrets
+	// and ConfigMaps it referenced at the time of the release.
+	ConfigHash string `json:"configHash"`
+
+	// Trigger describes the change that created this release,
+	// e.g. an image change or a rollback.
+	Trigger string `json:"trigger"`
+
+	// TriggeredBy is the field manager that last changed the application
+	// before this release, e.g. kubectl or a CI pipeline.
+	//+optional
+	TriggeredBy string `json:"triggeredBy,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:printcolumn:name="Application",type=string,JSONPath=`.spec.application`
+//+kubebuilder:printcolumn:name="Version",type=integer,JSONPath=`.spec.version`
+//+kubebuilder:printcolumn:name="Image",type=string,JSONPath=`.spec.image`
+//+kubebuilder:printcolumn:name="Trigger",type=string,JSONPath=`.spec.trigger`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// Release is the Schema for the releases API.
+// Releases are created by the operator for every change of an
+// application image or config, and can be rolled back to.
+type Release struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ReleaseSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ReleaseList contains a list of Release
+type ReleaseList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Release `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Release{}, &ReleaseList{})
+}
@@ -2,38 +2,52 @@ package v1alpha1
 
 import (
 	"errors"
+	"regexp"
 	"strings"
 )
 
-// +kubebuilder:validation:Enum=basic;basic-2x;standard-2x;performance
-type RuntimeSize string
+// RuntimeSizeName is the name of the RuntimeSize an application runs on.
+// +kubebuilder:validation:MaxLength=253
+// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
+type RuntimeSizeName string
 
+// Names of the built-in runtime sizes shipped with the operator.
 var (
 	// 256MB RAM, 256m vCPU
-	BasicMachineType RuntimeSize = "basic"
+	BasicMachineType RuntimeSizeName = "basic"
 	// 256MB RAM, 500m vCPU
-	Basic2xMachineType RuntimeSize = "basic-2x"
+	Basic2xMachineType RuntimeSizeName = "basic-2x"
 	// 512MB RAM, 500m vCPU
-	StandardMachineType RuntimeSize = "standard"
-	// 512MB RAM, 1vCPU
-	Standard2xMachineType RuntimeSize = "standard-2x"
-	// 1GB RAM, 2vCPU
-	PerformanceMachineType RuntimeSize = "performance"
+	StandardMachineType RuntimeSizeName = "standard"
+	// 1GB RAM, 1vCPU
+	Standard2xMachineType RuntimeSizeName = "standard-2x"
+	// 2GB RAM, 2vCPU
+	PerformanceMachineType RuntimeSizeName = "performance"
 )
 
-// Map of all valid runtime sizes.
-var RuntimeSizes = map[RuntimeSize]struct{}{
-	BasicMachineType:       {},
-	Basic2xMachineType:     {},
-	StandardMachineType:    {},
-	Standard2xMachineType:  {},
-	PerformanceMachineType: {},
-}
-
 var ErrInvalidRuntimeSize = errors.New("invalid runtime size")
 
 type RuntimeImage string
 
+// imageReferenceRegexp matches image references such as
+// registry.example.com:5000/org/app:v1 or app@sha256:<digest>.
+var imageReferenceRegexp = regexp.MustCompile(
+	`^(?:[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?)*(?::[0-9]+)?/)?` +
+		`[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*` +
+		`(?::[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,127})?(?:@sha256:[a-f0-9]{64})?$`,
+)
+
+var ErrInvalidRuntimeImage = errors.New("invalid image reference")
+
+// Validate checks that the image is a valid image reference.
+func (r RuntimeImage) Validate() error {
+	if r == "" || !imageReferenceRegexp.MatchString(string(r)) {
+		return ErrInvalidRuntimeImage
+	}
+
+	return nil
+}
+
 func (r RuntimeImage) Tag() string {
 	splits := strings.Split(string(r), ":")
 	if len(splits) < 2 {
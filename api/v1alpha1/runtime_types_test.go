@@ -0,0 +1,30 @@
+package v1alpha1
+
+import "testing"
+
+func TestRuntimeImage_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		image   RuntimeImage
+		wantErr bool
+	}{
+		{name: "name only", image: "nginx"},
+		{name: "name and tag", image: "shop:v1.2.0"},
+		{name: "registry with port", image: "registry.example.com:5000/org/shop:v1"},
+		{
+			name:  "digest",
+			image: "ghcr.io/org/shop@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		},
+		{name: "empty", image: "", wantErr: true},
+		{name: "uppercase repository", image: "Shop:v1", wantErr: true},
+		{name: "empty tag", image: "shop:", wantErr: true},
+		{name: "whitespace", image: "shop v1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.image.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("RuntimeImage.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RuntimeSizeSpec defines the resources and placement of the pods of the
+// applications running on a size.
+type RuntimeSizeSpec struct {
+	// Requests are the resources reserved for each pod.
+	Requests RuntimeSizeResources `json:"requests"`
+
+	// Limits are the resources each pod may use at most. Resources that are
+	// not limited default to their requests, so pods only burst above their
+	// requests when a higher limit is set.
+	// +optional
+	Limits *RuntimeSizeLimits `json:"limits,omitempty"`
+
+	// NodeSelector schedules the pods on the nodes of a node pool.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations let the pods schedule on tainted nodes, e.g. a dedicated
+	// node pool.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+}
+
+// RuntimeSizeResources are the resources of a runtime size.
+type RuntimeSizeResources struct {
+	CPU    resource.Quantity `json:"cpu"`
+	Memory resource.Quantity `json:"memory"`
+
+	// +optional
+	EphemeralStorage *resource.Quantity `json:"ephemeralStorage,omitempty"`
+}
+
+// RuntimeSizeLimits are the resource limits of a runtime size.
+type RuntimeSizeLimits struct {
+	// +optional
+	CPU *resource.Quantity `json:"cpu,omitempty"`
+	// +optional
+	Memory *resource.Quantity `json:"memory,omitempty"`
+	// +optional
+	EphemeralStorage *resource.Quantity `json:"ephemeralStorage,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="CPU",type=string,JSONPath=`.spec.requests.cpu`
+//+kubebuilder:printcolumn:name="Memory",type=string,JSONPath=`.spec.requests.memory`
+//+kubebuilder:printcolumn:name="CPU Limit",type=string,JSONPath=`.spec.limits.cpu`
+//+kubebuilder:printcolumn:name="Memory Limit",type=string,JSONPath=`.spec.limits.memory`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// RuntimeSize is the Schema for the runtimesizes API.
+// Applications select the size they run on by name, so cluster admins can
+// offer sizes that match the node pools of the cluster. The built-in sizes
+// are installed along with the operator.
+type RuntimeSize struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec RuntimeSizeSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// RuntimeSizeList contains a list of RuntimeSize
+type RuntimeSizeList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RuntimeSize `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RuntimeSize{}, &RuntimeSizeList{})
+}
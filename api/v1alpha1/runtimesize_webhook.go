@@ -0,0 +1,97 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var runtimesizelog = logf.Log.WithName("runtimesize-resource")
+
+func (r *RuntimeSize) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-operators-k4indie-io-v1alpha1-runtimesize,mutating=false,failurePolicy=fail,sideEffects=None,groups=operators.k4indie.io,resources=runtimesizes,verbs=create;update,versions=v1alpha1,name=vruntimesize.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &RuntimeSize{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *RuntimeSize) ValidateCreate() error {
+	runtimesizelog.Info("validate create", "name", r.Name)
+
+	return r.validateRuntimeSize()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *RuntimeSize) ValidateUpdate(old runtime.Object) error {
+	runtimesizelog.Info("validate update", "name", r.Name)
+
+	return r.validateRuntimeSize()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *RuntimeSize) ValidateDelete() error {
+	return nil
+}
+
+// validateRuntimeSize checks that the limits of the size are not below its
+// requests, which the pods running on the size would be rejected for.
+func (r *RuntimeSize) validateRuntimeSize() error {
+	if r.Spec.Limits == nil {
+		return nil
+	}
+
+	limitsPath := field.NewPath("spec", "limits")
+	requests := r.Spec.Requests
+	errs := field.ErrorList{}
+	errs = append(errs, validateLimit(limitsPath.Child("cpu"), r.Spec.Limits.CPU, &requests.CPU)...)
+	errs = append(errs, validateLimit(limitsPath.Child("memory"), r.Spec.Limits.Memory, &requests.Memory)...)
+	errs = append(errs, validateLimit(limitsPath.Child("ephemeralStorage"), r.Spec.Limits.EphemeralStorage, requests.EphemeralStorage)...)
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(
+		schema.GroupKind{Group: GroupVersion.Group, Kind: "RuntimeSize"},
+		r.Name,
+		errs,
+	)
+}
+
+func validateLimit(path *field.Path, limit, request *resource.Quantity) field.ErrorList {
+	if limit == nil || request == nil || limit.Cmp(*request) >= 0 {
+		return nil
+	}
+
+	return field.ErrorList{field.Invalid(
+		path,
+		limit.String(),
+		"must be greater than or equal to the request "+request.String(),
+	)}
+}
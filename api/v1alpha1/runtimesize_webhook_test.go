@@ -0,0 +1,46 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestRuntimeSize_ValidateCreate(t *testing.T) {
+	quantity := func(value string) *resource.Quantity {
+		q := resource.MustParse(value)
+		return &q
+	}
+	requests := RuntimeSizeResources{
+		CPU:              resource.MustParse("500m"),
+		Memory:           resource.MustParse("512Mi"),
+		EphemeralStorage: quantity("1Gi"),
+	}
+
+	tests := []struct {
+		name    string
+		limits  *RuntimeSizeLimits
+		wantErr bool
+	}{
+		{name: "no limits", limits: nil},
+		{
+			name:   "limits above requests",
+			limits: &RuntimeSizeLimits{CPU: quantity("1"), Memory: quantity("512Mi"), EphemeralStorage: quantity("2Gi")},
+		},
+		{name: "cpu limit below request", limits: &RuntimeSizeLimits{CPU: quantity("250m")}, wantErr: true},
+		{name: "memory limit below request", limits: &RuntimeSizeLimits{Memory: quantity("256Mi")}, wantErr: true},
+		{
+			name:    "ephemeral storage limit below request",
+			limits:  &RuntimeSizeLimits{EphemeralStorage: quantity("512Mi")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			size := &RuntimeSize{Spec: RuntimeSizeSpec{Requests: requests, Limits: tt.limits}}
+			if err := size.ValidateCreate(); (err != nil) != tt.wantErr {
+				t.Errorf("RuntimeSize.ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package v1alpha1
+
+import (
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ApplicationSchedule is a task that runs on a schedule with the image and
+// config of the application, e.g. to send a daily digest.
+type ApplicationSchedule struct {
+	// Schedule in cron format, e.g. "0 3 * * *" to run daily at 3am.
+	//+kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+
+	// Command of the task.
+	//+kubebuilder:validation:MinItems=1
+	Command []string `json:"command"`
+
+	// Size is the type of resources required to run the task.
+	// Defaults to the application runtime size.
+	//+optional
+	Size RuntimeSizeName `json:"size,omitempty"`
+
+	// ConcurrencyPolicy defines what happens when the task is due while its
+	// previous run is still running. Defaults to Forbid, which skips the
+	// new run.
+	//+optional
+	//+kubebuilder:validation:Enum=Allow;Forbid;Replace
+	//+kubebuilder:default=Forbid
+	ConcurrencyPolicy batchv1.ConcurrencyPolicy `json:"concurrencyPolicy,omitempty"`
+
+	// TimeZone of the schedule, e.g. "Europe/Berlin". Defaults to the time
+	// zone of the cluster.
+	//+optional
+	TimeZone *string `json:"timeZone,omitempty"`
+}
+
+// ScheduleStatus is the observed state of a scheduled task.
+type ScheduleStatus struct {
+	// Name of the scheduled task.
+	Name string `json:"name"`
+
+	// LastScheduleTime is when the task was last started.
+	//+optional
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+
+	// LastSuccessfulTime is when the task last succeeded.
+	//+optional
+	LastSuccessfulTime *metav1.Time `json:"lastSuccessfulTime,omitempty"`
+
+	// LastFailureTime is when the task last failed.
+	//+optional
+	LastFailureTime *metav1.Time `json:"lastFailureTime,omitempty"`
+
+	// LastFailureMessage describes the last failure of the task.
+	//+optional
+	LastFailureMessage string `json:"lastFailureMessage,omitempty"`
+}
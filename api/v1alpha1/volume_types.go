@@ -0,0 +1,37 @@
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// ApplicationVolume is a persistent disk mounted into the processes of an
+// application, e.g. for a SQLite database or uploaded files.
+type ApplicationVolume struct {
+	// Name of the volume. The PersistentVolumeClaim of the volume is named
+	// after the application and the volume.
+	//+kubebuilder:validation:MaxLength=63
+	//+kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+
+	// MountPath is the path the volume is mounted at in the containers.
+	//+kubebuilder:validation:MinLength=1
+	MountPath string `json:"mountPath"`
+
+	// Size of the volume. It can be increased later if the storage class
+	// allows volume expansion.
+	Size resource.Quantity `json:"size"`
+
+	// StorageClassName of the volume. Defaults to the default storage class
+	// of the cluster.
+	//+optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+
+	// AccessMode of the volume. Defaults to ReadWriteOnce, which only lets
+	// the pods of a single node mount the volume, so the processes are
+	// rolled out by recreating their pods.
+	//+optional
+	//+kubebuilder:validation:Enum=ReadWriteOnce;ReadWriteOncePod;ReadWriteMany;ReadOnlyMany
+	//+kubebuilder:default=ReadWriteOnce
+	AccessMode corev1.PersistentVolumeAccessMode `json:"accessMode,omitempty"`
+}
@@ -0,0 +1,16 @@
+package v1alpha1
+
+// WorkloadType defines how the processes of an application are run.
+// +kubebuilder:validation:Enum=stateless;stateful
+type WorkloadType string
+
+const (
+	// WorkloadStateless runs each process in a Deployment whose replicas
+	// are interchangeable and share the volumes of the application.
+	WorkloadStateless WorkloadType = "stateless"
+
+	// WorkloadStateful runs each process in a StatefulSet, so every replica
+	// gets a stable network identity and its own copy of the volumes of the
+	// application, e.g. to run a small database or message broker cluster.
+	WorkloadStateful WorkloadType = "stateful"
+)
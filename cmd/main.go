@@ -19,20 +19,25 @@ package main
 import (
 	"flag"
 	"os"
+	"strings"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	operatorsv1alpha1 "github.com/perfectmak/k4indie/api/v1alpha1"
 	"github.com/perfectmak/k4indie/internal/controller"
+	"github.com/perfectmak/k4indie/internal/controller/resolvers"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -52,11 +57,24 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var router string
+	var ingressClassName string
+	var gateway string
+	var traefikEntryPoints string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&router, "router", resolvers.RouterIngress,
+		"The router of the application domains, one of ingress, gateway or traefik.")
+	flag.StringVar(&ingressClassName, "ingress-class", "",
+		"The class of the Ingresses generated by the ingress router. The default class is used when empty.")
+	flag.StringVar(&gateway, "gateway", "",
+		"The Gateway the HTTPRoutes generated by the gateway router attach to, as namespace/name.")
+	flag.StringVar(&traefikEntryPoints, "traefik-entrypoints", "",
+		"Comma separated entry points of the IngressRoutes generated by the traefik router. "+
+			"All entry points are used when empty.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -65,6 +83,21 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	routerConfig := resolvers.RouterConfig{
+		Kind:             router,
+		IngressClassName: ingressClassName,
+	}
+	if gateway != "" {
+		gatewayNamespace, gatewayName, found := strings.Cut(gateway, "/")
+		if !found {
+			gatewayNamespace, gatewayName = "", gateway
+		}
+		routerConfig.GatewayNamespace = gatewayNamespace
+		routerConfig.GatewayName = gatewayName
+	}
+	if traefikEntryPoints != "" {
+		routerConfig.TraefikEntryPoints = strings.Split(traefikEntryPoints, ",")
+	}
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     metricsAddr,
@@ -72,6 +105,16 @@ func main() {
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "e78a9ac7.k4indie.io",
+		// Only the Secrets labeled as config, including the credentials of
+		// the addons, are cached, so the operator does not keep all the
+		// Secrets of the cluster in memory.
+		NewCache: cache.BuilderWithOptions(cache.Options{
+			SelectorsByObject: cache.SelectorsByObject{
+				&corev1.Secret{}: {
+					Label: labels.SelectorFromSet(labels.Set{operatorsv1alpha1.ConfigLabel: "true"}),
+				},
+			},
+		}),
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -89,14 +132,41 @@ func main() {
 		os.Exit(1)
 	}
 
+	// The routing of the operator config is resolved on every reconcile, so
+	// only the flags are checked here.
+	if _, err := resolvers.NewRouter(routerConfig); err != nil {
+		setupLog.Error(err, "unable to create router")
+		os.Exit(1)
+	}
+
 	if err = (&controller.ApplicationReconciler{
-		Client:   mgr.GetClient(),
-		Scheme:   mgr.GetScheme(),
-		Recorder: mgr.GetEventRecorderFor("application-controller"),
+		Client:         mgr.GetClient(),
+		Scheme:         mgr.GetScheme(),
+		Recorder:       mgr.GetEventRecorderFor("application-controller"),
+		APIReader:      mgr.GetAPIReader(),
+		RouterDefaults: routerConfig,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Application")
 		os.Exit(1)
 	}
+	if err = (&controller.AddonReconciler{
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		APIReader: mgr.GetAPIReader(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Addon")
+		os.Exit(1)
+	}
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&operatorsv1alpha1.Application{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Application")
+			os.Exit(1)
+		}
+		if err = (&operatorsv1alpha1.RuntimeSize{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "RuntimeSize")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
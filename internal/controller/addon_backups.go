@@ -0,0 +1,398 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	operatorsv1alpha1 "github.com/perfectmak/k4indie/api/v1alpha1"
+	"github.com/perfectmak/k4indie/internal/controller/resolvers"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// backupScheduleName is the schedule label of the backup jobs, telling
+	// them apart from the restore job of the addon.
+	backupScheduleName = "backup"
+	// restoreSourcePollInterval is how often a restore waiting for a backup
+	// of its source addon to complete is checked again.
+	restoreSourcePollInterval = time.Minute
+)
+
+var (
+	typeAddonBackupFailed = "BackupFailed"
+	typeAddonRestored     = "Restored"
+)
+
+// reconcileAddonBackups creates or updates the CronJob backing up the addon
+// and the volume it stores the backups in, or deletes the CronJob when
+// backups are disabled. The backups taken since the last reconciliation are
+// recorded in the addon status, which is updated by the caller.
+func (r *AddonReconciler) reconcileAddonBackups(ctx context.Context, addon *operatorsv1alpha1.Addon) error {
+	log := log.FromContext(ctx)
+	backup := addon.Spec.Backup
+
+	if backup == nil {
+		addon.Status.BackupSchedule = nil
+		meta.RemoveStatusCondition(&addon.Status.Conditions, typeAddonBackupFailed)
+
+		return r.deleteAddonBackupCronJob(ctx, addon)
+	}
+
+	if backup.Volume != nil {
+		claim := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      resolvers.AddonBackupClaimName(addon.Name),
+				Namespace: addon.Namespace,
+				Labels:    resolvers.AddonLabels(addon.Name),
+			},
+			Spec: resolvers.BuildVolumeClaimSpec(operatorsv1alpha1.ApplicationVolume{
+				Size:             backup.Volume.Size,
+				StorageClassName: backup.Volume.StorageClassName,
+			}),
+		}
+		if err := r.applyAddonResource(ctx, addon, claim); err != nil {
+			return err
+		}
+	}
+
+	labels := resolvers.MergeDefaultLabels(
+		resolvers.AddonLabels(addon.Name),
+		map[string]string{resolvers.ScheduleLabel: backupScheduleName},
+	)
+	// Completed jobs are only kept as long as their backup is retained, so
+	// the backups pruned from the status are never recorded again.
+	historyLimit := int32(3)
+	successfulHistoryLimit := historyLimit
+	if backup.Retain < successfulHistoryLimit {
+		successfulHistoryLimit = backup.Retain
+	}
+	backoffLimit := int32(1)
+	cronJob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      resolvers.AddonBackupCronJobName(addon.Name),
+			Namespace: addon.Namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.CronJobSpec{
+			Schedule:                   backup.Schedule,
+			TimeZone:                   backup.TimeZone,
+			ConcurrencyPolicy:          batchv1.ForbidConcurrent,
+			SuccessfulJobsHistoryLimit: &successfulHistoryLimit,
+			FailedJobsHistoryLimit:     &historyLimit,
+			JobTemplate: batchv1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: batchv1.JobSpec{
+					BackoffLimit: &backoffLimit,
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: labels},
+						Spec:       resolvers.BuildBackupPodSpec(addon),
+					},
+				},
+			},
+		},
+	}
+	if backup.S3 == nil {
+		// The backup volume can only be attached to one node, so backups
+		// are suspended while another addon restores one of them.
+		restoring, err := r.isAddonBackupRestoring(ctx, addon)
+		if err != nil {
+			return err
+		}
+		cronJob.Spec.Suspend = &restoring
+	}
+	if err := r.applyAddonResource(ctx, addon, cronJob); err != nil {
+		return err
+	}
+
+	jobs := &batchv1.JobList{}
+	err := r.List(ctx, jobs, client.InNamespace(addon.Namespace), client.MatchingLabels{
+		resolvers.AddonLabel:    addon.Name,
+		resolvers.ScheduleLabel: backupScheduleName,
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if !isJobConditionTrue(job, batchv1.JobComplete) || resolvers.FindBackup(addon, job.Name) != nil {
+			continue
+		}
+		if isBackupPruned(addon.Status.Backups, job, backup.Retain) {
+			continue
+		}
+
+		entry, err := r.getBackupResult(ctx, addon, job)
+		if err != nil {
+			// The result can't be read again, so the backup is skipped
+			// rather than retried forever.
+			log.Error(err, "failed to record addon backup", "job.name", job.Name)
+			continue
+		}
+		log.Info("recording addon backup", "backup.name", entry.Name, "backup.location", entry.Location)
+		addon.Status.Backups = resolvers.RecordBackup(addon.Status.Backups, entry, backup.Retain)
+	}
+
+	schedule := resolvers.ResolveScheduleStatus(backupScheduleName, cronJob, jobs.Items)
+	addon.Status.BackupSchedule = &schedule
+	if resolvers.IsScheduleFailing(schedule) {
+		meta.SetStatusCondition(&addon.Status.Conditions, metav1.Condition{
+			Type:    typeAddonBackupFailed,
+			Status:  metav1.ConditionTrue,
+			Reason:  "BackupJobFailed",
+			Message: fmt.Sprintf("Last backup of addon (%s) failed: %s", addon.Name, schedule.LastFailureMessage),
+		})
+	} else {
+		meta.SetStatusCondition(&addon.Status.Conditions, metav1.Condition{
+			Type:    typeAddonBackupFailed,
+			Status:  metav1.ConditionFalse,
+			Reason:  "BackupJobSucceeded",
+			Message: fmt.Sprintf("Last backup of addon (%s) did not fail", addon.Name),
+		})
+	}
+
+	return nil
+}
+
+// isBackupPruned returns whether the backup taken by a completed job is
+// older than all the retained backups, which means it was already pruned.
+func isBackupPruned(backups []operatorsv1alpha1.AddonBackupStatus, job *batchv1.Job, retain int32) bool {
+	if len(backups) < int(retain) || job.Status.CompletionTime == nil {
+		return false
+	}
+
+	return job.Status.CompletionTime.Before(&backups[0].Time)
+}
+
+// isAddonBackupRestoring returns whether a restore job reading the backup
+// volume of the addon is still running.
+func (r *AddonReconciler) isAddonBackupRestoring(ctx context.Context, addon *operatorsv1alpha1.Addon) (bool, error) {
+	jobs := &batchv1.JobList{}
+	err := r.List(ctx, jobs, client.InNamespace(addon.Namespace), client.MatchingLabels{
+		resolvers.RestoreSourceLabel: addon.Name,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return hasActiveJob(jobs.Items), nil
+}
+
+// isAddonBackingUp returns whether a backup job of the addon is still
+// running.
+func (r *AddonReconciler) isAddonBackingUp(ctx context.Context, namespace string, addonName string) (bool, error) {
+	jobs := &batchv1.JobList{}
+	err := r.List(ctx, jobs, client.InNamespace(namespace), client.MatchingLabels{
+		resolvers.AddonLabel:    addonName,
+		resolvers.ScheduleLabel: backupScheduleName,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return hasActiveJob(jobs.Items), nil
+}
+
+func hasActiveJob(jobs []batchv1.Job) bool {
+	for i := range jobs {
+		if !isJobConditionTrue(&jobs[i], batchv1.JobComplete) && !isJobConditionTrue(&jobs[i], batchv1.JobFailed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getBackupResult reads the backup reported by the succeeded pod of a
+// backup job. Pods are read from the API server, so the operator does not
+// cache the pods of the whole cluster.
+func (r *AddonReconciler) getBackupResult(
+	ctx context.Context,
+	addon *operatorsv1alpha1.Addon,
+	job *batchv1.Job,
+) (operatorsv1alpha1.AddonBackupStatus, error) {
+	pods := &corev1.PodList{}
+	err := r.APIReader.List(ctx, pods, client.InNamespace(job.Namespace), client.MatchingLabels{
+		"job-name": job.Name,
+	})
+	if err != nil {
+		return operatorsv1alpha1.AddonBackupStatus{}, err
+	}
+
+	for i := range pods.Items {
+		if pods.Items[i].Status.Phase == corev1.PodSucceeded {
+			return resolvers.ParseBackupResult(addon, job.Name, &pods.Items[i])
+		}
+	}
+
+	return operatorsv1alpha1.AddonBackupStatus{}, fmt.Errorf("job %s has no succeeded pod", job.Name)
+}
+
+// deleteAddonBackupCronJob deletes the CronJob backing up the addon, if it
+// is controlled by the addon. The backups already taken are kept.
+func (r *AddonReconciler) deleteAddonBackupCronJob(ctx context.Context, addon *operatorsv1alpha1.Addon) error {
+	cronJob := &batchv1.CronJob{}
+	err := r.Get(
+		ctx,
+		types.NamespacedName{Namespace: addon.Namespace, Name: resolvers.AddonBackupCronJobName(addon.Name)},
+		cronJob,
+	)
+	if err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if !metav1.IsControlledBy(cronJob, addon) {
+		return nil
+	}
+
+	log.FromContext(ctx).Info("deleting addon backup cronjob", "cronjob.name", cronJob.Name)
+	if err := r.Delete(ctx, cronJob); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+// reconcileAddonRestore restores the backup the addon is created from once
+// the addon accepts connections. The restore is only attempted once, and
+// its outcome is reported by the Restored condition of the addon status,
+// which is updated by the caller.
+// It returns a result when the restore must be checked again later.
+func (r *AddonReconciler) reconcileAddonRestore(
+	ctx context.Context,
+	addon *operatorsv1alpha1.Addon,
+	statefulSet *appsv1.StatefulSet,
+) (*reconcile.Result, error) {
+	log := log.FromContext(ctx)
+	restore := addon.Spec.RestoreFrom
+	if restore == nil {
+		return nil, nil
+	}
+
+	condition := meta.FindStatusCondition(addon.Status.Conditions, typeAddonRestored)
+	if condition != nil && (condition.Status == metav1.ConditionTrue || condition.Reason == "RestoreFailed") {
+		return nil, nil
+	}
+
+	job := &batchv1.Job{}
+	err := r.Get(
+		ctx,
+		types.NamespacedName{Namespace: addon.Namespace, Name: resolvers.AddonRestoreJobName(addon.Name)},
+		job,
+	)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+
+	if apierrors.IsNotFound(err) {
+		if statefulSet.Status.ReadyReplicas == 0 {
+			setAddonRestoring(addon, "WaitingForAddon", fmt.Sprintf("Waiting for addon (%s) to accept connections", addon.Name))
+			return nil, nil
+		}
+
+		// The source addon only defaults where the backup is stored, so it
+		// may have been deleted since the backup was taken.
+		source := &operatorsv1alpha1.Addon{}
+		err := r.Get(ctx, types.NamespacedName{Namespace: addon.Namespace, Name: restore.Addon}, source)
+		if apierrors.IsNotFound(err) {
+			source = nil
+		} else if err != nil {
+			return nil, err
+		}
+		backup := resolvers.ResolveRestoreBackup(restore, source)
+
+		labels := resolvers.AddonLabels(addon.Name)
+		if backup.S3 == nil {
+			// The backup volume can only be attached to one node, so the
+			// restore waits for the running backup of the source addon, which
+			// then suspends its backups until the restore is done.
+			backingUp, err := r.isAddonBackingUp(ctx, addon.Namespace, restore.Addon)
+			if err != nil {
+				return nil, err
+			}
+			if backingUp {
+				setAddonRestoring(addon, "WaitingForBackup", fmt.Sprintf("Waiting for the running backup of addon (%s) to complete", restore.Addon))
+				return &reconcile.Result{RequeueAfter: restoreSourcePollInterval}, nil
+			}
+			labels = resolvers.MergeDefaultLabels(labels, map[string]string{resolvers.RestoreSourceLabel: restore.Addon})
+		}
+
+		backoffLimit := int32(0)
+		job = &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      resolvers.AddonRestoreJobName(addon.Name),
+				Namespace: addon.Namespace,
+				Labels:    labels,
+			},
+			Spec: batchv1.JobSpec{
+				// Restoring twice would fail on the data restored by the
+				// first attempt, so the restore is never retried.
+				BackoffLimit: &backoffLimit,
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: resolvers.AddonLabels(addon.Name)},
+					Spec:       resolvers.BuildRestorePodSpec(addon, backup),
+				},
+			},
+		}
+
+		log.Info("restoring addon backup", "backup.name", backup.Name, "backup.location", backup.Location())
+		if err := r.applyAddonResource(ctx, addon, job); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case isJobConditionTrue(job, batchv1.JobComplete):
+		meta.SetStatusCondition(&addon.Status.Conditions, metav1.Condition{
+			Type:    typeAddonRestored,
+			Status:  metav1.ConditionTrue,
+			Reason:  "Restored",
+			Message: fmt.Sprintf("Backup %s of addon (%s) is restored", restore.Backup, restore.Addon),
+		})
+	case isJobConditionTrue(job, batchv1.JobFailed):
+		meta.SetStatusCondition(&addon.Status.Conditions, metav1.Condition{
+			Type:    typeAddonRestored,
+			Status:  metav1.ConditionFalse,
+			Reason:  "RestoreFailed",
+			Message: fmt.Sprintf("Failed to restore backup %s of addon (%s), see job %s", restore.Backup, restore.Addon, job.Name),
+		})
+	default:
+		setAddonRestoring(addon, "Restoring", fmt.Sprintf("Restoring backup %s of addon (%s)", restore.Backup, restore.Addon))
+	}
+
+	return nil, nil
+}
+
+func setAddonRestoring(addon *operatorsv1alpha1.Addon, reason string, message string) {
+	meta.SetStatusCondition(&addon.Status.Conditions, metav1.Condition{
+		Type:    typeAddonRestored,
+		Status:  metav1.ConditionFalse,
+		Reason:  reason,
+		Message: message,
+	})
+}
+
+// findAddonForJob enqueues the addon running a backup or restore job, so
+// its outcome is recorded on the addon status, and the addon whose backup
+// volume a restore job reads, so its backups are suspended meanwhile.
+func (r *AddonReconciler) findAddonForJob(obj client.Object) []reconcile.Request {
+	requests := []reconcile.Request{}
+	for _, label := range []string{resolvers.AddonLabel, resolvers.RestoreSourceLabel} {
+		if name, exists := obj.GetLabels()[label]; exists {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: name},
+			})
+		}
+	}
+
+	return requests
+}
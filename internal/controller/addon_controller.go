@@ -0,0 +1,280 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	operatorsv1alpha1 "github.com/perfectmak/k4indie/api/v1alpha1"
+	"github.com/perfectmak/k4indie/internal/controller/resolvers"
+)
+
+const addonPlansIndexKey = ".spec.plan"
+
+// AddonReconciler reconciles a Addon object
+type AddonReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	// APIReader reads the pods of the backup jobs and the Secrets, which
+	// are not cached or only cached when labeled.
+	APIReader client.Reader
+}
+
+var typeAddonReady = "Ready"
+
+//+kubebuilder:rbac:groups=operators.k4indie.io,resources=addons,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=operators.k4indie.io,resources=addons/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=operators.k4indie.io,resources=addons/finalizers,verbs=update
+//+kubebuilder:rbac:groups=operators.k4indie.io,resources=runtimesizes,verbs=get;list;watch
+//+kubebuilder:rbac:groups=operators.k4indie.io,resources=k4indieconfigs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=persistentvolumeclaims,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=pods,verbs=get;list
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile provisions the credentials, the data volume, the StatefulSet
+// and the Service of an addon, schedules its backups and restores the
+// backup it is created from, and reports whether it is ready in its status.
+func (r *AddonReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	addon := &operatorsv1alpha1.Addon{}
+	err := r.Get(ctx, req.NamespacedName, addon)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Info("addon resource not found. ignoring since object must be deleted")
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "failed to get addon")
+		return ctrl.Result{}, err
+	}
+
+	if err := r.reconcileAddonCredentials(ctx, addon); err != nil {
+		log.Error(err, "failed to reconcile addon credentials")
+		return ctrl.Result{}, r.setAddonReconcileError(ctx, addon, err)
+	}
+
+	if err := r.reconcileAddonDataClaim(ctx, addon); err != nil {
+		log.Error(err, "failed to reconcile addon data claim")
+		return ctrl.Result{}, r.setAddonReconcileError(ctx, addon, err)
+	}
+
+	statefulSet, applied, err := r.reconcileAddonStatefulSet(ctx, addon)
+	if err != nil {
+		log.Error(err, "failed to reconcile addon statefulset")
+		return ctrl.Result{}, r.setAddonReconcileError(ctx, addon, err)
+	}
+	if !applied {
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	if err := r.reconcileAddonService(ctx, addon); err != nil {
+		log.Error(err, "failed to reconcile addon service")
+		return ctrl.Result{}, r.setAddonReconcileError(ctx, addon, err)
+	}
+
+	if err := r.reconcileAddonBackups(ctx, addon); err != nil {
+		log.Error(err, "failed to reconcile addon backups")
+		return ctrl.Result{}, r.setAddonReconcileError(ctx, addon, err)
+	}
+
+	result, err := r.reconcileAddonRestore(ctx, addon, statefulSet)
+	if err != nil {
+		log.Error(err, "failed to reconcile addon restore")
+		return ctrl.Result{}, r.setAddonReconcileError(ctx, addon, err)
+	}
+
+	if err := r.setAddonReconciled(ctx, addon, statefulSet); err != nil {
+		return ctrl.Result{}, err
+	}
+	if result != nil {
+		return *result, nil
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// setAddonReconciled reports whether the addon accepts connections in its
+// status. Addons created from a backup are only ready once it is restored.
+// The addon is reconciled again when its StatefulSet or restore Job
+// changes, so it does not need to be requeued until it is ready.
+func (r *AddonReconciler) setAddonReconciled(
+	ctx context.Context,
+	addon *operatorsv1alpha1.Addon,
+	statefulSet *appsv1.StatefulSet,
+) error {
+	addon.Status.ObservedGeneration = addon.Generation
+	addon.Status.SecretName = resolvers.AddonSecretName(addon.Name)
+	addon.Status.Version = resolvers.AddonVersion(addon)
+
+	restoring := addon.Spec.RestoreFrom != nil &&
+		!meta.IsStatusConditionTrue(addon.Status.Conditions, typeAddonRestored)
+
+	switch {
+	case statefulSet.Status.ReadyReplicas == 0 ||
+		statefulSet.Status.ObservedGeneration < statefulSet.Generation:
+		meta.SetStatusCondition(&addon.Status.Conditions, metav1.Condition{
+			Type:    typeAddonReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  "Provisioning",
+			Message: fmt.Sprintf("Waiting for addon (%s) to accept connections", addon.Name),
+		})
+	case restoring:
+		meta.SetStatusCondition(&addon.Status.Conditions, metav1.Condition{
+			Type:    typeAddonReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  "Restoring",
+			Message: fmt.Sprintf("Waiting for the backup of addon (%s) to be restored", addon.Name),
+		})
+	default:
+		meta.SetStatusCondition(&addon.Status.Conditions, metav1.Condition{
+			Type:    typeAddonReady,
+			Status:  metav1.ConditionTrue,
+			Reason:  "Running",
+			Message: fmt.Sprintf("Addon (%s) accepts connections", addon.Name),
+		})
+	}
+
+	return r.Status().Update(ctx, addon)
+}
+
+// setAddonReconcileError reports the error on the addon status, and returns
+// it so the addon is retried.
+func (r *AddonReconciler) setAddonReconcileError(
+	ctx context.Context,
+	addon *operatorsv1alpha1.Addon,
+	err error,
+) error {
+	meta.SetStatusCondition(&addon.Status.Conditions, metav1.Condition{
+		Type:    typeAddonReady,
+		Status:  metav1.ConditionFalse,
+		Reason:  "ReconcileError",
+		Message: fmt.Sprintf("Failed to update the addon resources (%s): (%s)", addon.Name, err),
+	})
+
+	if err := r.Status().Update(ctx, addon); err != nil {
+		log.FromContext(ctx).Error(err, "failed to update addon status")
+	}
+
+	return err
+}
+
+// ensureControlled checks that the resource the addon is about to apply
+// either does not exist yet or is controlled by the addon, so an addon never
+// takes over the resources of an application with the same name.
+func (r *AddonReconciler) ensureControlled(
+	ctx context.Context,
+	addon *operatorsv1alpha1.Addon,
+	obj client.Object,
+) error {
+	// Resources are read from the API server, so Secrets created before they
+	// were labeled are still found.
+	existing := obj.DeepCopyObject().(client.Object)
+	err := r.APIReader.Get(ctx, types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}, existing)
+	if err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	if !metav1.IsControlledBy(existing, addon) {
+		return fmt.Errorf("%T %s already exists and is not managed by the addon", obj, obj.GetName())
+	}
+
+	return nil
+}
+
+func indexAddonPlans(obj client.Object) []string {
+	addon := obj.(*operatorsv1alpha1.Addon)
+	if addon.Spec.Plan == "" {
+		return nil
+	}
+
+	return []string{string(addon.Spec.Plan)}
+}
+
+// findAddonsForRuntimeSize enqueues the addons running on a RuntimeSize, so
+// changes to the size are rolled out to them.
+func (r *AddonReconciler) findAddonsForRuntimeSize(obj client.Object) []reconcile.Request {
+	addons := &operatorsv1alpha1.AddonList{}
+	err := r.List(context.Background(), addons, client.MatchingFields{addonPlansIndexKey: obj.GetName()})
+	if err != nil {
+		log.Log.Error(err, "failed to list addons for runtime size", "size.name", obj.GetName())
+		return []reconcile.Request{}
+	}
+
+	requests := make([]reconcile.Request, 0, len(addons.Items))
+	for _, addon := range addons.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: addon.Namespace,
+				Name:      addon.Name,
+			},
+		})
+	}
+
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *AddonReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	err := mgr.GetFieldIndexer().IndexField(
+		context.Background(),
+		&operatorsv1alpha1.Addon{},
+		addonPlansIndexKey,
+		indexAddonPlans,
+	)
+	if err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&operatorsv1alpha1.Addon{}).
+		Owns(&appsv1.StatefulSet{}).
+		Owns(&corev1.Service{}).
+		Owns(&corev1.Secret{}).
+		Owns(&corev1.PersistentVolumeClaim{}).
+		Owns(&batchv1.CronJob{}).
+		// Backup jobs are owned by the CronJob, so jobs are mapped to their
+		// addon by label instead.
+		Watches(
+			&source.Kind{Type: &batchv1.Job{}},
+			handler.EnqueueRequestsFromMapFunc(r.findAddonForJob),
+		).
+		Watches(
+			&source.Kind{Type: &operatorsv1alpha1.RuntimeSize{}},
+			handler.EnqueueRequestsFromMapFunc(r.findAddonsForRuntimeSize),
+		).
+		Complete(r)
+}
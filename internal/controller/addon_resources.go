@@ -0,0 +1,237 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	operatorsv1alpha1 "github.com/perfectmak/k4indie/api/v1alpha1"
+	"github.com/perfectmak/k4indie/internal/controller/resolvers"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// reconcileAddonCredentials creates the Secret holding the credentials of
+// the addon. The password is generated once and kept afterwards, while the
+// rest of the credentials are rendered again from the addon.
+func (r *AddonReconciler) reconcileAddonCredentials(ctx context.Context, addon *operatorsv1alpha1.Addon) error {
+	log := log.FromContext(ctx)
+
+	// The Secret is read from the API server, so the password of a Secret
+	// created before it was labeled is not generated again.
+	secret := &corev1.Secret{}
+	err := r.APIReader.Get(
+		ctx,
+		types.NamespacedName{Namespace: addon.Namespace, Name: resolvers.AddonSecretName(addon.Name)},
+		secret,
+	)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	password := resolvers.AddonPassword(addon.Spec.Type, secret.Data)
+	if password == "" {
+		log.Info("generating addon credentials", "secret.name", resolvers.AddonSecretName(addon.Name))
+		password, err = resolvers.GeneratePassword()
+		if err != nil {
+			return err
+		}
+	}
+
+	newSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      resolvers.AddonSecretName(addon.Name),
+			Namespace: addon.Namespace,
+			// The config label lets the operator watch the Secret.
+			Labels: resolvers.MergeDefaultLabels(
+				resolvers.AddonLabels(addon.Name),
+				map[string]string{operatorsv1alpha1.ConfigLabel: "true"},
+			),
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: resolvers.BuildAddonCredentials(addon, password),
+	}
+
+	return r.applyAddonResource(ctx, addon, newSecret)
+}
+
+// addonUIDAnnotation is set on the data claim of an addon to the UID of the
+// addon, since the claim is not owned by the addon.
+const addonUIDAnnotation = "k4indie.io/addon-uid"
+
+// reconcileAddonDataClaim creates or updates the PersistentVolumeClaim
+// holding the data of the addon. The claim is not owned by the addon, so it
+// is retained along with its data when the addon is deleted. A claim
+// retained from a previous addon with the same name is not taken over,
+// since its data doesn't match the new credentials.
+func (r *AddonReconciler) reconcileAddonDataClaim(ctx context.Context, addon *operatorsv1alpha1.Addon) error {
+	existing := &corev1.PersistentVolumeClaim{}
+	err := r.APIReader.Get(
+		ctx,
+		types.NamespacedName{Namespace: addon.Namespace, Name: resolvers.AddonDataClaimName(addon.Name)},
+		existing,
+	)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	// Claims created before they were retained are owned by the addon, and
+	// their owner reference is removed when the claim is applied.
+	if err == nil && existing.Annotations[addonUIDAnnotation] != string(addon.UID) &&
+		!metav1.IsControlledBy(existing, addon) {
+		return fmt.Errorf("PersistentVolumeClaim %s already exists and is not managed by the addon", existing.Name)
+	}
+
+	claim := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        resolvers.AddonDataClaimName(addon.Name),
+			Namespace:   addon.Namespace,
+			Labels:      resolvers.AddonLabels(addon.Name),
+			Annotations: map[string]string{addonUIDAnnotation: string(addon.UID)},
+		},
+		Spec: resolvers.BuildVolumeClaimSpec(operatorsv1alpha1.ApplicationVolume{
+			Size:             addon.Spec.Storage,
+			StorageClassName: addon.Spec.StorageClassName,
+		}),
+	}
+
+	return applyResource(ctx, r.Client, r.Scheme, claim)
+}
+
+// reconcileAddonStatefulSet creates or updates the StatefulSet running the
+// addon, and returns it with its status. A StatefulSet whose service name
+// changed is deleted without its pods instead, since the service name is
+// immutable, and it returns false until it is created again.
+func (r *AddonReconciler) reconcileAddonStatefulSet(
+	ctx context.Context,
+	addon *operatorsv1alpha1.Addon,
+) (*appsv1.StatefulSet, bool, error) {
+	log := log.FromContext(ctx)
+
+	size, err := getRuntimeSize(ctx, r.Client, addon.Spec.Plan)
+	if err != nil {
+		return nil, false, err
+	}
+	labels := resolvers.AddonLabels(addon.Name)
+	replicas := int32(1)
+
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      addon.Name,
+			Namespace: addon.Namespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:    &replicas,
+			Selector:    &metav1.LabelSelector{MatchLabels: labels},
+			ServiceName: resolvers.HeadlessServiceName(addon.Name),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					SecurityContext: resolvers.BuildAddonPodSecurityContext(addon.Spec.Type),
+					NodeSelector:    size.Spec.NodeSelector,
+					Tolerations:     size.Spec.Tolerations,
+					Containers: []corev1.Container{
+						resolvers.BuildAddonContainer(addon, resolvers.GetResourcesForRuntimeSize(size)),
+					},
+					Volumes: []corev1.Volume{{
+						Name: "data",
+						VolumeSource: corev1.VolumeSource{
+							PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+								ClaimName: resolvers.AddonDataClaimName(addon.Name),
+							},
+						},
+					}},
+				},
+			},
+		},
+	}
+
+	existing := &appsv1.StatefulSet{}
+	err = r.Get(ctx, client.ObjectKeyFromObject(statefulSet), existing)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, false, err
+	}
+	if err == nil && metav1.IsControlledBy(existing, addon) &&
+		existing.Spec.ServiceName != statefulSet.Spec.ServiceName {
+		// The pod is orphaned, so the addon keeps running until the new
+		// StatefulSet adopts and replaces it.
+		log.Info("recreating addon statefulset with new service name", "statefulset.name", existing.Name)
+		err := r.Delete(ctx, existing, client.PropagationPolicy(metav1.DeletePropagationOrphan))
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, false, err
+		}
+		return nil, false, nil
+	}
+
+	if err := r.applyAddonResource(ctx, addon, statefulSet); err != nil {
+		return nil, false, err
+	}
+
+	return statefulSet, true, nil
+}
+
+// reconcileAddonService creates or updates the Service applications connect
+// to the addon through, and the headless Service giving the pod of the
+// StatefulSet its network identity.
+func (r *AddonReconciler) reconcileAddonService(ctx context.Context, addon *operatorsv1alpha1.Addon) error {
+	labels := resolvers.AddonLabels(addon.Name)
+	ports := []corev1.ServicePort{{
+		Name:     string(addon.Spec.Type),
+		Port:     resolvers.AddonPort(addon.Spec.Type),
+		Protocol: corev1.ProtocolTCP,
+	}}
+
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      addon.Name,
+			Namespace: addon.Namespace,
+			Labels:    labels,
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: labels,
+			Type:     corev1.ServiceTypeClusterIP,
+			Ports:    ports,
+		},
+	}
+	if err := r.applyAddonResource(ctx, addon, service); err != nil {
+		return err
+	}
+
+	headlessService := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      resolvers.HeadlessServiceName(addon.Name),
+			Namespace: addon.Namespace,
+			Labels:    labels,
+		},
+		Spec: corev1.ServiceSpec{
+			Selector:  labels,
+			Type:      corev1.ServiceTypeClusterIP,
+			ClusterIP: corev1.ClusterIPNone,
+			Ports:     ports,
+		},
+	}
+
+	return r.applyAddonResource(ctx, addon, headlessService)
+}
+
+// applyAddonResource sets the addon as the controller of the resource and
+// applies it, unless the resource belongs to something else.
+func (r *AddonReconciler) applyAddonResource(
+	ctx context.Context,
+	addon *operatorsv1alpha1.Addon,
+	obj client.Object,
+) error {
+	if err := r.ensureControlled(ctx, addon, obj); err != nil {
+		return err
+	}
+	if err := ctrl.SetControllerReference(addon, obj, r.Scheme); err != nil {
+		return err
+	}
+
+	return applyResource(ctx, r.Client, r.Scheme, obj)
+}
@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	operatorsv1alpha1 "github.com/perfectmak/k4indie/api/v1alpha1"
+	"github.com/perfectmak/k4indie/internal/controller/resolvers"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const addonsIndexKey = ".spec.addons"
+
+// resolveAddonEnvVars returns the environment variables holding the URLs
+// of the addons of the application. The URLs are read from the credentials
+// Secrets of the addons when the pods start, so the Secrets don't have to
+// exist yet.
+func (r *ApplicationReconciler) resolveAddonEnvVars(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+) ([]corev1.EnvVar, error) {
+	if len(appToReconcile.Spec.Addons) == 0 {
+		return nil, nil
+	}
+
+	envVars := make([]corev1.EnvVar, 0, len(appToReconcile.Spec.Addons))
+	// The names are only known once the types of the addons are, so
+	// references whose default names collide are rejected here rather than
+	// by the webhook.
+	refsByEnv := make(map[string]string, len(appToReconcile.Spec.Addons))
+	for _, ref := range appToReconcile.Spec.Addons {
+		addon := &operatorsv1alpha1.Addon{}
+		err := r.Get(ctx, types.NamespacedName{Namespace: appToReconcile.Namespace, Name: ref.Name}, addon)
+		if err != nil && apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("addon %q not found", ref.Name)
+		} else if err != nil {
+			return nil, err
+		}
+
+		env := resolvers.AddonURLEnv(ref, addon.Spec.Type)
+		if other, exists := refsByEnv[env]; exists {
+			return nil, fmt.Errorf("addons %q and %q both set %s, set env on one of them", other, ref.Name, env)
+		}
+		refsByEnv[env] = ref.Name
+
+		envVars = append(envVars, corev1.EnvVar{
+			Name: env,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: resolvers.AddonSecretName(addon.Name),
+					},
+					Key: resolvers.AddonURLKey,
+				},
+			},
+		})
+	}
+
+	return envVars, nil
+}
+
+// indexAddons indexes the applications by the addons they use, so that
+// applications pick up addons created after them.
+func indexAddons(obj client.Object) []string {
+	app := obj.(*operatorsv1alpha1.Application)
+
+	names := make([]string, 0, len(app.Spec.Addons))
+	for _, ref := range app.Spec.Addons {
+		names = append(names, ref.Name)
+	}
+
+	return names
+}
@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"context"
+
+	operatorsv1alpha1 "github.com/perfectmak/k4indie/api/v1alpha1"
+	"github.com/perfectmak/k4indie/internal/controller/resolvers"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// reconcileAutoscaler attempts to create a horizontal pod autoscaler for
+// each autoscaled process of the application if it does not exist. And if
+// it does, it tries to update the autoscaler to match the application spec.
+// Autoscalers of processes that are no longer autoscaled are deleted.
+func (r *ApplicationReconciler) reconcileAutoscaler(
+	ctx context.Context,
+	req reconcile.Request,
+	appToReconcile *operatorsv1alpha1.Application,
+) (*reconcile.Result, error) {
+	log := log.FromContext(ctx)
+	processes := resolvers.ProcessesWithAutoscale(resolvers.ResolveProcesses(appToReconcile))
+
+	for _, process := range processes {
+		result, err := r.reconcileProcessAutoscaler(ctx, req, appToReconcile, process)
+		if err != nil || result != nil {
+			return result, err
+		}
+	}
+
+	resourceNames := make([]string, 0, len(processes))
+	for _, process := range processes {
+		resourceNames = append(resourceNames, process.ResourceName)
+	}
+	err := r.deleteStaleResources(
+		ctx,
+		appToReconcile,
+		&autoscalingv2.HorizontalPodAutoscalerList{},
+		resourceNames,
+	)
+	if err != nil {
+		log.Error(err, "failed to delete stale autoscalers")
+		return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
+	}
+
+	return nil, nil
+}
+
+func (r *ApplicationReconciler) reconcileProcessAutoscaler(
+	ctx context.Context,
+	req reconcile.Request,
+	appToReconcile *operatorsv1alpha1.Application,
+	process resolvers.Process,
+) (*reconcile.Result, error) {
+	log := log.FromContext(ctx).WithValues("process", process.Name)
+
+	autoscaler := &autoscalingv2.HorizontalPodAutoscaler{}
+	err := r.Get(
+		ctx,
+		types.NamespacedName{Namespace: appToReconcile.Namespace, Name: process.ResourceName},
+		autoscaler,
+	)
+
+	if err != nil && apierrors.IsNotFound(err) {
+		autoscaler, err = r.buildAutoscaler(ctx, appToReconcile, process)
+		if err != nil {
+			return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
+		}
+
+		log.Info("creating autoscaler", "autoscaler.name", autoscaler.Name)
+		if err := r.applyResource(ctx, autoscaler); err != nil {
+			log.Error(err, "failed to create autoscaler")
+			return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
+		}
+
+		return nil, nil
+	} else if err != nil {
+		log.Error(err, "failed to get existing autoscaler")
+		return nil, err
+	}
+
+	newAutoscaler, err := r.buildAutoscaler(ctx, appToReconcile, process)
+	if err != nil {
+		return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
+	}
+	drifted := resolvers.IsDrifted(newAutoscaler, autoscaler, newAutoscaler.Spec, autoscaler.Spec)
+	if err := r.applyResource(ctx, newAutoscaler); err != nil {
+		log.Error(err, "failed to update autoscaler")
+		return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
+	}
+	if drifted {
+		r.recordDriftCorrected(appToReconcile, "HorizontalPodAutoscaler", autoscaler.Name)
+	}
+
+	return nil, nil
+}
+
+func (r *ApplicationReconciler) buildAutoscaler(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+	process resolvers.Process,
+) (*autoscalingv2.HorizontalPodAutoscaler, error) {
+	labels := resolvers.MergeDefaultLabels(
+		appToReconcile.Labels,
+		resolvers.SelectorLabels(appToReconcile.Name, process.Name),
+	)
+	minReplicas := resolvers.AutoscaleMinReplicas(process.Autoscale)
+	targetKind := "Deployment"
+	if resolvers.IsStateful(appToReconcile) {
+		targetKind = "StatefulSet"
+	}
+
+	autoscaler := &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      process.ResourceName,
+			Namespace: appToReconcile.Namespace,
+			Labels:    labels,
+		},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       targetKind,
+				Name:       process.ResourceName,
+			},
+			MinReplicas: &minReplicas,
+			MaxReplicas: process.Autoscale.Max,
+			Metrics:     resolvers.BuildAutoscaleMetrics(process.Autoscale),
+		},
+	}
+
+	if err := setRenderedHash(autoscaler, autoscaler.Spec); err != nil {
+		return nil, err
+	}
+
+	if err := ctrl.SetControllerReference(appToReconcile, autoscaler, r.Scheme); err != nil {
+		return nil, err
+	}
+
+	return autoscaler, nil
+}
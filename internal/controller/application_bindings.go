@@ -0,0 +1,244 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	operatorsv1alpha1 "github.com/perfectmak/k4indie/api/v1alpha1"
+	"github.com/perfectmak/k4indie/internal/controller/resolvers"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	bindingsIndexKey          = ".spec.bindings"
+	bindingNamespacesIndexKey = ".spec.bindings.namespace"
+)
+
+var typeBindingFailed = "BindingFailed"
+
+// bindingError is returned when a binding can't be resolved, as opposed to
+// failing to read the cluster.
+type bindingError struct {
+	reason  string
+	message string
+}
+
+func (e *bindingError) Error() string {
+	return e.message
+}
+
+// reconcileBindings reports in the BindingFailed condition whether the
+// bindings of the application resolve. The workloads are only updated once
+// they all resolve, so they keep the addresses they were last given
+// meanwhile.
+// It returns a result when the reconciliation must stop.
+func (r *ApplicationReconciler) reconcileBindings(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+) (*reconcile.Result, error) {
+	log := log.FromContext(ctx)
+
+	var condition *metav1.Condition
+	_, err := r.resolveBindingEnvVars(ctx, appToReconcile)
+	bindingErr := &bindingError{}
+	switch {
+	case errors.As(err, &bindingErr):
+		condition = &metav1.Condition{
+			Type:    typeBindingFailed,
+			Status:  metav1.ConditionTrue,
+			Reason:  bindingErr.reason,
+			Message: bindingErr.message,
+		}
+	case err != nil:
+		log.Error(err, "failed to resolve bindings")
+		return nil, err
+	case len(appToReconcile.Spec.Bindings) > 0:
+		condition = &metav1.Condition{
+			Type:    typeBindingFailed,
+			Status:  metav1.ConditionFalse,
+			Reason:  "BindingsResolved",
+			Message: fmt.Sprintf("%d bindings are resolved", len(appToReconcile.Spec.Bindings)),
+		}
+	}
+
+	changed := false
+	existing := meta.FindStatusCondition(appToReconcile.Status.Conditions, typeBindingFailed)
+	if condition == nil {
+		if existing != nil {
+			meta.RemoveStatusCondition(&appToReconcile.Status.Conditions, typeBindingFailed)
+			changed = true
+		}
+	} else if existing == nil || existing.Status != condition.Status || existing.Message != condition.Message {
+		meta.SetStatusCondition(&appToReconcile.Status.Conditions, *condition)
+		changed = true
+	}
+	if changed {
+		if err := r.updateStatus(ctx, appToReconcile); err != nil {
+			log.Error(err, "failed to update application status")
+			return nil, err
+		}
+	}
+
+	if condition != nil && condition.Status == metav1.ConditionTrue {
+		// The application is enqueued again when the bound application or
+		// its namespace changes.
+		log.Info("waiting for bindings to resolve", "reason", condition.Message)
+		return &reconcile.Result{}, nil
+	}
+
+	return nil, nil
+}
+
+// resolveBindingEnvVars returns the environment variables holding the
+// addresses of the applications the application is bound to. Applications
+// of other namespaces can only be bound when their namespace allows it.
+// A binding that can't be resolved is reported with a *bindingError.
+func (r *ApplicationReconciler) resolveBindingEnvVars(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+) ([]corev1.EnvVar, error) {
+	if len(appToReconcile.Spec.Bindings) == 0 {
+		return nil, nil
+	}
+
+	envVars := make([]corev1.EnvVar, 0, 3*len(appToReconcile.Spec.Bindings))
+	for _, binding := range appToReconcile.Spec.Bindings {
+		namespace := resolvers.BindingNamespace(appToReconcile.Namespace, binding)
+
+		if namespace != appToReconcile.Namespace {
+			ns := &corev1.Namespace{}
+			err := r.Get(ctx, types.NamespacedName{Name: namespace}, ns)
+			if err != nil && apierrors.IsNotFound(err) {
+				return nil, &bindingError{
+					reason:  "NamespaceNotFound",
+					message: fmt.Sprintf("binding %q: namespace %s not found", binding.Name, namespace),
+				}
+			} else if err != nil {
+				return nil, err
+			}
+			annotation, annotated := ns.Annotations[operatorsv1alpha1.AllowedBindingNamespacesAnnotation]
+			if !resolvers.IsBindingAllowed(appToReconcile.Namespace, namespace, annotation, annotated) {
+				return nil, &bindingError{
+					reason: "BindingDenied",
+					message: fmt.Sprintf(
+						"binding %q: namespace %s does not allow bindings from namespace %s",
+						binding.Name, namespace, appToReconcile.Namespace,
+					),
+				}
+			}
+		}
+
+		target := &operatorsv1alpha1.Application{}
+		err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: binding.Application}, target)
+		if err != nil && apierrors.IsNotFound(err) {
+			return nil, &bindingError{
+				reason:  "ApplicationNotFound",
+				message: fmt.Sprintf("binding %q: application %s/%s not found", binding.Name, namespace, binding.Application),
+			}
+		} else if err != nil {
+			return nil, err
+		}
+
+		endpoint, err := resolvers.ResolveBindingEndpoint(target, binding)
+		if err != nil {
+			return nil, &bindingError{
+				reason:  "EndpointNotFound",
+				message: fmt.Sprintf("binding %q: %s", binding.Name, err),
+			}
+		}
+
+		envVars = append(envVars, resolvers.BuildBindingEnvVars(binding, endpoint)...)
+	}
+
+	return envVars, nil
+}
+
+// indexBindings indexes the applications by the namespaced names of the
+// applications they are bound to.
+func indexBindings(obj client.Object) []string {
+	app := obj.(*operatorsv1alpha1.Application)
+
+	keys := make([]string, 0, len(app.Spec.Bindings))
+	for _, binding := range app.Spec.Bindings {
+		keys = append(keys, types.NamespacedName{
+			Namespace: resolvers.BindingNamespace(app.Namespace, binding),
+			Name:      binding.Application,
+		}.String())
+	}
+
+	return keys
+}
+
+// findApplicationsForBinding enqueues the applications bound to an
+// application, in any namespace, so changes to its endpoints are rolled out
+// to them.
+func (r *ApplicationReconciler) findApplicationsForBinding(obj client.Object) []reconcile.Request {
+	key := types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}.String()
+
+	apps := &operatorsv1alpha1.ApplicationList{}
+	err := r.List(context.Background(), apps, client.MatchingFields{bindingsIndexKey: key})
+	if err != nil {
+		log.Log.Error(err, "failed to list applications bound to application", "application", key)
+		return []reconcile.Request{}
+	}
+
+	requests := make([]reconcile.Request, 0, len(apps.Items))
+	for _, app := range apps.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: app.Namespace,
+				Name:      app.Name,
+			},
+		})
+	}
+
+	return requests
+}
+
+// indexBindingNamespaces indexes the applications by the other namespaces
+// they are bound to.
+func indexBindingNamespaces(obj client.Object) []string {
+	app := obj.(*operatorsv1alpha1.Application)
+
+	namespaces := []string{}
+	for _, binding := range app.Spec.Bindings {
+		namespace := resolvers.BindingNamespace(app.Namespace, binding)
+		if namespace != app.Namespace {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+
+	return namespaces
+}
+
+// findApplicationsForBindingNamespace enqueues the applications bound to
+// applications of a namespace, so changes to the namespaces it allows
+// bindings from are picked up.
+func (r *ApplicationReconciler) findApplicationsForBindingNamespace(obj client.Object) []reconcile.Request {
+	apps := &operatorsv1alpha1.ApplicationList{}
+	err := r.List(context.Background(), apps, client.MatchingFields{bindingNamespacesIndexKey: obj.GetName()})
+	if err != nil {
+		log.Log.Error(err, "failed to list applications bound to namespace", "namespace", obj.GetName())
+		return []reconcile.Request{}
+	}
+
+	requests := make([]reconcile.Request, 0, len(apps.Items))
+	for _, app := range apps.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: app.Namespace,
+				Name:      app.Name,
+			},
+		})
+	}
+
+	return requests
+}
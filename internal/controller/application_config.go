@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"context"
+
+	operatorsv1alpha1 "github.com/perfectmak/k4indie/api/v1alpha1"
+	"github.com/perfectmak/k4indie/internal/controller/resolvers"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	configSecretsIndexKey    = ".spec.config.secrets"
+	configConfigMapsIndexKey = ".spec.config.configMaps"
+)
+
+// resolveConfigHash fetches all the Secrets and ConfigMaps referenced by
+// the application config and returns a hash of their content. Secrets are
+// read from the API server, since only the labeled ones are cached.
+// Missing objects are skipped since references can be optional, and the
+// pods will fail to start on their own for required ones.
+func (r *ApplicationReconciler) resolveConfigHash(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+) (string, error) {
+	secrets := []corev1.Secret{}
+	for _, name := range appToReconcile.Spec.Config.SecretNames() {
+		secret := corev1.Secret{}
+		err := r.APIReader.Get(ctx, types.NamespacedName{Namespace: appToReconcile.Namespace, Name: name}, &secret)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return "", err
+		}
+		secrets = append(secrets, secret)
+	}
+
+	configMaps := []corev1.ConfigMap{}
+	for _, name := range appToReconcile.Spec.Config.ConfigMapNames() {
+		configMap := corev1.ConfigMap{}
+		err := r.Get(ctx, types.NamespacedName{Namespace: appToReconcile.Namespace, Name: name}, &configMap)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return "", err
+		}
+		configMaps = append(configMaps, configMap)
+	}
+
+	return resolvers.HashConfigData(secrets, configMaps), nil
+}
+
+func indexConfigSecrets(obj client.Object) []string {
+	app := obj.(*operatorsv1alpha1.Application)
+	return app.Spec.Config.SecretNames()
+}
+
+func indexConfigConfigMaps(obj client.Object) []string {
+	app := obj.(*operatorsv1alpha1.Application)
+	return app.Spec.Config.ConfigMapNames()
+}
+
+// findApplicationsForConfig returns a mapping function that enqueues all
+// the applications in the object's namespace that reference it through
+// the given index. Cluster scoped objects are looked up in all namespaces.
+func (r *ApplicationReconciler) findApplicationsForConfig(indexKey string) func(client.Object) []reconcile.Request {
+	return func(obj client.Object) []reconcile.Request {
+		apps := &operatorsv1alpha1.ApplicationList{}
+		err := r.List(
+			context.Background(),
+			apps,
+			client.InNamespace(obj.GetNamespace()),
+			client.MatchingFields{indexKey: obj.GetName()},
+		)
+		if err != nil {
+			log.Log.Error(
+				err, "failed to list applications referencing config",
+				"config.name", obj.GetName(),
+				"config.namespace", obj.GetNamespace(),
+			)
+			return []reconcile.Request{}
+		}
+
+		requests := make([]reconcile.Request, 0, len(apps.Items))
+		for _, app := range apps.Items {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Namespace: app.Namespace,
+					Name:      app.Name,
+				},
+			})
+		}
+
+		return requests
+	}
+}
@@ -19,40 +19,84 @@ package controller
 import (
 	"context"
 	"fmt"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlbuilder "sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"github.com/go-logr/logr"
 	operatorsv1alpha1 "github.com/perfectmak/k4indie/api/v1alpha1"
+	"github.com/perfectmak/k4indie/internal/controller/resolvers"
 )
 
+// rolloutPollInterval is how often a deployment rollout is checked until it
+// settles.
+const rolloutPollInterval = 10 * time.Second
+
 // ApplicationReconciler reconciles a Application object
 type ApplicationReconciler struct {
 	client.Client
 	Scheme   *runtime.Scheme
 	Recorder record.EventRecorder
+	// APIReader reads the Secrets without the config label, and the pods and
+	// ReplicaSets of the deployments, which are not cached.
+	APIReader client.Reader
+	// RouterDefaults configures the router of the application domains for
+	// the fields the routing of the operator config does not set.
+	RouterDefaults resolvers.RouterConfig
 }
 
 var (
-	typeDeploymentAvailable = "DeploymentAvailable"
-	typeDeploymentDegraded  = "DeploymentDegraded"
+	typeDeploymentAvailable   = "DeploymentAvailable"
+	typeDeploymentProgressing = "DeploymentProgressing"
+	typeDeploymentDegraded    = "DeploymentDegraded"
+	typeReleaseFailed         = "ReleaseFailed"
+	typeCertificateReady      = "CertificateReady"
+	typeDomainsClaimed        = "DomainsClaimed"
+	typeScheduleFailed        = "ScheduleFailed"
 )
 
 //+kubebuilder:rbac:groups=operators.k4indie.io,resources=applications,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=operators.k4indie.io,resources=applications/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=operators.k4indie.io,resources=applications/finalizers,verbs=update
+//+kubebuilder:rbac:groups=operators.k4indie.io,resources=releases,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=operators.k4indie.io,resources=domainclaims,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=operators.k4indie.io,resources=k4indieconfigs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=operators.k4indie.io,resources=runtimesizes,verbs=get;list;watch
+//+kubebuilder:rbac:groups=operators.k4indie.io,resources=addons,verbs=get;list;watch
 //+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=apps,resources=replicasets,verbs=get;list;patch
+//+kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;patch
 //+kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=persistentvolumeclaims,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=traefik.io,resources=ingressroutes,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=cert-manager.io,resources=certificates,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;delete
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=limitranges,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -70,11 +114,60 @@ func (r *ApplicationReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, err
 	}
 
-	if appToReconcile.GetDeletionTimestamp() != nil {
-		return ctrl.Result{}, nil
+	result, err := r.reconcileFinalizer(ctx, appToReconcile)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if result != nil {
+		return *result, nil
+	}
+
+	result, err = r.reconcileReleaseHistory(ctx, req, appToReconcile)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if result != nil {
+		return *result, nil
+	}
+
+	domainsClaimed, err := r.reconcileDomainClaims(ctx, appToReconcile)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	result, err = r.reconcileBindings(ctx, appToReconcile)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if result != nil {
+		return *result, nil
+	}
+
+	result, err = r.reconcileVolumes(ctx, req, appToReconcile)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if result != nil {
+		return *result, nil
+	}
+
+	result, err = r.reconcileDeployment(ctx, req, appToReconcile)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if result != nil {
+		return *result, nil
+	}
+
+	result, err = r.reconcileSchedules(ctx, req, appToReconcile)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if result != nil {
+		return *result, nil
 	}
 
-	result, err := r.reconcileDeployment(ctx, req, appToReconcile)
+	result, err = r.reconcileAutoscaler(ctx, req, appToReconcile)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
@@ -90,7 +183,20 @@ func (r *ApplicationReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return *result, nil
 	}
 
-	result, err = r.reconcileIngress(ctx, req, appToReconcile)
+	result, err = r.reconcileRoutes(ctx, req, appToReconcile)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if result != nil {
+		return *result, nil
+	}
+
+	certificatesReady, err := r.reconcileCertificates(ctx, appToReconcile)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	result, err = r.recordRelease(ctx, req, appToReconcile)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
@@ -98,15 +204,50 @@ func (r *ApplicationReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return *result, nil
 	}
 
-	return r.setApplicationReconciled(ctx, req, appToReconcile, log)
+	reconciledResult, err := r.setApplicationReconciled(ctx, req, appToReconcile, log)
+	if err != nil {
+		return reconciledResult, err
+	}
+	if !certificatesReady {
+		requeueAfter(&reconciledResult, certificatePollInterval)
+	}
+	if !domainsClaimed {
+		requeueAfter(&reconciledResult, domainClaimPollInterval)
+	}
+
+	return reconciledResult, nil
+}
+
+// requeueAfter requeues the result after the interval, unless it is already
+// requeued sooner.
+func requeueAfter(result *reconcile.Result, interval time.Duration) {
+	if result.RequeueAfter == 0 || result.RequeueAfter > interval {
+		result.RequeueAfter = interval
+	}
 }
 
+// setApplicationReconciled reports the rollout progress of the application
+// deployments in its status. The application is requeued until the rollout
+// settles, since the deployments only report their progress over time.
 func (r *ApplicationReconciler) setApplicationReconciled(
 	ctx context.Context,
 	req reconcile.Request,
 	appToReconcile *operatorsv1alpha1.Application,
 	log logr.Logger,
 ) (reconcile.Result, error) {
+	rollout, err := r.resolveRolloutStatus(ctx, appToReconcile)
+	if err != nil {
+		log.Error(err, "failed to resolve rollout status")
+		return reconcile.Result{}, err
+	}
+	observedGeneration := appToReconcile.Generation
+	// The URL is resolved before the application is re-fetched, since only
+	// the in memory application excludes the domains it could not claim.
+	url := resolvers.ApplicationURL(
+		appToReconcile.Spec.TLS,
+		resolvers.ResolveProcesses(appToReconcile),
+	)
+
 	// Re-fetch the Resource before update the status
 	// so that we have the latest state of the resource on the cluster and we will avoid
 	// raise the issue "the object has been modified, please apply
@@ -116,24 +257,86 @@ func (r *ApplicationReconciler) setApplicationReconciled(
 		return reconcile.Result{}, err
 	}
 
-	meta.SetStatusCondition(
-		&appToReconcile.Status.Conditions,
-		metav1.Condition{
+	appToReconcile.Status.ObservedGeneration = observedGeneration
+	appToReconcile.Status.Image = operatorsv1alpha1.RuntimeImage(rollout.Image)
+	appToReconcile.Status.Replicas = rollout.Replicas
+	appToReconcile.Status.ReadyReplicas = rollout.ReadyReplicas
+	appToReconcile.Status.UpdatedReplicas = rollout.UpdatedReplicas
+	appToReconcile.Status.AvailableReplicas = rollout.AvailableReplicas
+	appToReconcile.Status.Selector = resolvers.ScaleSelector(appToReconcile)
+	appToReconcile.Status.URL = url
+
+	processCount := len(resolvers.ResolveProcesses(appToReconcile))
+	if rollout.Available {
+		meta.SetStatusCondition(&appToReconcile.Status.Conditions, metav1.Condition{
 			Type:   typeDeploymentAvailable,
 			Status: metav1.ConditionTrue,
 			Reason: "Reconciled",
 			Message: fmt.Sprintf(
-				"Deployment for custom resource (%s) with %d replicas created successfully",
+				"Deployments for custom resource (%s) with %d processes are available",
+				appToReconcile.Name,
+				processCount,
+			),
+		})
+	} else {
+		meta.SetStatusCondition(&appToReconcile.Status.Conditions, metav1.Condition{
+			Type:   typeDeploymentAvailable,
+			Status: metav1.ConditionFalse,
+			Reason: "MinimumReplicasUnavailable",
+			Message: fmt.Sprintf(
+				"Deployments for custom resource (%s) do not have minimum availability",
+				appToReconcile.Name,
+			),
+		})
+	}
+
+	if rollout.Progressing && !rollout.Degraded {
+		meta.SetStatusCondition(&appToReconcile.Status.Conditions, metav1.Condition{
+			Type:    typeDeploymentProgressing,
+			Status:  metav1.ConditionTrue,
+			Reason:  "RolloutInProgress",
+			Message: rollout.Message,
+		})
+	} else {
+		meta.SetStatusCondition(&appToReconcile.Status.Conditions, metav1.Condition{
+			Type:   typeDeploymentProgressing,
+			Status: metav1.ConditionFalse,
+			Reason: "RolloutSettled",
+			Message: fmt.Sprintf(
+				"Rollout of custom resource (%s) is not progressing",
 				appToReconcile.Name,
-				appToReconcile.Spec.Replicas,
 			),
 		})
+	}
+
+	if rollout.Degraded {
+		meta.SetStatusCondition(&appToReconcile.Status.Conditions, metav1.Condition{
+			Type:    typeDeploymentDegraded,
+			Status:  metav1.ConditionTrue,
+			Reason:  "RolloutFailed",
+			Message: rollout.Message,
+		})
+	} else {
+		meta.SetStatusCondition(&appToReconcile.Status.Conditions, metav1.Condition{
+			Type:   typeDeploymentDegraded,
+			Status: metav1.ConditionFalse,
+			Reason: "RolloutHealthy",
+			Message: fmt.Sprintf(
+				"Deployments for custom resource (%s) are healthy",
+				appToReconcile.Name,
+			),
+		})
+	}
 
 	if err := r.Status().Update(ctx, appToReconcile); err != nil {
 		log.Error(err, "failed to update application status")
 		return reconcile.Result{}, err
 	}
 
+	if rollout.Progressing && !rollout.Degraded {
+		return reconcile.Result{RequeueAfter: rolloutPollInterval}, nil
+	}
+
 	return reconcile.Result{}, nil
 }
 
@@ -174,10 +377,167 @@ func (r *ApplicationReconciler) setApplicationReconcileError(
 	return nil, err
 }
 
+// resolveRolloutStatus aggregates the rollout status of the workloads of the
+// application processes.
+func (r *ApplicationReconciler) resolveRolloutStatus(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+) (resolvers.RolloutStatus, error) {
+	if resolvers.IsStateful(appToReconcile) {
+		statefulSets, err := r.getProcessStatefulSets(ctx, appToReconcile)
+		if err != nil {
+			return resolvers.RolloutStatus{}, err
+		}
+		return resolvers.ResolveStatefulSetRolloutStatus(statefulSets), nil
+	}
+
+	deployments, err := r.getProcessDeployments(ctx, appToReconcile)
+	if err != nil {
+		return resolvers.RolloutStatus{}, err
+	}
+	return resolvers.ResolveRolloutStatus(deployments), nil
+}
+
+// updateStatus updates the status of the application without overwriting
+// its spec in memory, since the spec can hold a rolled back release.
+func (r *ApplicationReconciler) updateStatus(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+) error {
+	updated := appToReconcile.DeepCopy()
+	if err := r.Status().Update(ctx, updated); err != nil {
+		return err
+	}
+
+	appToReconcile.ResourceVersion = updated.ResourceVersion
+	appToReconcile.Status = updated.Status
+
+	return nil
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *ApplicationReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	// Index applications by the Secrets and ConfigMaps they reference so
+	// that changes to those objects can be traced back to the applications.
+	err := mgr.GetFieldIndexer().IndexField(
+		context.Background(),
+		&operatorsv1alpha1.Application{},
+		configSecretsIndexKey,
+		indexConfigSecrets,
+	)
+	if err != nil {
+		return err
+	}
+
+	err = mgr.GetFieldIndexer().IndexField(
+		context.Background(),
+		&operatorsv1alpha1.Application{},
+		configConfigMapsIndexKey,
+		indexConfigConfigMaps,
+	)
+	if err != nil {
+		return err
+	}
+
+	err = mgr.GetFieldIndexer().IndexField(
+		context.Background(),
+		&operatorsv1alpha1.Application{},
+		runtimeSizesIndexKey,
+		indexRuntimeSizes,
+	)
+	if err != nil {
+		return err
+	}
+
+	err = mgr.GetFieldIndexer().IndexField(
+		context.Background(),
+		&operatorsv1alpha1.Application{},
+		addonsIndexKey,
+		indexAddons,
+	)
+	if err != nil {
+		return err
+	}
+
+	err = mgr.GetFieldIndexer().IndexField(
+		context.Background(),
+		&operatorsv1alpha1.Application{},
+		bindingsIndexKey,
+		indexBindings,
+	)
+	if err != nil {
+		return err
+	}
+
+	err = mgr.GetFieldIndexer().IndexField(
+		context.Background(),
+		&operatorsv1alpha1.Application{},
+		bindingNamespacesIndexKey,
+		indexBindingNamespaces,
+	)
+	if err != nil {
+		return err
+	}
+
+	builder := ctrl.NewControllerManagedBy(mgr).
 		For(&operatorsv1alpha1.Application{}).
 		Owns(&appsv1.Deployment{}).
+		Owns(&appsv1.StatefulSet{}).
+		Owns(&corev1.Service{}).
+		Owns(&corev1.PersistentVolumeClaim{}).
+		Owns(&batchv1.Job{}).
+		Owns(&batchv1.CronJob{}).
+		Owns(&autoscalingv2.HorizontalPodAutoscaler{})
+	// The router can change at runtime, so the routing objects of all the
+	// routers whose CRDs are installed are watched.
+	for _, routeType := range resolvers.RouteTypes() {
+		installed, err := isInstalled(mgr.GetRESTMapper(), mgr.GetScheme(), routeType)
+		if err != nil {
+			return err
+		}
+		if installed {
+			builder = builder.Owns(routeType)
+		}
+	}
+
+	return builder.
+		Watches(
+			&source.Kind{Type: &corev1.Secret{}},
+			handler.EnqueueRequestsFromMapFunc(r.findApplicationsForConfig(configSecretsIndexKey)),
+		).
+		Watches(
+			&source.Kind{Type: &corev1.ConfigMap{}},
+			handler.EnqueueRequestsFromMapFunc(r.findApplicationsForConfig(configConfigMapsIndexKey)),
+		).
+		Watches(
+			&source.Kind{Type: &operatorsv1alpha1.RuntimeSize{}},
+			handler.EnqueueRequestsFromMapFunc(r.findApplicationsForRuntimeSize),
+		).
+		Watches(
+			&source.Kind{Type: &corev1.LimitRange{}},
+			handler.EnqueueRequestsFromMapFunc(r.findApplicationsForLimitRange),
+		).
+		Watches(
+			&source.Kind{Type: &operatorsv1alpha1.Addon{}},
+			handler.EnqueueRequestsFromMapFunc(r.findApplicationsForConfig(addonsIndexKey)),
+		).
+		// Only spec changes of bound applications change the addresses
+		// exposed to the applications bound to them.
+		Watches(
+			&source.Kind{Type: &operatorsv1alpha1.Application{}},
+			handler.EnqueueRequestsFromMapFunc(r.findApplicationsForBinding),
+			ctrlbuilder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
+		// Only annotation changes of namespaces change the namespaces they
+		// allow bindings from.
+		Watches(
+			&source.Kind{Type: &corev1.Namespace{}},
+			handler.EnqueueRequestsFromMapFunc(r.findApplicationsForBindingNamespace),
+			ctrlbuilder.WithPredicates(predicate.AnnotationChangedPredicate{}),
+		).
+		Watches(
+			&source.Kind{Type: &operatorsv1alpha1.K4IndieConfig{}},
+			handler.EnqueueRequestsFromMapFunc(r.findApplicationsForOperatorConfig),
+		).
 		Complete(r)
 }
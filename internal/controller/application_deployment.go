@@ -5,33 +5,148 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/go-logr/logr"
 	operatorsv1alpha1 "github.com/perfectmak/k4indie/api/v1alpha1"
 	"github.com/perfectmak/k4indie/internal/controller/resolvers"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
-// reconcileDeployment attempts to create a deployment for the application
-// if it does not exist. And if it does, it tries to update the deployment
-// schema to match the application spec.
+// reconcileDeployment attempts to create a deployment, or a statefulset for
+// stateful applications, for each process of the application if it does not
+// exist. And if it does, it tries to update it to match the application spec.
+// When the application has a release command, the release phase has to
+// succeed before any deployment is updated.
+// Workloads of processes that are no longer declared are deleted.
 func (r *ApplicationReconciler) reconcileDeployment(
 	ctx context.Context,
 	req reconcile.Request,
 	appToReconcile *operatorsv1alpha1.Application,
 ) (*reconcile.Result, error) {
 	log := log.FromContext(ctx)
+	processes := resolvers.ResolveProcesses(appToReconcile)
+
+	result, err := r.reconcileRelease(ctx, req, appToReconcile)
+	if err != nil || result != nil {
+		return result, err
+	}
+
+	stateful := resolvers.IsStateful(appToReconcile)
+	var requeue *reconcile.Result
+	for _, process := range processes {
+		if stateful {
+			result, err = r.reconcileProcessStatefulSet(ctx, req, appToReconcile, process)
+		} else {
+			result, err = r.reconcileProcessDeployment(ctx, req, appToReconcile, process)
+		}
+		if err != nil {
+			return result, err
+		}
+		if result != nil {
+			requeue = result
+		}
+	}
+
+	// The workloads of the other workload type are stale, so switching the
+	// workload type replaces all of them.
+	resourceNames := make([]string, 0, len(processes))
+	for _, process := range processes {
+		resourceNames = append(resourceNames, process.ResourceName)
+	}
+	deploymentNames, statefulSetNames := resourceNames, []string{}
+	if stateful {
+		deploymentNames, statefulSetNames = statefulSetNames, deploymentNames
+	}
+	err = r.deleteStaleResources(ctx, appToReconcile, &appsv1.DeploymentList{}, deploymentNames)
+	if err != nil {
+		log.Error(err, "failed to delete stale deployments")
+		return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
+	}
+	err = r.deleteStaleResources(ctx, appToReconcile, &appsv1.StatefulSetList{}, statefulSetNames)
+	if err != nil {
+		log.Error(err, "failed to delete stale statefulsets")
+		return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
+	}
+
+	return requeue, nil
+}
+
+// getProcessDeployments returns the existing deployments of the processes
+// of the application.
+func (r *ApplicationReconciler) getProcessDeployments(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+) ([]appsv1.Deployment, error) {
+	deployments := []appsv1.Deployment{}
+
+	for _, process := range resolvers.ResolveProcesses(appToReconcile) {
+		deployment := &appsv1.Deployment{}
+		err := r.Get(
+			ctx,
+			types.NamespacedName{Namespace: appToReconcile.Namespace, Name: process.ResourceName},
+			deployment,
+		)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		deployments = append(deployments, *deployment)
+	}
+
+	return deployments, nil
+}
+
+// getProcessPodTemplate returns the pod template of the existing workload of
+// a process, or nil when the process has no workload yet.
+func (r *ApplicationReconciler) getProcessPodTemplate(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+	process resolvers.Process,
+) (*corev1.PodTemplateSpec, error) {
+	key := types.NamespacedName{Namespace: appToReconcile.Namespace, Name: process.ResourceName}
+
+	if resolvers.IsStateful(appToReconcile) {
+		statefulSet := &appsv1.StatefulSet{}
+		if err := r.Get(ctx, key, statefulSet); err != nil {
+			return nil, client.IgnoreNotFound(err)
+		}
+		return &statefulSet.Spec.Template, nil
+	}
+
+	deployment := &appsv1.Deployment{}
+	if err := r.Get(ctx, key, deployment); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	return &deployment.Spec.Template, nil
+}
+
+func (r *ApplicationReconciler) reconcileProcessDeployment(
+	ctx context.Context,
+	req reconcile.Request,
+	appToReconcile *operatorsv1alpha1.Application,
+	process resolvers.Process,
+) (*reconcile.Result, error) {
+	log := log.FromContext(ctx).WithValues("process", process.Name)
 	deployment := &appsv1.Deployment{}
-	err := r.Get(ctx, req.NamespacedName, deployment)
+	err := r.Get(
+		ctx,
+		types.NamespacedName{Namespace: appToReconcile.Namespace, Name: process.ResourceName},
+		deployment,
+	)
 
 	if err != nil && apierrors.IsNotFound(err) {
-		deployment, err = r.createDeployment(ctx, appToReconcile)
+		_, err = r.createDeployment(ctx, appToReconcile, process)
 		if err != nil {
 			log.Error(err, "failed to create deployment")
 
@@ -48,7 +163,7 @@ func (r *ApplicationReconciler) reconcileDeployment(
 				},
 			)
 
-			if err := r.Status().Update(ctx, appToReconcile); err != nil {
+			if err := r.updateStatus(ctx, appToReconcile); err != nil {
 				log.Error(err, "failed to update application status")
 				return nil, err
 			}
@@ -63,27 +178,136 @@ func (r *ApplicationReconciler) reconcileDeployment(
 		return nil, err
 	}
 
-	err = r.updateDeploymentSpec(ctx, appToReconcile, deployment, log)
+	newDeployment, err := r.buildDeployment(ctx, appToReconcile, process)
 	if err != nil {
 		return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
 	}
 
-	if err := r.Update(ctx, deployment); err != nil {
+	if process.Autoscale != nil {
+		if err := r.handOverReplicas(ctx, deployment, deployment.Spec.Replicas); err != nil {
+			log.Error(err, "failed to hand over deployment replicas")
+			return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
+		}
+	}
+
+	// Deployment selectors are immutable, so deployments created with a
+	// different selector have to be recreated.
+	if !equality.Semantic.DeepEqual(newDeployment.Spec.Selector, deployment.Spec.Selector) {
+		if deployment.GetDeletionTimestamp() != nil {
+			return &reconcile.Result{RequeueAfter: time.Second}, nil
+		}
+
+		log.Info("recreating deployment with a new selector")
+		if err := r.migrateDeploymentSelector(ctx, appToReconcile, process, deployment); err != nil {
+			return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
+		}
+
+		return &reconcile.Result{RequeueAfter: time.Second}, nil
+	}
+
+	drifted := resolvers.IsDrifted(newDeployment, deployment, newDeployment.Spec, deployment.Spec)
+	if err := r.applyResource(ctx, newDeployment); err != nil {
 		log.Error(err, "failed to update deployment")
 
 		return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
 	}
+	if drifted {
+		r.recordDriftCorrected(appToReconcile, "Deployment", deployment.Name)
+	}
 
 	return nil, nil
 }
 
+// migrateDeploymentSelector deletes a deployment whose selector changed
+// without stopping its pods. Its ReplicaSets and pods are labeled with the
+// new selector labels, so the new deployment adopts the ReplicaSets and
+// replaces their pods with a rolling update, while the Service keeps
+// routing to them. Pods are read from the API server, so the operator does
+// not cache the pods of the whole cluster.
+func (r *ApplicationReconciler) migrateDeploymentSelector(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+	process resolvers.Process,
+	deployment *appsv1.Deployment,
+) error {
+	selectorLabels := resolvers.SelectorLabels(appToReconcile.Name, process.Name)
+
+	replicaSets := &appsv1.ReplicaSetList{}
+	err := r.APIReader.List(ctx, replicaSets, client.InNamespace(deployment.Namespace))
+	if err != nil {
+		return err
+	}
+
+	for i := range replicaSets.Items {
+		replicaSet := &replicaSets.Items[i]
+		if !metav1.IsControlledBy(replicaSet, deployment) {
+			continue
+		}
+
+		selector, err := metav1.LabelSelectorAsSelector(replicaSet.Spec.Selector)
+		if err != nil {
+			return err
+		}
+		pods := &corev1.PodList{}
+		err = r.APIReader.List(
+			ctx,
+			pods,
+			client.InNamespace(deployment.Namespace),
+			client.MatchingLabelsSelector{Selector: selector},
+		)
+		if err != nil {
+			return err
+		}
+		for j := range pods.Items {
+			if err := r.addLabels(ctx, &pods.Items[j], selectorLabels); err != nil {
+				return err
+			}
+		}
+
+		if err := r.addLabels(ctx, replicaSet, selectorLabels); err != nil {
+			return err
+		}
+	}
+
+	err = r.Delete(ctx, deployment, client.PropagationPolicy(metav1.DeletePropagationOrphan))
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+// addLabels adds the labels to the object, unless it already has them.
+func (r *ApplicationReconciler) addLabels(ctx context.Context, obj client.Object, labels map[string]string) error {
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+
+	objLabels := obj.GetLabels()
+	if objLabels == nil {
+		objLabels = map[string]string{}
+	}
+	changed := false
+	for key, value := range labels {
+		if objLabels[key] != value {
+			objLabels[key] = value
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	obj.SetLabels(objLabels)
+
+	return client.IgnoreNotFound(r.Patch(ctx, obj, patch))
+}
+
 func (r *ApplicationReconciler) createDeployment(
 	ctx context.Context,
 	appToReconcile *operatorsv1alpha1.Application,
+	process resolvers.Process,
 ) (*appsv1.Deployment, error) {
 	log := log.FromContext(ctx)
 
-	deployment, err := r.buildDeployment(ctx, appToReconcile)
+	deployment, err := r.buildDeployment(ctx, appToReconcile, process)
 	if err != nil {
 		return nil, err
 	}
@@ -93,7 +317,7 @@ func (r *ApplicationReconciler) createDeployment(
 		"deployment.name", deployment.Name,
 		"deployment.namespace", deployment.Namespace,
 	)
-	if err := r.Create(ctx, deployment); err != nil {
+	if err := r.applyResource(ctx, deployment); err != nil {
 		return nil, err
 	}
 
@@ -103,82 +327,150 @@ func (r *ApplicationReconciler) createDeployment(
 func (r *ApplicationReconciler) buildDeployment(
 	ctx context.Context,
 	appToReconcile *operatorsv1alpha1.Application,
+	process resolvers.Process,
 ) (*appsv1.Deployment, error) {
-	labels := resolvers.MergeDefaultLabels(
-		appToReconcile.Labels,
-		map[string]string{
-			"app.kubernetes.io/instance": appToReconcile.Name,
-			"app.kubernetes.io/version":  appToReconcile.Spec.Runtime.Image.Tag(),
-		})
-	resourcesRequired, err := resolvers.GetResourcesForRuntimeSize(
-		appToReconcile.Spec.Runtime.Size,
-	)
+	podTemplate, err := r.buildProcessPodTemplate(ctx, appToReconcile, process)
 	if err != nil {
 		return nil, err
 	}
+	volumes, mounts := resolvers.BuildVolumes(appToReconcile.Name, appToReconcile.Spec.Volumes)
+	podTemplate.Spec.Volumes = volumes
+	podTemplate.Spec.Containers[0].VolumeMounts = mounts
 
 	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      appToReconcile.Name,
+			Name:      process.ResourceName,
 			Namespace: appToReconcile.Namespace,
-			Labels:    labels,
+			Labels:    podTemplate.Labels,
 		},
 		Spec: appsv1.DeploymentSpec{
-			Replicas: &appToReconcile.Spec.Replicas,
+			// Autoscaled deployments start with a single replica, until the
+			// autoscaler scales them to its minimum.
+			Replicas: resolvers.ProcessReplicas(process),
 			Selector: &metav1.LabelSelector{
-				MatchLabels: labels,
-			},
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Labels: labels,
-				},
-				Spec: corev1.PodSpec{
-					SecurityContext: &corev1.PodSecurityContext{
-						RunAsNonRoot: &[]bool{true}[0],
-						SeccompProfile: &corev1.SeccompProfile{
-							Type: corev1.SeccompProfileTypeRuntimeDefault,
-						},
-					},
-					Containers: []corev1.Container{{
-						Image:           appToReconcile.Spec.Runtime.Image.String(),
-						Name:            "application",
-						ImagePullPolicy: corev1.PullIfNotPresent,
-						SecurityContext: &corev1.SecurityContext{
-							RunAsNonRoot:             &[]bool{true}[0],
-							AllowPrivilegeEscalation: &[]bool{false}[0],
-							Capabilities: &corev1.Capabilities{
-								Drop: []corev1.Capability{
-									"ALL",
-								},
-							},
-						},
-						Ports:     appToReconcile.Spec.Endpoints.AsContainerPorts(),
-						Command:   appToReconcile.Spec.LaunchCommand,
-						Resources: resourcesRequired,
-					}},
-				},
+				MatchLabels: resolvers.SelectorLabels(appToReconcile.Name, process.Name),
 			},
+			Template: podTemplate,
+			Strategy: resolvers.BuildDeploymentStrategy(appToReconcile.Spec.Volumes),
 		},
 	}
 
+	if err := setRenderedHash(deployment, deployment.Spec); err != nil {
+		return nil, err
+	}
+
 	if err := ctrl.SetControllerReference(appToReconcile, deployment, r.Scheme); err != nil {
 		return nil, err
 	}
 	return deployment, nil
 }
 
-func (r *ApplicationReconciler) updateDeploymentSpec(
+// buildProcessPodTemplate builds the pod template of the workload of a
+// process, which is annotated with the release it runs. Only the workloads
+// of the processes mount the volumes, release and scheduled tasks run
+// without them, so the volumes are added by the caller.
+func (r *ApplicationReconciler) buildProcessPodTemplate(
 	ctx context.Context,
 	appToReconcile *operatorsv1alpha1.Application,
-	deployment *appsv1.Deployment,
-	log logr.Logger,
-) error {
-	newDeployment, err := r.buildDeployment(ctx, appToReconcile)
+	process resolvers.Process,
+) (corev1.PodTemplateSpec, error) {
+	podTemplate, err := r.buildPodTemplate(ctx, appToReconcile, process)
 	if err != nil {
-		return err
+		return corev1.PodTemplateSpec{}, err
+	}
+	if len(appToReconcile.Spec.Release) > 0 {
+		releaseHash, err := r.resolveReleaseHash(ctx, appToReconcile)
+		if err != nil {
+			return corev1.PodTemplateSpec{}, err
+		}
+		podTemplate.Annotations[resolvers.ReleaseHashAnnotation] = releaseHash
 	}
 
-	newDeployment.DeepCopyInto(deployment)
+	return podTemplate, nil
+}
 
-	return nil
+// buildPodTemplate builds the pod template running the given process with
+// the application image and config.
+func (r *ApplicationReconciler) buildPodTemplate(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+	process resolvers.Process,
+) (corev1.PodTemplateSpec, error) {
+	labels := resolvers.MergeDefaultLabels(
+		appToReconcile.Labels,
+		resolvers.SelectorLabels(appToReconcile.Name, process.Name),
+		map[string]string{
+			resolvers.VersionLabel: appToReconcile.Spec.Runtime.Image.Tag(),
+		})
+	operatorConfig, err := r.resolveOperatorConfig(ctx)
+	if err != nil {
+		return corev1.PodTemplateSpec{}, err
+	}
+	size, err := r.getRuntimeSize(ctx, process.Size)
+	if err != nil {
+		return corev1.PodTemplateSpec{}, err
+	}
+	resources, err := r.resolveResources(ctx, appToReconcile, size)
+	if err != nil {
+		return corev1.PodTemplateSpec{}, err
+	}
+	configHash, err := r.resolveConfigHash(ctx, appToReconcile)
+	if err != nil {
+		return corev1.PodTemplateSpec{}, err
+	}
+	probes, err := resolvers.BuildProbes(process)
+	if err != nil {
+		return corev1.PodTemplateSpec{}, err
+	}
+	envVars, err := r.resolveEnvVars(ctx, appToReconcile)
+	if err != nil {
+		return corev1.PodTemplateSpec{}, err
+	}
+
+	return corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: labels,
+			Annotations: map[string]string{
+				resolvers.ConfigHashAnnotation: configHash,
+			},
+		},
+		Spec: corev1.PodSpec{
+			SecurityContext: operatorConfig.PodSecurityContext,
+			NodeSelector:    size.Spec.NodeSelector,
+			Tolerations:     size.Spec.Tolerations,
+			Containers: []corev1.Container{{
+				Image:           appToReconcile.Spec.Runtime.Image.String(),
+				Name:            "application",
+				ImagePullPolicy: operatorConfig.ImagePullPolicy,
+				SecurityContext: operatorConfig.SecurityContext,
+				Ports:           process.Endpoints.AsContainerPorts(),
+				Command:         process.Command,
+				Env:             envVars,
+				EnvFrom:         appToReconcile.Spec.Config.AsEnvFromSources(),
+				Resources:       resources,
+				ReadinessProbe:  probes.Readiness,
+				LivenessProbe:   probes.Liveness,
+				StartupProbe:    probes.Startup,
+			}},
+		},
+	}, nil
+}
+
+// resolveEnvVars returns the environment variables of the application
+// containers. Bound addresses replace the addon URLs with the same name,
+// and config vars replace both, since containers can't repeat a name.
+func (r *ApplicationReconciler) resolveEnvVars(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+) ([]corev1.EnvVar, error) {
+	addonEnvVars, err := r.resolveAddonEnvVars(ctx, appToReconcile)
+	if err != nil {
+		return nil, err
+	}
+	bindingEnvVars, err := r.resolveBindingEnvVars(ctx, appToReconcile)
+	if err != nil {
+		return nil, err
+	}
+
+	return resolvers.MergeEnvVars(addonEnvVars, bindingEnvVars, appToReconcile.Spec.Config.AsEnvVars()), nil
 }
@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	operatorsv1alpha1 "github.com/perfectmak/k4indie/api/v1alpha1"
+	"github.com/perfectmak/k4indie/internal/controller/resolvers"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// TestAutoscaledWorkloadsDoNotApplyReplicas checks that the operator does not
+// own the replicas of autoscaled workloads, since the fields it owns are the
+// ones of the objects it applies.
+func TestAutoscaledWorkloadsDoNotApplyReplicas(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := operatorsv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	size := &operatorsv1alpha1.RuntimeSize{
+		ObjectMeta: metav1.ObjectMeta{Name: string(operatorsv1alpha1.BasicMachineType)},
+		Spec: operatorsv1alpha1.RuntimeSizeSpec{
+			Requests: operatorsv1alpha1.RuntimeSizeResources{
+				CPU:    resource.MustParse("250m"),
+				Memory: resource.MustParse("512Mi"),
+			},
+		},
+	}
+	app := &operatorsv1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "default", UID: "shop-uid"},
+		Spec: operatorsv1alpha1.ApplicationSpec{
+			Runtime:   operatorsv1alpha1.ApplicationRuntime{Image: "shop:v1", Size: operatorsv1alpha1.BasicMachineType},
+			Replicas:  2,
+			Autoscale: &operatorsv1alpha1.Autoscale{Min: 2, Max: 5},
+		},
+	}
+	r := &ApplicationReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(size).Build(),
+		Scheme: scheme,
+	}
+	process := resolvers.ResolveProcesses(app)[0]
+
+	deployment, err := r.buildDeployment(context.Background(), app, process)
+	if err != nil {
+		t.Fatalf("buildDeployment() error = %v", err)
+	}
+	statefulSet, err := r.buildStatefulSet(context.Background(), app, process)
+	if err != nil {
+		t.Fatalf("buildStatefulSet() error = %v", err)
+	}
+
+	for _, obj := range []client.Object{deployment, statefulSet} {
+		data, err := client.Apply.Data(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		applied := struct {
+			Spec map[string]json.RawMessage `json:"spec"`
+		}{}
+		if err := json.Unmarshal(data, &applied); err != nil {
+			t.Fatal(err)
+		}
+		if replicas, exists := applied.Spec["replicas"]; exists {
+			t.Errorf("%T applies replicas %s, want none for autoscaled processes", obj, replicas)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	operatorsv1alpha1 "github.com/perfectmak/k4indie/api/v1alpha1"
+	"github.com/perfectmak/k4indie/internal/controller/resolvers"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// domainClaimPollInterval is how often domains claimed by other
+// applications are checked again.
+const domainClaimPollInterval = time.Minute
+
+// reconcileDomainClaims claims the domains and paths of the application
+// endpoints and releases the ones it no longer uses. Paths that are claimed
+// by another application, or whose domain is not allowed in the namespace
+// of the application, are removed from appToReconcile in memory, so the
+// rest of the reconciliation does not route them.
+// It returns false while some paths could not be claimed.
+func (r *ApplicationReconciler) reconcileDomainClaims(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+) (bool, error) {
+	log := log.FromContext(ctx)
+	routes := resolvers.ResolveDomainRoutes(resolvers.ResolveProcesses(appToReconcile))
+
+	allowed, err := r.resolveAllowedDomains(ctx, appToReconcile.Namespace)
+	if err != nil {
+		log.Error(err, "failed to get allowed domains")
+		return false, err
+	}
+
+	claimed := []resolvers.DomainRoute{}
+	denied := []resolvers.DomainRoute{}
+	conflicts := []string{}
+	for _, route := range routes {
+		if allowed != nil && !resolvers.IsDomainAllowed(route.Domain, allowed) {
+			denied = append(denied, route)
+			conflicts = append(conflicts, fmt.Sprintf("%s is not allowed in namespace %s", route.Domain, appToReconcile.Namespace))
+			continue
+		}
+
+		granted := allowed != nil && resolvers.IsDomainGranted(route.Domain, allowed)
+		owner, err := r.claimDomain(ctx, appToReconcile, route, granted)
+		if err != nil {
+			log.Error(err, "failed to claim domain", "domain", route.Domain, "path", route.Path)
+			return false, err
+		}
+		if owner != nil {
+			denied = append(denied, route)
+			conflicts = append(conflicts, fmt.Sprintf("%s is claimed by %s/%s", route, owner.Namespace, owner.Name))
+			continue
+		}
+
+		claimed = append(claimed, route)
+	}
+
+	if err := r.releaseDomainClaims(ctx, appToReconcile, claimed); err != nil {
+		log.Error(err, "failed to release domain claims")
+		return false, err
+	}
+
+	var condition *metav1.Condition
+	switch {
+	case len(denied) > 0:
+		condition = &metav1.Condition{
+			Type:    typeDomainsClaimed,
+			Status:  metav1.ConditionFalse,
+			Reason:  "DomainConflict",
+			Message: fmt.Sprintf("Domains are not routed: %s", strings.Join(conflicts, ", ")),
+		}
+	case len(routes) > 0:
+		condition = &metav1.Condition{
+			Type:    typeDomainsClaimed,
+			Status:  metav1.ConditionTrue,
+			Reason:  "DomainsClaimed",
+			Message: fmt.Sprintf("%d domain paths are claimed", len(routes)),
+		}
+	}
+
+	changed := false
+	existing := meta.FindStatusCondition(appToReconcile.Status.Conditions, typeDomainsClaimed)
+	if condition == nil {
+		if existing != nil {
+			meta.RemoveStatusCondition(&appToReconcile.Status.Conditions, typeDomainsClaimed)
+			changed = true
+		}
+	} else if existing == nil || existing.Status != condition.Status || existing.Message != condition.Message {
+		meta.SetStatusCondition(&appToReconcile.Status.Conditions, *condition)
+		changed = true
+	}
+	if changed {
+		if err := r.updateStatus(ctx, appToReconcile); err != nil {
+			log.Error(err, "failed to update application status")
+			return false, err
+		}
+	}
+
+	resolvers.RemoveEndpointRoutes(appToReconcile, denied)
+
+	return len(denied) == 0, nil
+}
+
+// resolveAllowedDomains returns the domains the applications of the
+// namespace may claim, or nil when they may claim any domain.
+func (r *ApplicationReconciler) resolveAllowedDomains(ctx context.Context, namespace string) ([]string, error) {
+	ns := &corev1.Namespace{}
+	if err := r.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+		return nil, err
+	}
+
+	annotation, exists := ns.Annotations[operatorsv1alpha1.AllowedDomainsAnnotation]
+	if !exists {
+		return nil, nil
+	}
+
+	return resolvers.ParseAllowedDomains(annotation), nil
+}
+
+// claimDomain claims the path of the domain for the application if it is
+// not claimed yet. The first claim of a domain owns the whole domain, so the
+// paths of a domain owned by another namespace are only claimed when the
+// domain is granted to the namespace of the application. It returns the
+// application that claimed the path, or that owns the domain, when the path
+// can't be claimed.
+func (r *ApplicationReconciler) claimDomain(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+	route resolvers.DomainRoute,
+	granted bool,
+) (*operatorsv1alpha1.ApplicationReference, error) {
+	log := log.FromContext(ctx)
+	ref := operatorsv1alpha1.ApplicationReference{
+		Namespace: appToReconcile.Namespace,
+		Name:      appToReconcile.Name,
+	}
+
+	owner, err := r.resolveForeignDomainOwner(ctx, r.Client, appToReconcile, route, granted)
+	if err != nil || owner != nil {
+		return owner, err
+	}
+
+	claim := &operatorsv1alpha1.DomainClaim{}
+	err = r.Get(ctx, types.NamespacedName{Name: resolvers.DomainClaimName(route)}, claim)
+	if err != nil && apierrors.IsNotFound(err) {
+		claim = &operatorsv1alpha1.DomainClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: resolvers.DomainClaimName(route),
+				Labels: resolvers.MergeDefaultLabels(map[string]string{
+					resolvers.InstanceLabel:             appToReconcile.Name,
+					resolvers.ApplicationNamespaceLabel: appToReconcile.Namespace,
+				}),
+			},
+			Spec: operatorsv1alpha1.DomainClaimSpec{
+				Domain:         route.Domain,
+				Path:           route.Path,
+				ApplicationRef: ref,
+			},
+		}
+
+		log.Info("claiming domain", "domain", route.Domain, "path", route.Path)
+		err = r.Create(ctx, claim)
+		if err == nil {
+			// Another namespace may have claimed another path of the domain
+			// in the meantime, which the cache doesn't know yet.
+			owner, err := r.resolveForeignDomainOwner(ctx, r.APIReader, appToReconcile, route, granted)
+			if err != nil || owner == nil {
+				return nil, err
+			}
+			log.Info("releasing domain owned by another namespace", "domain", route.Domain, "path", route.Path)
+			if err := r.Delete(ctx, claim); err != nil && !apierrors.IsNotFound(err) {
+				return nil, err
+			}
+			return owner, nil
+		}
+		if !apierrors.IsAlreadyExists(err) {
+			return nil, err
+		}
+
+		// Another application claimed the path in the meantime.
+		if err := r.Get(ctx, types.NamespacedName{Name: claim.Name}, claim); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	if claim.Spec.ApplicationRef != ref || resolvers.ClaimedDomainRoute(claim) != route {
+		return &claim.Spec.ApplicationRef, nil
+	}
+
+	return nil, nil
+}
+
+// resolveForeignDomainOwner returns the application owning the domain of the
+// route when it belongs to another namespace that doesn't share the domain
+// with the namespace of the application, or nil otherwise.
+func (r *ApplicationReconciler) resolveForeignDomainOwner(
+	ctx context.Context,
+	reader client.Reader,
+	appToReconcile *operatorsv1alpha1.Application,
+	route resolvers.DomainRoute,
+	granted bool,
+) (*operatorsv1alpha1.ApplicationReference, error) {
+	if granted {
+		return nil, nil
+	}
+
+	claims := &operatorsv1alpha1.DomainClaimList{}
+	if err := reader.List(ctx, claims); err != nil {
+		return nil, err
+	}
+	owner := resolvers.DomainOwner(claims.Items, route.Domain)
+	if owner == nil || owner.Spec.ApplicationRef.Namespace == appToReconcile.Namespace {
+		return nil, nil
+	}
+
+	return &owner.Spec.ApplicationRef, nil
+}
+
+// releaseDomainClaims deletes the domain claims of the application, except
+// the claims of the paths in keepRoutes.
+func (r *ApplicationReconciler) releaseDomainClaims(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+	keepRoutes []resolvers.DomainRoute,
+) error {
+	log := log.FromContext(ctx)
+
+	claims := &operatorsv1alpha1.DomainClaimList{}
+	err := r.List(ctx, claims, client.MatchingLabels{
+		resolvers.InstanceLabel:             appToReconcile.Name,
+		resolvers.ApplicationNamespaceLabel: appToReconcile.Namespace,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Claims are kept by name, so claims named differently by previous
+	// versions of the operator are released.
+	keep := map[string]struct{}{}
+	for _, route := range keepRoutes {
+		keep[resolvers.DomainClaimName(route)] = struct{}{}
+	}
+
+	for i := range claims.Items {
+		claim := &claims.Items[i]
+		if _, exists := keep[claim.Name]; exists {
+			continue
+		}
+		if claim.Spec.ApplicationRef.Namespace != appToReconcile.Namespace ||
+			claim.Spec.ApplicationRef.Name != appToReconcile.Name {
+			continue
+		}
+
+		log.Info("releasing domain", "domain", claim.Spec.Domain, "path", claim.Spec.Path)
+		if err := r.Delete(ctx, claim); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
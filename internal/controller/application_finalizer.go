@@ -0,0 +1,297 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	operatorsv1alpha1 "github.com/perfectmak/k4indie/api/v1alpha1"
+	"github.com/perfectmak/k4indie/internal/controller/resolvers"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// applicationFinalizer lets the operator clean up the artifacts of an
+// application that are not garbage collected with it.
+const applicationFinalizer = "operators.k4indie.io/finalizer"
+
+// finalizePollInterval is how often the deletion of the resources the
+// finalizer waits for is checked.
+const finalizePollInterval = 5 * time.Second
+
+// reconcileFinalizer registers the finalizer of the application, and
+// finalizes the application according to its deletion policy once it is
+// being deleted. It returns a non nil result when the application is being
+// deleted, since there is nothing else to reconcile.
+func (r *ApplicationReconciler) reconcileFinalizer(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+) (*reconcile.Result, error) {
+	log := log.FromContext(ctx)
+
+	if appToReconcile.GetDeletionTimestamp() == nil {
+		if controllerutil.ContainsFinalizer(appToReconcile, applicationFinalizer) {
+			return nil, nil
+		}
+
+		log.Info("adding finalizer")
+		controllerutil.AddFinalizer(appToReconcile, applicationFinalizer)
+		if err := r.Update(ctx, appToReconcile, client.FieldOwner(fieldManager)); err != nil {
+			log.Error(err, "failed to add finalizer")
+			return nil, err
+		}
+
+		return nil, nil
+	}
+
+	if !controllerutil.ContainsFinalizer(appToReconcile, applicationFinalizer) {
+		return &reconcile.Result{}, nil
+	}
+
+	log.Info("finalizing application", "deletionPolicy", appToReconcile.Spec.DeletionPolicy)
+	finalized, err := r.finalizeApplication(ctx, appToReconcile)
+	if err != nil {
+		log.Error(err, "failed to finalize application")
+		return nil, err
+	}
+	if !finalized {
+		return &reconcile.Result{RequeueAfter: finalizePollInterval}, nil
+	}
+
+	controllerutil.RemoveFinalizer(appToReconcile, applicationFinalizer)
+	if err := r.Update(ctx, appToReconcile, client.FieldOwner(fieldManager)); err != nil {
+		log.Error(err, "failed to remove finalizer")
+		return nil, err
+	}
+
+	return &reconcile.Result{}, nil
+}
+
+// finalizeApplication applies the deletion policy of the application.
+// Owned resources are garbage collected after the application is deleted,
+// so resources to keep are orphaned and artifacts the application does not
+// own are deleted explicitly. It returns false while it waits for resources
+// to be deleted.
+func (r *ApplicationReconciler) finalizeApplication(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+) (bool, error) {
+	// Domains are released with every policy, so an orphaned application
+	// can claim them again from its new namespace.
+	if err := r.releaseDomainClaims(ctx, appToReconcile, nil); err != nil {
+		return false, err
+	}
+
+	switch appToReconcile.Spec.DeletionPolicy {
+	case operatorsv1alpha1.DeletionPolicyOrphan:
+		lists := []client.ObjectList{
+			&appsv1.DeploymentList{},
+			&appsv1.StatefulSetList{},
+			&corev1.ServiceList{},
+			&corev1.PersistentVolumeClaimList{},
+			&autoscalingv2.HorizontalPodAutoscalerList{},
+			&batchv1.JobList{},
+			&batchv1.CronJobList{},
+			&operatorsv1alpha1.ReleaseList{},
+		}
+		routeLists, err := r.newInstalledRouteLists()
+		if err != nil {
+			return false, err
+		}
+		for _, list := range routeLists {
+			lists = append(lists, list)
+		}
+		for _, list := range lists {
+			if err := r.orphanResources(ctx, appToReconcile, list); err != nil {
+				return false, err
+			}
+		}
+
+		return true, nil
+	case operatorsv1alpha1.DeletionPolicyRetainData:
+		lists := []client.ObjectList{
+			&operatorsv1alpha1.ReleaseList{},
+			&corev1.PersistentVolumeClaimList{},
+		}
+		for _, list := range lists {
+			if err := r.orphanResources(ctx, appToReconcile, list); err != nil {
+				return false, err
+			}
+		}
+
+		return r.deleteIssuedCertificates(ctx, appToReconcile)
+	}
+
+	if err := r.deleteReplicaVolumeClaims(ctx, appToReconcile); err != nil {
+		return false, err
+	}
+
+	return r.deleteIssuedCertificates(ctx, appToReconcile)
+}
+
+// deleteIssuedCertificates deletes the Secrets of the certificates issued by
+// cert-manager for the application domains, which are not owned by the
+// application. The router does not request certificates when it does not
+// terminate TLS, so there is nothing to delete then.
+// cert-manager issues the certificates again while they are requested, so
+// the routes and Certificates requesting them are deleted first, along with
+// the Certificates cert-manager created for them, and it returns false until
+// they are gone.
+func (r *ApplicationReconciler) deleteIssuedCertificates(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+) (bool, error) {
+	log := log.FromContext(ctx)
+	router, err := r.resolveRouter(ctx)
+	if err != nil {
+		return false, err
+	}
+	if !router.TerminatesTLS() {
+		return true, nil
+	}
+
+	deleted, err := r.deleteRoutes(ctx, appToReconcile)
+	if err != nil || !deleted {
+		return false, err
+	}
+
+	domains := resolvers.ResolveDomainTLS(
+		appToReconcile.Name,
+		appToReconcile.Spec.TLS,
+		resolvers.ResolveProcesses(appToReconcile),
+	)
+
+	for _, name := range resolvers.IssuedTLSSecretNames(domains) {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: appToReconcile.Namespace},
+		}
+
+		log.Info("deleting certificate secret", "secret.name", name)
+		if err := r.Delete(ctx, secret); err != nil && !apierrors.IsNotFound(err) {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// deleteRoutes deletes the routing objects controlled by the application
+// in the foreground, so the objects they own are deleted before them. It
+// returns false until they are all gone.
+func (r *ApplicationReconciler) deleteRoutes(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+) (bool, error) {
+	log := log.FromContext(ctx)
+	deleted := true
+
+	routeLists, err := r.newInstalledRouteLists()
+	if err != nil {
+		return false, err
+	}
+	for kind, list := range routeLists {
+		err = r.List(
+			ctx,
+			list,
+			client.InNamespace(appToReconcile.Namespace),
+			client.MatchingLabels{resolvers.InstanceLabel: appToReconcile.Name},
+		)
+		if err != nil {
+			return false, err
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return false, err
+		}
+
+		for _, item := range items {
+			obj, ok := item.(client.Object)
+			if !ok || !metav1.IsControlledBy(obj, appToReconcile) {
+				continue
+			}
+			deleted = false
+			if obj.GetDeletionTimestamp() != nil {
+				continue
+			}
+
+			log.Info("deleting route", "route.kind", kind, "route.name", obj.GetName())
+			err := r.Delete(ctx, obj, client.PropagationPolicy(metav1.DeletePropagationForeground))
+			if err != nil && !apierrors.IsNotFound(err) {
+				return false, err
+			}
+		}
+	}
+
+	return deleted, nil
+}
+
+// orphanResources removes the owner reference to the application from the
+// resources of the list type it controls, so they are not garbage collected
+// when the application is deleted.
+func (r *ApplicationReconciler) orphanResources(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+	list client.ObjectList,
+) error {
+	log := log.FromContext(ctx)
+
+	err := r.List(
+		ctx,
+		list,
+		client.InNamespace(appToReconcile.Namespace),
+		client.MatchingLabels{resolvers.InstanceLabel: appToReconcile.Name},
+	)
+	if err != nil {
+		return err
+	}
+
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		obj, ok := item.(client.Object)
+		if !ok || !metav1.IsControlledBy(obj, appToReconcile) {
+			continue
+		}
+
+		log.Info("orphaning resource", "resource.name", obj.GetName())
+		if err := r.orphanResource(ctx, appToReconcile, obj); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// orphanResource removes the owner reference of the application from the
+// resource, so it is not garbage collected with the application.
+func (r *ApplicationReconciler) orphanResource(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+	obj client.Object,
+) error {
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+	ownerReferences := []metav1.OwnerReference{}
+	for _, ownerReference := range obj.GetOwnerReferences() {
+		if ownerReference.UID != appToReconcile.UID {
+			ownerReferences = append(ownerReferences, ownerReference)
+		}
+	}
+	obj.SetOwnerReferences(ownerReferences)
+
+	if err := r.Patch(ctx, obj, patch); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"context"
+
+	operatorsv1alpha1 "github.com/perfectmak/k4indie/api/v1alpha1"
+	"github.com/perfectmak/k4indie/internal/controller/resolvers"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// resolveOperatorConfig returns the operator config of the cluster. The
+// defaults are used when the cluster has no K4IndieConfig.
+func (r *ApplicationReconciler) resolveOperatorConfig(ctx context.Context) (resolvers.OperatorConfig, error) {
+	config, err := GetK4IndieConfig(ctx, r.Client)
+	if err != nil {
+		return resolvers.OperatorConfig{}, err
+	}
+
+	return resolvers.ResolveOperatorConfig(config), nil
+}
+
+// resolveRouter returns the router selected by the routing of the operator
+// config, which can change at runtime. The Ingress router is used when the
+// CRDs of the selected router are not installed in the cluster.
+func (r *ApplicationReconciler) resolveRouter(ctx context.Context) (resolvers.Router, error) {
+	config, err := GetK4IndieConfig(ctx, r.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	routerConfig := resolvers.ResolveRouterConfig(r.RouterDefaults, config)
+	router, err := resolvers.NewRouter(routerConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, routeType := range router.RouteTypes() {
+		installed, err := isInstalled(r.RESTMapper(), r.Scheme, routeType)
+		if err != nil {
+			return nil, err
+		}
+		if !installed {
+			log.FromContext(ctx).Info(
+				"the CRDs of the router are not installed, using the ingress router",
+				"router", routerConfig.Kind,
+			)
+			return &resolvers.IngressRouter{IngressClassName: routerConfig.IngressClassName}, nil
+		}
+	}
+
+	return router, nil
+}
+
+// isInstalled checks if the type of the object is served by the cluster,
+// i.e. its CRD is installed.
+func isInstalled(mapper meta.RESTMapper, scheme *runtime.Scheme, obj client.Object) (bool, error) {
+	gvk, err := apiutil.GVKForObject(obj, scheme)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// GetK4IndieConfig returns the K4IndieConfig of the cluster, or nil when the
+// cluster has none or its CRD is not installed.
+func GetK4IndieConfig(ctx context.Context, reader client.Reader) (*operatorsv1alpha1.K4IndieConfig, error) {
+	config := &operatorsv1alpha1.K4IndieConfig{}
+	err := reader.Get(ctx, types.NamespacedName{Name: operatorsv1alpha1.K4IndieConfigName}, config)
+	if err != nil && (apierrors.IsNotFound(err) || meta.IsNoMatchError(err)) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// findApplicationsForOperatorConfig enqueues all the applications of the
+// cluster when the operator config changes, since it applies to all of
+// them.
+func (r *ApplicationReconciler) findApplicationsForOperatorConfig(obj client.Object) []reconcile.Request {
+	if obj.GetName() != operatorsv1alpha1.K4IndieConfigName {
+		return []reconcile.Request{}
+	}
+
+	apps := &operatorsv1alpha1.ApplicationList{}
+	if err := r.List(context.Background(), apps); err != nil {
+		log.Log.Error(err, "failed to list applications for operator config")
+		return []reconcile.Request{}
+	}
+
+	requests := make([]reconcile.Request, 0, len(apps.Items))
+	for _, app := range apps.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: app.Namespace,
+				Name:      app.Name,
+			},
+		})
+	}
+
+	return requests
+}
@@ -0,0 +1,222 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	operatorsv1alpha1 "github.com/perfectmak/k4indie/api/v1alpha1"
+	"github.com/perfectmak/k4indie/internal/controller/resolvers"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// releaseJobPollInterval is how often a running release phase is checked.
+const releaseJobPollInterval = 10 * time.Second
+
+// reconcileRelease runs the release phase of the application when it has a
+// release command and the current release has not been rolled out yet.
+// It returns a non nil result while the release Job is running or when it
+// failed, so that the deployments are only updated once the Job succeeded.
+func (r *ApplicationReconciler) reconcileRelease(
+	ctx context.Context,
+	req reconcile.Request,
+	appToReconcile *operatorsv1alpha1.Application,
+) (*reconcile.Result, error) {
+	if len(appToReconcile.Spec.Release) == 0 {
+		return nil, nil
+	}
+
+	log := log.FromContext(ctx)
+	releaseHash, err := r.resolveReleaseHash(ctx, appToReconcile)
+	if err != nil {
+		return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
+	}
+	jobName := resolvers.ReleaseJobName(appToReconcile.Name, releaseHash)
+
+	job := &batchv1.Job{}
+	err = r.Get(ctx, types.NamespacedName{Namespace: appToReconcile.Namespace, Name: jobName}, job)
+	if err != nil && apierrors.IsNotFound(err) {
+		released, err := r.isReleaseDeployed(ctx, appToReconcile, releaseHash)
+		if err != nil {
+			log.Error(err, "failed to check deployed release")
+			return nil, err
+		}
+		if released {
+			return nil, nil
+		}
+
+		job, err = r.buildReleaseJob(ctx, appToReconcile, jobName)
+		if err != nil {
+			return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
+		}
+
+		log.Info("creating release job", "job.name", job.Name)
+		if err := r.Create(ctx, job); err != nil {
+			log.Error(err, "failed to create release job")
+			return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
+		}
+
+		return &reconcile.Result{RequeueAfter: releaseJobPollInterval}, nil
+	} else if err != nil {
+		log.Error(err, "failed to get release job")
+		return nil, err
+	}
+
+	switch {
+	case isJobConditionTrue(job, batchv1.JobFailed):
+		log.Info("release job failed", "job.name", job.Name)
+
+		return r.setReleaseFailed(ctx, appToReconcile, metav1.Condition{
+			Type:   typeReleaseFailed,
+			Status: metav1.ConditionTrue,
+			Reason: "ReleaseJobFailed",
+			Message: fmt.Sprintf(
+				"Release job (%s) failed or timed out, the previous release keeps serving",
+				job.Name,
+			),
+		})
+	case isJobConditionTrue(job, batchv1.JobComplete):
+		if meta.IsStatusConditionTrue(appToReconcile.Status.Conditions, typeReleaseFailed) {
+			result, err := r.setReleaseFailed(ctx, appToReconcile, metav1.Condition{
+				Type:    typeReleaseFailed,
+				Status:  metav1.ConditionFalse,
+				Reason:  "ReleaseJobSucceeded",
+				Message: fmt.Sprintf("Release job (%s) succeeded", job.Name),
+			})
+			if err != nil {
+				return result, err
+			}
+		}
+
+		err := r.deleteStaleResources(ctx, appToReconcile, &batchv1.JobList{}, []string{job.Name})
+		if err != nil {
+			log.Error(err, "failed to delete previous release jobs")
+		}
+
+		return nil, nil
+	default:
+		log.Info("waiting for release job to complete", "job.name", job.Name)
+		return &reconcile.Result{RequeueAfter: releaseJobPollInterval}, nil
+	}
+}
+
+// resolveReleaseHash returns the hash identifying the current release of
+// the application.
+func (r *ApplicationReconciler) resolveReleaseHash(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+) (string, error) {
+	configHash, err := r.resolveConfigHash(ctx, appToReconcile)
+	if err != nil {
+		return "", err
+	}
+	envVars, err := r.resolveEnvVars(ctx, appToReconcile)
+	if err != nil {
+		return "", err
+	}
+
+	return resolvers.HashRelease(
+		appToReconcile.Spec.Runtime.Image,
+		appToReconcile.Spec.Release,
+		envVars,
+		configHash,
+	)
+}
+
+// isReleaseDeployed checks if all the process workloads are already
+// running the release, in which case the release phase is not run again
+// even if its Job was deleted.
+func (r *ApplicationReconciler) isReleaseDeployed(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+	releaseHash string,
+) (bool, error) {
+	for _, process := range resolvers.ResolveProcesses(appToReconcile) {
+		template, err := r.getProcessPodTemplate(ctx, appToReconcile, process)
+		if err != nil {
+			return false, err
+		}
+		if template == nil || template.Annotations[resolvers.ReleaseHashAnnotation] != releaseHash {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func (r *ApplicationReconciler) buildReleaseJob(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+	jobName string,
+) (*batchv1.Job, error) {
+	podTemplate, err := r.buildPodTemplate(ctx, appToReconcile, resolvers.Process{
+		Name:         resolvers.ReleaseProcessName,
+		ResourceName: jobName,
+		Command:      appToReconcile.Spec.Release,
+		Size:         appToReconcile.Spec.Runtime.Size,
+	})
+	if err != nil {
+		return nil, err
+	}
+	podTemplate.Spec.RestartPolicy = corev1.RestartPolicyNever
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      jobName,
+			Namespace: appToReconcile.Namespace,
+			Labels:    podTemplate.Labels,
+		},
+		Spec: batchv1.JobSpec{
+			// Release commands like migrations are not safe to retry blindly.
+			BackoffLimit:          &[]int32{0}[0],
+			ActiveDeadlineSeconds: &[]int64{resolvers.ReleaseDeadlineSeconds(appToReconcile)}[0],
+			Template:              podTemplate,
+		},
+	}
+
+	if err := ctrl.SetControllerReference(appToReconcile, job, r.Scheme); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func (r *ApplicationReconciler) setReleaseFailed(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+	condition metav1.Condition,
+) (*reconcile.Result, error) {
+	log := log.FromContext(ctx)
+
+	meta.SetStatusCondition(&appToReconcile.Status.Conditions, condition)
+	if err := r.updateStatus(ctx, appToReconcile); err != nil {
+		log.Error(err, "failed to update application status")
+		return nil, err
+	}
+
+	if condition.Status == metav1.ConditionTrue {
+		// Stop the reconciliation so the current deployments keep serving.
+		// A new release is attempted once the image or config changes.
+		return &reconcile.Result{}, nil
+	}
+
+	return nil, nil
+}
+
+func isJobConditionTrue(job *batchv1.Job, conditionType batchv1.JobConditionType) bool {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == conditionType && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,205 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	operatorsv1alpha1 "github.com/perfectmak/k4indie/api/v1alpha1"
+	"github.com/perfectmak/k4indie/internal/controller/resolvers"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// reconcileReleaseHistory resolves the Release the application is rolled
+// back to. When the application is rolled back, the image and config of
+// appToReconcile are replaced in memory by the ones of that Release, so the
+// rest of the reconciliation renders it.
+func (r *ApplicationReconciler) reconcileReleaseHistory(
+	ctx context.Context,
+	req reconcile.Request,
+	appToReconcile *operatorsv1alpha1.Application,
+) (*reconcile.Result, error) {
+	rollbackTo := appToReconcile.Spec.RollbackTo
+	if rollbackTo == nil {
+		return nil, nil
+	}
+
+	log := log.FromContext(ctx)
+	releases, err := r.listReleases(ctx, appToReconcile)
+	if err != nil {
+		log.Error(err, "failed to list releases")
+		return nil, err
+	}
+
+	for _, release := range releases {
+		if release.Spec.Version == *rollbackTo {
+			appToReconcile.Spec.Runtime.Image = release.Spec.Image
+			appToReconcile.Spec.Config = *release.Spec.Config.DeepCopy()
+			return nil, nil
+		}
+	}
+
+	err = fmt.Errorf("release v%d of application %s not found", *rollbackTo, appToReconcile.Name)
+	return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
+}
+
+// recordRelease records a new Release every time the image or config the
+// application runs changes, once its release phase succeeded and it is
+// rolled out, and prunes the Releases beyond the history limit.
+func (r *ApplicationReconciler) recordRelease(
+	ctx context.Context,
+	req reconcile.Request,
+	appToReconcile *operatorsv1alpha1.Application,
+) (*reconcile.Result, error) {
+	log := log.FromContext(ctx)
+
+	rollout, err := r.resolveRolloutStatus(ctx, appToReconcile)
+	if err != nil {
+		log.Error(err, "failed to resolve rollout status")
+		return nil, err
+	}
+	if !rollout.IsComplete(appToReconcile.Spec.Runtime.Image) {
+		return nil, nil
+	}
+
+	releases, err := r.listReleases(ctx, appToReconcile)
+	if err != nil {
+		log.Error(err, "failed to list releases")
+		return nil, err
+	}
+
+	var latest *operatorsv1alpha1.Release
+	for i := range releases {
+		if latest == nil || latest.Spec.Version < releases[i].Spec.Version {
+			latest = &releases[i]
+		}
+	}
+
+	image := appToReconcile.Spec.Runtime.Image
+	config := appToReconcile.Spec.Config
+	contentHash, err := r.resolveConfigHash(ctx, appToReconcile)
+	if err != nil {
+		return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
+	}
+	configHash, err := resolvers.HashReleaseConfig(config, contentHash)
+	if err != nil {
+		return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
+	}
+
+	current := latest
+	if latest == nil || latest.Spec.Image != image || latest.Spec.ConfigHash != configHash {
+		current, err = r.createRelease(ctx, appToReconcile, latest, image, config, configHash)
+		if err != nil {
+			if apierrors.IsAlreadyExists(err) {
+				return &reconcile.Result{Requeue: true}, nil
+			}
+			log.Error(err, "failed to create release")
+			return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
+		}
+		releases = append(releases, *current)
+	}
+
+	if appToReconcile.Status.CurrentRelease != current.Spec.Version {
+		appToReconcile.Status.CurrentRelease = current.Spec.Version
+		if err := r.updateStatus(ctx, appToReconcile); err != nil {
+			log.Error(err, "failed to update application status")
+			return nil, err
+		}
+	}
+
+	for _, release := range resolvers.ReleasesToPrune(appToReconcile, releases) {
+		release := release
+		log.Info("pruning release", "release.name", release.Name)
+		if err := r.Delete(ctx, &release); client.IgnoreNotFound(err) != nil {
+			log.Error(err, "failed to prune release", "release.name", release.Name)
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+// listReleases returns the Releases of the application.
+func (r *ApplicationReconciler) listReleases(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+) ([]operatorsv1alpha1.Release, error) {
+	releaseList := &operatorsv1alpha1.ReleaseList{}
+	err := r.List(
+		ctx,
+		releaseList,
+		client.InNamespace(appToReconcile.Namespace),
+		client.MatchingLabels{resolvers.InstanceLabel: appToReconcile.Name},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var releases []operatorsv1alpha1.Release
+	for _, release := range releaseList.Items {
+		if release.Spec.Application == appToReconcile.Name {
+			releases = append(releases, release)
+		}
+	}
+
+	return releases, nil
+}
+
+func (r *ApplicationReconciler) createRelease(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+	latest *operatorsv1alpha1.Release,
+	image operatorsv1alpha1.RuntimeImage,
+	config operatorsv1alpha1.ApplicationConfig,
+	configHash string,
+) (*operatorsv1alpha1.Release, error) {
+	log := log.FromContext(ctx)
+
+	version := int32(1)
+	if latest != nil {
+		version = latest.Spec.Version + 1
+	}
+
+	release := &operatorsv1alpha1.Release{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      resolvers.ReleaseName(appToReconcile.Name, version),
+			Namespace: appToReconcile.Namespace,
+			Labels: resolvers.MergeDefaultLabels(map[string]string{
+				resolvers.InstanceLabel: appToReconcile.Name,
+			}),
+		},
+		Spec: operatorsv1alpha1.ReleaseSpec{
+			Application: appToReconcile.Name,
+			Version:     version,
+			Image:       image,
+			Config:      config,
+			ConfigHash:  configHash,
+			Trigger: resolvers.ReleaseTrigger(
+				latest,
+				image,
+				configHash,
+				appToReconcile.Spec.RollbackTo,
+			),
+			TriggeredBy: resolvers.LastSpecManager(appToReconcile.ManagedFields, fieldManager),
+		},
+	}
+
+	if err := ctrl.SetControllerReference(appToReconcile, release, r.Scheme); err != nil {
+		return nil, err
+	}
+
+	log.Info(
+		"creating release",
+		"release.name", release.Name,
+		"release.trigger", release.Spec.Trigger,
+	)
+	if err := r.Create(ctx, release); err != nil {
+		return nil, err
+	}
+
+	return release, nil
+}
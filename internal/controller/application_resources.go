@@ -0,0 +1,208 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	operatorsv1alpha1 "github.com/perfectmak/k4indie/api/v1alpha1"
+	"github.com/perfectmak/k4indie/internal/controller/resolvers"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// fieldManager is the field manager of the resources applied by the operator.
+const fieldManager = "k4indie"
+
+// applyResource creates or updates the resource with server-side apply.
+// The operator only owns the fields it renders, so fields set by other
+// controllers, e.g. the cluster IP of a Service, are left untouched, and
+// fields it no longer renders are removed.
+// The labels of the operator config are added to the resource, without
+// overriding its rendered labels.
+// Resources controlled by another owner are never taken over, e.g. when the
+// process "web" of the application "foo" and the application "foo-web"
+// render resources with the same name.
+func (r *ApplicationReconciler) applyResource(ctx context.Context, obj client.Object) error {
+	if err := ensureSameController(ctx, r.Client, r.Scheme, obj); err != nil {
+		return err
+	}
+
+	return applyResource(ctx, r.Client, r.Scheme, obj)
+}
+
+// replicasHandOverFieldManager is the field manager keeping the replicas
+// of workloads the operator stops applying replicas to.
+const replicasHandOverFieldManager = "k4indie-replicas-handover"
+
+// handOverReplicas hands the replicas of a workload, previously applied by
+// the operator, over to the autoscaler. Fields the operator stops applying
+// are removed when it is their only manager, which would scale the workload
+// back to a single replica, so the current replicas are applied by another
+// field manager first. The autoscaler takes them over when it scales the
+// workload.
+func (r *ApplicationReconciler) handOverReplicas(ctx context.Context, obj client.Object, replicas *int32) error {
+	if replicas == nil || !resolvers.ManagesReplicas(obj.GetManagedFields(), fieldManager) {
+		return nil
+	}
+
+	gvk, err := apiutil.GVKForObject(obj, r.Scheme)
+	if err != nil {
+		return err
+	}
+	handOver := &unstructured.Unstructured{}
+	handOver.SetGroupVersionKind(gvk)
+	handOver.SetNamespace(obj.GetNamespace())
+	handOver.SetName(obj.GetName())
+	if err := unstructured.SetNestedField(handOver.Object, int64(*replicas), "spec", "replicas"); err != nil {
+		return err
+	}
+
+	return r.Patch(ctx, handOver, client.Apply, client.FieldOwner(replicasHandOverFieldManager))
+}
+
+// ensureSameController checks that the resource either does not exist yet,
+// or is controlled by the owner it is rendered with.
+func ensureSameController(ctx context.Context, c client.Reader, scheme *runtime.Scheme, obj client.Object) error {
+	controller := metav1.GetControllerOf(obj)
+	if controller == nil {
+		return nil
+	}
+
+	existing := obj.DeepCopyObject().(client.Object)
+	err := c.Get(ctx, types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}, existing)
+	if err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	existingController := metav1.GetControllerOf(existing)
+	if existingController == nil || existingController.UID == controller.UID {
+		return nil
+	}
+
+	gvk, err := apiutil.GVKForObject(obj, scheme)
+	if err != nil {
+		return err
+	}
+
+	return fmt.Errorf(
+		"%s %s already exists and is controlled by %s %s",
+		gvk.Kind, obj.GetName(), existingController.Kind, existingController.Name,
+	)
+}
+
+// applyResource creates or updates the resource with server-side apply,
+// for the controllers of all the kinds of the operator.
+func applyResource(ctx context.Context, c client.Client, scheme *runtime.Scheme, obj client.Object) error {
+	gvk, err := apiutil.GVKForObject(obj, scheme)
+	if err != nil {
+		return err
+	}
+	config, err := GetK4IndieConfig(ctx, c)
+	if err != nil {
+		return err
+	}
+	operatorConfig := resolvers.ResolveOperatorConfig(config)
+	obj.SetLabels(resolvers.MergeDefaultLabels(operatorConfig.Labels, obj.GetLabels()))
+	obj.GetObjectKind().SetGroupVersionKind(gvk)
+	obj.SetResourceVersion("")
+	obj.SetManagedFields(nil)
+
+	return c.Patch(ctx, obj, client.Apply, client.FieldOwner(fieldManager), client.ForceOwnership)
+}
+
+// deleteStaleResources deletes the resources of the list type that are
+// controlled by the application but whose name is not in resourceNames.
+// This cleans up resources generated for processes that were removed.
+func (r *ApplicationReconciler) deleteStaleResources(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+	list client.ObjectList,
+	resourceNames []string,
+) error {
+	log := log.FromContext(ctx)
+
+	err := r.List(
+		ctx,
+		list,
+		client.InNamespace(appToReconcile.Namespace),
+		client.MatchingLabels{resolvers.InstanceLabel: appToReconcile.Name},
+	)
+	if err != nil {
+		return err
+	}
+
+	keep := map[string]struct{}{}
+	for _, name := range resourceNames {
+		keep[name] = struct{}{}
+	}
+
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		obj, ok := item.(client.Object)
+		if !ok {
+			continue
+		}
+		if _, exists := keep[obj.GetName()]; exists {
+			continue
+		}
+		if !metav1.IsControlledBy(obj, appToReconcile) {
+			continue
+		}
+
+		log.Info("deleting stale resource", "resource.name", obj.GetName())
+		err := r.Delete(ctx, obj, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setRenderedHash annotates a rendered resource with the hash of its labels,
+// annotations and spec, so later changes made outside of the operator can be
+// told apart from changes of the application.
+func setRenderedHash(obj metav1.Object, spec interface{}) error {
+	hash, err := resolvers.HashRendered(obj.GetLabels(), obj.GetAnnotations(), spec)
+	if err != nil {
+		return err
+	}
+
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[resolvers.RenderedHashAnnotation] = hash
+	obj.SetAnnotations(annotations)
+
+	return nil
+}
+
+// recordDriftCorrected emits an event on the application when a resource
+// modified outside of the operator was reverted to its rendered state.
+func (r *ApplicationReconciler) recordDriftCorrected(
+	appToReconcile *operatorsv1alpha1.Application,
+	kind string,
+	name string,
+) {
+	r.Recorder.Eventf(
+		appToReconcile,
+		corev1.EventTypeNormal,
+		"DriftCorrected",
+		"Reverted changes made outside of the operator to %s %s",
+		kind,
+		name,
+	)
+}
@@ -0,0 +1,197 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	operatorsv1alpha1 "github.com/perfectmak/k4indie/api/v1alpha1"
+	"github.com/perfectmak/k4indie/internal/controller/resolvers"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// reconcileRoutes applies the routing objects built by the router for the
+// domains of the application, and deletes the routing objects it no longer
+// builds.
+func (r *ApplicationReconciler) reconcileRoutes(
+	ctx context.Context,
+	req ctrl.Request,
+	appToReconcile *operatorsv1alpha1.Application,
+) (*reconcile.Result, error) {
+	log := log.FromContext(ctx)
+	processes := resolvers.ResolveProcesses(appToReconcile)
+
+	router, err := r.resolveRouter(ctx)
+	if err != nil {
+		log.Error(err, "failed to resolve router")
+		return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
+	}
+
+	routes, err := router.BuildRoutes(appToReconcile, processes)
+	if err != nil {
+		log.Error(err, "failed to build routes")
+		return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
+	}
+
+	routeNames := map[string][]string{}
+	for _, route := range routes {
+		gvk, err := apiutil.GVKForObject(route, r.Scheme)
+		if err != nil {
+			return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
+		}
+		routeNames[gvk.Kind] = append(routeNames[gvk.Kind], route.GetName())
+
+		if err := r.applyRoute(ctx, appToReconcile, route, gvk.Kind); err != nil {
+			log.Error(err, "failed to apply route", "kind", gvk.Kind, "route.name", route.GetName())
+			return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
+		}
+	}
+
+	// The routing objects of every router are checked, since the router
+	// may have changed since they were created.
+	routeLists, err := r.newInstalledRouteLists()
+	if err != nil {
+		return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
+	}
+	for kind, list := range routeLists {
+		if err := r.deleteStaleResources(ctx, appToReconcile, list, routeNames[kind]); err != nil {
+			log.Error(err, "failed to delete stale routes", "kind", kind)
+			return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
+		}
+	}
+
+	return nil, nil
+}
+
+// applyRoute creates or updates a routing object of the application.
+func (r *ApplicationReconciler) applyRoute(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+	route client.Object,
+	kind string,
+) error {
+	spec, err := routeSpec(route)
+	if err != nil {
+		return err
+	}
+	if err := setRenderedHash(route, spec); err != nil {
+		return err
+	}
+	if err := ctrl.SetControllerReference(appToReconcile, route, r.Scheme); err != nil {
+		return err
+	}
+
+	existing, err := r.newRouteObject(route, kind)
+	if err != nil {
+		return err
+	}
+	err = r.Get(ctx, client.ObjectKeyFromObject(route), existing)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	drifted := false
+	if err == nil {
+		existingSpec, err := routeSpec(existing)
+		if err != nil {
+			return err
+		}
+		drifted = resolvers.IsDrifted(route, existing, spec, existingSpec)
+	}
+
+	if err := r.applyResource(ctx, route); err != nil {
+		return err
+	}
+	if drifted {
+		r.recordDriftCorrected(appToReconcile, kind, route.GetName())
+	}
+
+	return nil
+}
+
+// newRouteObject returns an empty object of the same type as the routing
+// object.
+func (r *ApplicationReconciler) newRouteObject(route client.Object, kind string) (client.Object, error) {
+	if _, isUnstructured := route.(*unstructured.Unstructured); isUnstructured {
+		existing := &unstructured.Unstructured{}
+		existing.GetObjectKind().SetGroupVersionKind(route.GetObjectKind().GroupVersionKind())
+		return existing, nil
+	}
+
+	existing, ok := reflect.New(reflect.TypeOf(route).Elem()).Interface().(client.Object)
+	if !ok {
+		return nil, fmt.Errorf("%s %s is not a client object", kind, route.GetName())
+	}
+
+	return existing, nil
+}
+
+// newRouteList returns an empty list of the routing object type, along with
+// the kind of the type.
+func (r *ApplicationReconciler) newRouteList(routeType client.Object) (client.ObjectList, string, error) {
+	gvk, err := apiutil.GVKForObject(routeType, r.Scheme)
+	if err != nil {
+		return nil, "", err
+	}
+	listGVK := gvk.GroupVersion().WithKind(gvk.Kind + "List")
+
+	if _, isUnstructured := routeType.(*unstructured.Unstructured); isUnstructured {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(listGVK)
+		return list, gvk.Kind, nil
+	}
+
+	obj, err := r.Scheme.New(listGVK)
+	if err != nil {
+		return nil, "", err
+	}
+	list, ok := obj.(client.ObjectList)
+	if !ok {
+		return nil, "", fmt.Errorf("%s is not a list", listGVK)
+	}
+
+	return list, gvk.Kind, nil
+}
+
+// newInstalledRouteLists returns an empty list of each type of routing
+// object built by any of the routers, by kind. Types whose CRD is not
+// installed in the cluster are skipped, since there can't be any objects of
+// those types.
+func (r *ApplicationReconciler) newInstalledRouteLists() (map[string]client.ObjectList, error) {
+	lists := map[string]client.ObjectList{}
+
+	for _, routeType := range resolvers.RouteTypes() {
+		installed, err := isInstalled(r.RESTMapper(), r.Scheme, routeType)
+		if err != nil {
+			return nil, err
+		}
+		if !installed {
+			continue
+		}
+
+		list, kind, err := r.newRouteList(routeType)
+		if err != nil {
+			return nil, err
+		}
+		lists[kind] = list
+	}
+
+	return lists, nil
+}
+
+// routeSpec returns the spec of a routing object, regardless of whether it
+// is typed or unstructured.
+func routeSpec(route client.Object) (interface{}, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(route)
+	if err != nil {
+		return nil, err
+	}
+
+	return content["spec"], nil
+}
@@ -0,0 +1,159 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	operatorsv1alpha1 "github.com/perfectmak/k4indie/api/v1alpha1"
+	"github.com/perfectmak/k4indie/internal/controller/resolvers"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const runtimeSizesIndexKey = ".spec.runtime.sizes"
+
+// defaultRuntimeSizeIndexValue indexes the applications running on the
+// default size of the operator config.
+const defaultRuntimeSizeIndexValue = "<default>"
+
+// getRuntimeSize returns the RuntimeSize a process runs on. Processes
+// without a size run on the default size of the operator config.
+func (r *ApplicationReconciler) getRuntimeSize(
+	ctx context.Context,
+	name operatorsv1alpha1.RuntimeSizeName,
+) (*operatorsv1alpha1.RuntimeSize, error) {
+	if name == "" {
+		operatorConfig, err := r.resolveOperatorConfig(ctx)
+		if err != nil {
+			return nil, err
+		}
+		name = operatorConfig.DefaultSize
+	}
+
+	return getRuntimeSize(ctx, r.Client, name)
+}
+
+// getRuntimeSize returns the RuntimeSize with the given name, for the
+// controllers of all the kinds of the operator.
+func getRuntimeSize(
+	ctx context.Context,
+	reader client.Reader,
+	name operatorsv1alpha1.RuntimeSizeName,
+) (*operatorsv1alpha1.RuntimeSize, error) {
+	size := &operatorsv1alpha1.RuntimeSize{}
+	err := reader.Get(ctx, types.NamespacedName{Name: string(name)}, size)
+	if err != nil && apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("%w: runtime size %q not found", operatorsv1alpha1.ErrInvalidRuntimeSize, name)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return size, nil
+}
+
+// resolveResources returns the resources of the containers running on the
+// size, with the resource overrides of the application applied. Overrides
+// are checked against the LimitRanges of the namespace, so they fail on the
+// application status instead of when the pods are created.
+func (r *ApplicationReconciler) resolveResources(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+	size *operatorsv1alpha1.RuntimeSize,
+) (corev1.ResourceRequirements, error) {
+	resources := resolvers.ApplyResourceOverrides(
+		resolvers.GetResourcesForRuntimeSize(size),
+		appToReconcile.Spec.Runtime.Resources,
+	)
+	if appToReconcile.Spec.Runtime.Resources == nil {
+		return resources, nil
+	}
+
+	limitRanges := &corev1.LimitRangeList{}
+	if err := r.List(ctx, limitRanges, client.InNamespace(appToReconcile.Namespace)); err != nil {
+		return corev1.ResourceRequirements{}, err
+	}
+	if err := resolvers.ValidateLimitRanges(resources, limitRanges.Items); err != nil {
+		return corev1.ResourceRequirements{}, err
+	}
+
+	return resources, nil
+}
+
+// indexRuntimeSizes indexes the applications by the sizes their processes
+// and scheduled tasks run on, so changes to a RuntimeSize roll out the applications using it.
+func indexRuntimeSizes(obj client.Object) []string {
+	app := obj.(*operatorsv1alpha1.Application)
+
+	names := []string{}
+	seen := map[operatorsv1alpha1.RuntimeSizeName]struct{}{}
+	sizes := []operatorsv1alpha1.RuntimeSizeName{}
+	for _, process := range resolvers.ResolveProcesses(app) {
+		sizes = append(sizes, process.Size)
+	}
+	for _, schedule := range resolvers.ResolveSchedules(app) {
+		sizes = append(sizes, schedule.Size)
+	}
+
+	for _, size := range sizes {
+		if _, exists := seen[size]; exists {
+			continue
+		}
+		seen[size] = struct{}{}
+
+		if size == "" {
+			names = append(names, defaultRuntimeSizeIndexValue)
+		} else {
+			names = append(names, string(size))
+		}
+	}
+
+	return names
+}
+
+// findApplicationsForRuntimeSize enqueues the applications running on a
+// RuntimeSize, including the applications without a size when it is the
+// default size.
+func (r *ApplicationReconciler) findApplicationsForRuntimeSize(obj client.Object) []reconcile.Request {
+	requests := r.findApplicationsForConfig(runtimeSizesIndexKey)(obj)
+
+	operatorConfig, err := r.resolveOperatorConfig(context.Background())
+	if err != nil {
+		log.Log.Error(err, "failed to resolve operator config for runtime size", "size.name", obj.GetName())
+		return requests
+	}
+	if string(operatorConfig.DefaultSize) != obj.GetName() {
+		return requests
+	}
+
+	defaultSized := &operatorsv1alpha1.RuntimeSize{}
+	defaultSized.SetName(defaultRuntimeSizeIndexValue)
+
+	return append(requests, r.findApplicationsForConfig(runtimeSizesIndexKey)(defaultSized)...)
+}
+
+// findApplicationsForLimitRange enqueues the applications of the namespace
+// of a LimitRange that override the resources of their size, since their
+// overrides are checked against it.
+func (r *ApplicationReconciler) findApplicationsForLimitRange(obj client.Object) []reconcile.Request {
+	apps := &operatorsv1alpha1.ApplicationList{}
+	if err := r.List(context.Background(), apps, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.Log.Error(err, "failed to list applications for limit range", "limitRange.name", obj.GetName())
+		return []reconcile.Request{}
+	}
+
+	requests := []reconcile.Request{}
+	for _, app := range apps.Items {
+		if app.Spec.Runtime.Resources == nil {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: app.Namespace, Name: app.Name},
+		})
+	}
+
+	return requests
+}
@@ -0,0 +1,228 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	operatorsv1alpha1 "github.com/perfectmak/k4indie/api/v1alpha1"
+	"github.com/perfectmak/k4indie/internal/controller/resolvers"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// reconcileSchedules attempts to create a CronJob for each scheduled task
+// of the application if it does not exist. And if it does, it tries to
+// update the CronJob to match the application spec. CronJobs of removed
+// tasks are deleted. The last runs of the tasks are reported on the
+// application status.
+func (r *ApplicationReconciler) reconcileSchedules(
+	ctx context.Context,
+	req reconcile.Request,
+	appToReconcile *operatorsv1alpha1.Application,
+) (*reconcile.Result, error) {
+	log := log.FromContext(ctx)
+	schedules := resolvers.ResolveSchedules(appToReconcile)
+
+	statuses := make([]operatorsv1alpha1.ScheduleStatus, 0, len(schedules))
+	for _, schedule := range schedules {
+		cronJob, err := r.reconcileScheduleCronJob(ctx, appToReconcile, schedule)
+		if err != nil {
+			log.Error(err, "failed to reconcile cronjob", "schedule", schedule.Name)
+			return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
+		}
+
+		jobs := &batchv1.JobList{}
+		err = r.List(
+			ctx,
+			jobs,
+			client.InNamespace(appToReconcile.Namespace),
+			client.MatchingLabels{
+				resolvers.InstanceLabel: appToReconcile.Name,
+				resolvers.ScheduleLabel: schedule.Name,
+			},
+		)
+		if err != nil {
+			log.Error(err, "failed to list scheduled jobs", "schedule", schedule.Name)
+			return nil, err
+		}
+
+		statuses = append(statuses, resolvers.ResolveScheduleStatus(schedule.Name, cronJob, jobs.Items))
+	}
+
+	resourceNames := make([]string, 0, len(schedules))
+	for _, schedule := range schedules {
+		resourceNames = append(resourceNames, schedule.ResourceName)
+	}
+	err := r.deleteStaleResources(ctx, appToReconcile, &batchv1.CronJobList{}, resourceNames)
+	if err != nil {
+		log.Error(err, "failed to delete stale cronjobs")
+		return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
+	}
+
+	if err := r.setSchedulesStatus(ctx, appToReconcile, statuses); err != nil {
+		log.Error(err, "failed to update application status")
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// reconcileScheduleCronJob creates or updates the CronJob of a scheduled
+// task, and returns it with its status.
+func (r *ApplicationReconciler) reconcileScheduleCronJob(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+	schedule resolvers.Schedule,
+) (*batchv1.CronJob, error) {
+	log := log.FromContext(ctx).WithValues("schedule", schedule.Name)
+
+	cronJob := &batchv1.CronJob{}
+	err := r.Get(
+		ctx,
+		types.NamespacedName{Namespace: appToReconcile.Namespace, Name: schedule.ResourceName},
+		cronJob,
+	)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	exists := err == nil
+
+	newCronJob, err := r.buildCronJob(ctx, appToReconcile, schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	drifted := exists && resolvers.IsDrifted(newCronJob, cronJob, newCronJob.Spec, cronJob.Spec)
+	if !exists {
+		log.Info("creating cronjob", "cronjob.name", newCronJob.Name)
+	}
+	if err := r.applyResource(ctx, newCronJob); err != nil {
+		return nil, err
+	}
+	if drifted {
+		r.recordDriftCorrected(appToReconcile, "CronJob", newCronJob.Name)
+	}
+
+	return newCronJob, nil
+}
+
+func (r *ApplicationReconciler) buildCronJob(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+	schedule resolvers.Schedule,
+) (*batchv1.CronJob, error) {
+	podTemplate, err := r.buildPodTemplate(ctx, appToReconcile, resolvers.Process{
+		Name:         schedule.Name,
+		ResourceName: schedule.ResourceName,
+		Command:      schedule.Command,
+		Size:         schedule.Size,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Scheduled pods are labeled as a schedule instead of a process, so a
+	// process with the same name never selects them.
+	delete(podTemplate.Labels, resolvers.ProcessLabel)
+	podTemplate.Labels = resolvers.MergeDefaultLabels(
+		podTemplate.Labels,
+		resolvers.ScheduleLabels(appToReconcile.Name, schedule.Name),
+	)
+	podTemplate.Spec.RestartPolicy = corev1.RestartPolicyNever
+
+	cronJob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      schedule.ResourceName,
+			Namespace: appToReconcile.Namespace,
+			Labels:    podTemplate.Labels,
+		},
+		Spec: batchv1.CronJobSpec{
+			Schedule:          schedule.Schedule,
+			TimeZone:          schedule.TimeZone,
+			ConcurrencyPolicy: schedule.ConcurrencyPolicy,
+			JobTemplate: batchv1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: podTemplate.Labels,
+				},
+				Spec: batchv1.JobSpec{
+					Template: podTemplate,
+				},
+			},
+		},
+	}
+
+	if err := setRenderedHash(cronJob, cronJob.Spec); err != nil {
+		return nil, err
+	}
+
+	if err := ctrl.SetControllerReference(appToReconcile, cronJob, r.Scheme); err != nil {
+		return nil, err
+	}
+
+	return cronJob, nil
+}
+
+// setSchedulesStatus reports the status of the scheduled tasks, along with
+// the ScheduleFailed condition when the last run of a task failed.
+func (r *ApplicationReconciler) setSchedulesStatus(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+	statuses []operatorsv1alpha1.ScheduleStatus,
+) error {
+	failures := []string{}
+	for _, status := range statuses {
+		if resolvers.IsScheduleFailing(status) {
+			failures = append(failures, fmt.Sprintf("%s: %s", status.Name, status.LastFailureMessage))
+		}
+	}
+
+	var condition *metav1.Condition
+	switch {
+	case len(failures) > 0:
+		condition = &metav1.Condition{
+			Type:    typeScheduleFailed,
+			Status:  metav1.ConditionTrue,
+			Reason:  "ScheduledJobFailed",
+			Message: fmt.Sprintf("Last run of scheduled tasks failed: %s", strings.Join(failures, ", ")),
+		}
+	case len(statuses) > 0:
+		condition = &metav1.Condition{
+			Type:    typeScheduleFailed,
+			Status:  metav1.ConditionFalse,
+			Reason:  "ScheduledJobsSucceeded",
+			Message: fmt.Sprintf("Last run of %d scheduled tasks did not fail", len(statuses)),
+		}
+	}
+
+	if len(statuses) == 0 {
+		statuses = nil
+	}
+	changed := !reflect.DeepEqual(appToReconcile.Status.Schedules, statuses)
+	appToReconcile.Status.Schedules = statuses
+
+	existing := meta.FindStatusCondition(appToReconcile.Status.Conditions, typeScheduleFailed)
+	if condition == nil {
+		if existing != nil {
+			meta.RemoveStatusCondition(&appToReconcile.Status.Conditions, typeScheduleFailed)
+			changed = true
+		}
+	} else if existing == nil || existing.Status != condition.Status || existing.Message != condition.Message {
+		meta.SetStatusCondition(&appToReconcile.Status.Conditions, *condition)
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+
+	return r.updateStatus(ctx, appToReconcile)
+}
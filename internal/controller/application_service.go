@@ -8,64 +8,95 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
-// reconcileService attempts to create a service for the application
-// if it does not exist. And if it does, it tries to update the service
-// schema to match the application spec.
-// If the application does not have any endpoints defined, then
-// the service will not be created.
+// reconcileService attempts to create a service for each process of the
+// application if it does not exist. And if it does, it tries to update the
+// service schema to match the application spec.
+// Only processes with endpoints defined get a service, services of other
+// processes are deleted. The processes of stateful applications also get a
+// headless service.
 func (r *ApplicationReconciler) reconcileService(
 	ctx context.Context,
 	req reconcile.Request,
 	appToReconcile *operatorsv1alpha1.Application,
 ) (*reconcile.Result, error) {
 	log := log.FromContext(ctx)
+	processes := resolvers.ProcessesWithEndpoints(resolvers.ResolveProcesses(appToReconcile))
 
-	service := &corev1.Service{}
-	err := r.Get(ctx, req.NamespacedName, service)
+	for _, process := range processes {
+		result, err := r.reconcileProcessService(ctx, req, appToReconcile, process)
+		if err != nil || result != nil {
+			return result, err
+		}
+	}
 
-	noEndpointsDefined := len(appToReconcile.Spec.Endpoints) == 0
+	resourceNames := make([]string, 0, len(processes))
+	for _, process := range processes {
+		resourceNames = append(resourceNames, process.ResourceName)
+	}
 
-	if err != nil && apierrors.IsNotFound(err) {
-		if noEndpointsDefined {
-			return nil, nil
+	// Every process of a stateful application gets a headless service, even
+	// without endpoints, so its replicas can be resolved by name.
+	if resolvers.IsStateful(appToReconcile) {
+		for _, process := range resolvers.ResolveProcesses(appToReconcile) {
+			if err := r.reconcileHeadlessService(ctx, appToReconcile, process); err != nil {
+				log.Error(err, "failed to reconcile headless service", "process", process.Name)
+				return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
+			}
+			resourceNames = append(resourceNames, resolvers.HeadlessServiceName(process.ResourceName))
 		}
+	}
+
+	err := r.deleteStaleResources(ctx, appToReconcile, &corev1.ServiceList{}, resourceNames)
+	if err != nil {
+		log.Error(err, "failed to delete stale services")
+		return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
+	}
+
+	return nil, nil
+}
 
-		service, err = r.createService(ctx, appToReconcile)
+func (r *ApplicationReconciler) reconcileProcessService(
+	ctx context.Context,
+	req reconcile.Request,
+	appToReconcile *operatorsv1alpha1.Application,
+	process resolvers.Process,
+) (*reconcile.Result, error) {
+	log := log.FromContext(ctx).WithValues("process", process.Name)
+
+	service := &corev1.Service{}
+	err := r.Get(
+		ctx,
+		types.NamespacedName{Namespace: appToReconcile.Namespace, Name: process.ResourceName},
+		service,
+	)
+
+	if err != nil && apierrors.IsNotFound(err) {
+		_, err = r.createService(ctx, appToReconcile, process)
 		if err != nil {
 			log.Error(err, "failed to create service")
 			return nil, err
 		}
 
-		// Re-enqueue the request to check the status of the service
-		return &reconcile.Result{}, nil
-
+		return nil, nil
 	} else if err != nil {
 		log.Error(err, "failed to get existing service")
 		return nil, err
 	}
 
-	if noEndpointsDefined {
-		log.Info("deleting service")
-		err := r.Delete(ctx, service)
-		if err != nil {
-			return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
-		}
-
-		return nil, nil
-	}
-
 	log.Info("updating service")
-	err = r.updateServiceSpec(ctx, appToReconcile, service)
+	newService, err := r.buildService(ctx, appToReconcile, process)
 	if err != nil {
 		return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
 	}
 
-	if err := r.Update(ctx, service); err != nil {
+	drifted := resolvers.IsDrifted(newService, service, newService.Spec, service.Spec)
+	if err := r.applyResource(ctx, newService); err != nil {
 		log.Error(err, "failed to update service")
 
 		return r.setApplicationReconcileError(
@@ -74,6 +105,9 @@ func (r *ApplicationReconciler) reconcileService(
 			err,
 		)
 	}
+	if drifted {
+		r.recordDriftCorrected(appToReconcile, "Service", service.Name)
+	}
 
 	return nil, nil
 }
@@ -81,10 +115,11 @@ func (r *ApplicationReconciler) reconcileService(
 func (r *ApplicationReconciler) createService(
 	ctx context.Context,
 	appToReconcile *operatorsv1alpha1.Application,
+	process resolvers.Process,
 ) (*corev1.Service, error) {
 	log := log.FromContext(ctx)
 
-	service, err := r.buildService(ctx, appToReconcile)
+	service, err := r.buildService(ctx, appToReconcile, process)
 	if err != nil {
 		return nil, err
 	}
@@ -92,10 +127,10 @@ func (r *ApplicationReconciler) createService(
 	log.Info(
 		"creating service",
 		"service.name", service.Name,
-		"serfvice.namespace", service.Namespace,
+		"service.namespace", service.Namespace,
 	)
 
-	if err := r.Create(ctx, service); err != nil {
+	if err := r.applyResource(ctx, service); err != nil {
 		return nil, err
 	}
 
@@ -105,44 +140,34 @@ func (r *ApplicationReconciler) createService(
 func (r *ApplicationReconciler) buildService(
 	ctx context.Context,
 	appToReconcile *operatorsv1alpha1.Application,
+	process resolvers.Process,
 ) (*corev1.Service, error) {
+	selectorLabels := resolvers.SelectorLabels(appToReconcile.Name, process.Name)
 	labels := resolvers.MergeDefaultLabels(
 		appToReconcile.Labels,
-		map[string]string{
-			"app.kubernetes.io/instance": appToReconcile.Name,
-		})
+		selectorLabels,
+	)
 
 	service := &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      appToReconcile.Name,
+			Name:      process.ResourceName,
 			Namespace: appToReconcile.Namespace,
 			Labels:    labels,
 		},
 		Spec: corev1.ServiceSpec{
-			Selector: labels,
+			Selector: selectorLabels,
 			Type:     corev1.ServiceTypeClusterIP,
-			Ports:    appToReconcile.Spec.Endpoints.AsServicePorts(),
+			Ports:    process.Endpoints.AsServicePorts(),
 		},
 	}
 
-	if err := ctrl.SetControllerReference(appToReconcile, service, r.Scheme); err != nil {
+	if err := setRenderedHash(service, service.Spec); err != nil {
 		return nil, err
 	}
 
-	return service, nil
-}
-
-func (r *ApplicationReconciler) updateServiceSpec(
-	ctx context.Context,
-	appToReconcile *operatorsv1alpha1.Application,
-	service *corev1.Service,
-) error {
-	newService, err := r.buildService(ctx, appToReconcile)
-	if err != nil {
-		return err
+	if err := ctrl.SetControllerReference(appToReconcile, service, r.Scheme); err != nil {
+		return nil, err
 	}
 
-	newService.DeepCopyInto(service)
-
-	return nil
+	return service, nil
 }
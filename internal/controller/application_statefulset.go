@@ -0,0 +1,308 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	operatorsv1alpha1 "github.com/perfectmak/k4indie/api/v1alpha1"
+	"github.com/perfectmak/k4indie/internal/controller/resolvers"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// reconcileProcessStatefulSet creates or updates the statefulset of a
+// process of a stateful application. StatefulSets whose immutable fields,
+// such as their volume claim templates, no longer match the application
+// spec are recreated.
+func (r *ApplicationReconciler) reconcileProcessStatefulSet(
+	ctx context.Context,
+	req reconcile.Request,
+	appToReconcile *operatorsv1alpha1.Application,
+	process resolvers.Process,
+) (*reconcile.Result, error) {
+	log := log.FromContext(ctx).WithValues("process", process.Name)
+
+	statefulSet := &appsv1.StatefulSet{}
+	err := r.Get(
+		ctx,
+		types.NamespacedName{Namespace: appToReconcile.Namespace, Name: process.ResourceName},
+		statefulSet,
+	)
+	if err != nil && !apierrors.IsNotFound(err) {
+		log.Error(err, "failed to get statefulset")
+		return nil, err
+	}
+	exists := err == nil
+
+	newStatefulSet, err := r.buildStatefulSet(ctx, appToReconcile, process)
+	if err != nil {
+		return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
+	}
+
+	if !exists {
+		log.Info(
+			"creating statefulset",
+			"statefulset.name", newStatefulSet.Name,
+			"statefulset.namespace", newStatefulSet.Namespace,
+		)
+		if err := r.applyResource(ctx, newStatefulSet); err != nil {
+			log.Error(err, "failed to create statefulset")
+			return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
+		}
+
+		// Re-enqueue the request to check the status of the statefulset
+		return &reconcile.Result{RequeueAfter: time.Minute}, nil
+	}
+
+	if err := r.expandReplicaVolumeClaims(ctx, appToReconcile, process); err != nil {
+		log.Error(err, "failed to expand replica volume claims")
+		return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
+	}
+
+	if process.Autoscale != nil {
+		if err := r.handOverReplicas(ctx, statefulSet, statefulSet.Spec.Replicas); err != nil {
+			log.Error(err, "failed to hand over statefulset replicas")
+			return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
+		}
+	}
+
+	if resolvers.IsStatefulSetRecreateRequired(newStatefulSet, statefulSet) {
+		// Pods are orphaned so they keep running until the new statefulset
+		// adopts and replaces them, unless the new statefulset doesn't
+		// select them anymore. The claims of the replicas are kept either
+		// way, so existing replicas don't get the new claim templates, but
+		// their claims were expanded to the new sizes above.
+		propagation := metav1.DeletePropagationOrphan
+		if !equality.Semantic.DeepEqual(newStatefulSet.Spec.Selector, statefulSet.Spec.Selector) {
+			propagation = metav1.DeletePropagationBackground
+		}
+
+		log.Info("recreating statefulset with new immutable fields", "propagation", propagation)
+		err := r.Delete(ctx, statefulSet, client.PropagationPolicy(propagation))
+		if err != nil && !apierrors.IsNotFound(err) {
+			return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
+		}
+
+		return &reconcile.Result{Requeue: true}, nil
+	}
+
+	drifted := resolvers.IsDrifted(newStatefulSet, statefulSet, newStatefulSet.Spec, statefulSet.Spec)
+	if err := r.applyResource(ctx, newStatefulSet); err != nil {
+		log.Error(err, "failed to update statefulset")
+		return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
+	}
+	if drifted {
+		r.recordDriftCorrected(appToReconcile, "StatefulSet", statefulSet.Name)
+	}
+
+	return nil, nil
+}
+
+// expandReplicaVolumeClaims grows the claims of the replicas of a stateful
+// process to the size of their volume. The claim templates of a
+// StatefulSet only apply to the claims of new replicas.
+func (r *ApplicationReconciler) expandReplicaVolumeClaims(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+	process resolvers.Process,
+) error {
+	log := log.FromContext(ctx)
+
+	claims := &corev1.PersistentVolumeClaimList{}
+	err := r.List(
+		ctx,
+		claims,
+		client.InNamespace(appToReconcile.Namespace),
+		client.MatchingLabels(resolvers.SelectorLabels(appToReconcile.Name, process.Name)),
+	)
+	if err != nil {
+		return err
+	}
+
+	for i := range claims.Items {
+		claim := &claims.Items[i]
+		volume, found := resolvers.ReplicaVolumeClaimVolume(claim.Name, process.ResourceName, appToReconcile.Spec.Volumes)
+		if !found {
+			continue
+		}
+		size := claim.Spec.Resources.Requests[corev1.ResourceStorage]
+		if size.Cmp(volume.Size) >= 0 {
+			continue
+		}
+
+		patch := client.MergeFrom(claim.DeepCopy())
+		if claim.Spec.Resources.Requests == nil {
+			claim.Spec.Resources.Requests = corev1.ResourceList{}
+		}
+		claim.Spec.Resources.Requests[corev1.ResourceStorage] = volume.Size.DeepCopy()
+
+		log.Info("expanding replica volume claim", "claim.name", claim.Name, "size", volume.Size.String())
+		if err := r.Patch(ctx, claim, patch); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getProcessStatefulSets returns the existing statefulsets of the processes
+// of the application.
+func (r *ApplicationReconciler) getProcessStatefulSets(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+) ([]appsv1.StatefulSet, error) {
+	statefulSets := []appsv1.StatefulSet{}
+
+	for _, process := range resolvers.ResolveProcesses(appToReconcile) {
+		statefulSet := &appsv1.StatefulSet{}
+		err := r.Get(
+			ctx,
+			types.NamespacedName{Namespace: appToReconcile.Namespace, Name: process.ResourceName},
+			statefulSet,
+		)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		statefulSets = append(statefulSets, *statefulSet)
+	}
+
+	return statefulSets, nil
+}
+
+func (r *ApplicationReconciler) buildStatefulSet(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+	process resolvers.Process,
+) (*appsv1.StatefulSet, error) {
+	podTemplate, err := r.buildProcessPodTemplate(ctx, appToReconcile, process)
+	if err != nil {
+		return nil, err
+	}
+	// The volumes of the pods come from the claim templates, each replica
+	// mounting its own claims.
+	_, mounts := resolvers.BuildVolumes(appToReconcile.Name, appToReconcile.Spec.Volumes)
+	podTemplate.Spec.Containers[0].VolumeMounts = mounts
+
+	selectorLabels := resolvers.SelectorLabels(appToReconcile.Name, process.Name)
+
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      process.ResourceName,
+			Namespace: appToReconcile.Namespace,
+			Labels:    podTemplate.Labels,
+		},
+		Spec: appsv1.StatefulSetSpec{
+			// Autoscaled statefulsets start with a single replica, until the
+			// autoscaler scales them to its minimum.
+			Replicas: resolvers.ProcessReplicas(process),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: selectorLabels,
+			},
+			Template:    podTemplate,
+			ServiceName: resolvers.HeadlessServiceName(process.ResourceName),
+			// Replicas find their peers through the headless service, so
+			// they don't need to be started one after the other.
+			PodManagementPolicy: appsv1.ParallelPodManagement,
+			// Claim templates are immutable, so they are only labeled with
+			// labels that never change for the process.
+			VolumeClaimTemplates: resolvers.BuildVolumeClaimTemplates(appToReconcile.Spec.Volumes, selectorLabels),
+		},
+	}
+
+	if err := setRenderedHash(statefulSet, statefulSet.Spec); err != nil {
+		return nil, err
+	}
+
+	if err := ctrl.SetControllerReference(appToReconcile, statefulSet, r.Scheme); err != nil {
+		return nil, err
+	}
+	return statefulSet, nil
+}
+
+// reconcileHeadlessService creates or updates the headless service giving
+// the replicas of a stateful process their stable network identity.
+func (r *ApplicationReconciler) reconcileHeadlessService(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+	process resolvers.Process,
+) error {
+	log := log.FromContext(ctx).WithValues("process", process.Name)
+
+	service := &corev1.Service{}
+	err := r.Get(
+		ctx,
+		types.NamespacedName{
+			Namespace: appToReconcile.Namespace,
+			Name:      resolvers.HeadlessServiceName(process.ResourceName),
+		},
+		service,
+	)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	exists := err == nil
+
+	newService, err := r.buildHeadlessService(appToReconcile, process)
+	if err != nil {
+		return err
+	}
+
+	drifted := exists && resolvers.IsDrifted(newService, service, newService.Spec, service.Spec)
+	if !exists {
+		log.Info("creating headless service", "service.name", newService.Name)
+	}
+	if err := r.applyResource(ctx, newService); err != nil {
+		return err
+	}
+	if drifted {
+		r.recordDriftCorrected(appToReconcile, "Service", newService.Name)
+	}
+
+	return nil
+}
+
+func (r *ApplicationReconciler) buildHeadlessService(
+	appToReconcile *operatorsv1alpha1.Application,
+	process resolvers.Process,
+) (*corev1.Service, error) {
+	selectorLabels := resolvers.SelectorLabels(appToReconcile.Name, process.Name)
+
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      resolvers.HeadlessServiceName(process.ResourceName),
+			Namespace: appToReconcile.Namespace,
+			Labels:    resolvers.MergeDefaultLabels(appToReconcile.Labels, selectorLabels),
+		},
+		Spec: corev1.ServiceSpec{
+			Selector:  selectorLabels,
+			Type:      corev1.ServiceTypeClusterIP,
+			ClusterIP: corev1.ClusterIPNone,
+			Ports:     process.Endpoints.AsServicePorts(),
+			// Replicas have to resolve their peers before they are ready,
+			// e.g. to form a cluster.
+			PublishNotReadyAddresses: true,
+		},
+	}
+
+	if err := setRenderedHash(service, service.Spec); err != nil {
+		return nil, err
+	}
+
+	if err := ctrl.SetControllerReference(appToReconcile, service, r.Scheme); err != nil {
+		return nil, err
+	}
+
+	return service, nil
+}
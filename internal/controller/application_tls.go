@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	operatorsv1alpha1 "github.com/perfectmak/k4indie/api/v1alpha1"
+	"github.com/perfectmak/k4indie/internal/controller/resolvers"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// certificatePollInterval is how often pending certificates are checked.
+const certificatePollInterval = time.Minute
+
+// reconcileCertificates reports the readiness of the certificates of the
+// TLS domains of the application on its status. A certificate is ready once
+// its Secret holds a certificate, whether it was issued by cert-manager or
+// provided by the user.
+// When the router does not terminate TLS, the TLS settings of the
+// application are ignored and the condition reports it instead.
+// It returns false while some certificates are still pending.
+func (r *ApplicationReconciler) reconcileCertificates(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+) (bool, error) {
+	log := log.FromContext(ctx)
+	router, err := r.resolveRouter(ctx)
+	if err != nil {
+		log.Error(err, "failed to resolve router")
+		return false, err
+	}
+
+	domains := resolvers.ResolveDomainTLS(
+		appToReconcile.Name,
+		appToReconcile.Spec.TLS,
+		resolvers.ResolveProcesses(appToReconcile),
+	)
+
+	if len(domains) > 0 && !router.TerminatesTLS() {
+		hosts := []string{}
+		for _, domain := range domains {
+			hosts = append(hosts, domain.Host)
+		}
+		condition := metav1.Condition{
+			Type:   typeCertificateReady,
+			Status: metav1.ConditionFalse,
+			Reason: "TLSNotTerminated",
+			Message: fmt.Sprintf(
+				"The router does not terminate TLS, configure it on the Gateway listeners instead for: %s",
+				strings.Join(hosts, ", "),
+			),
+		}
+		return true, r.setCertificateCondition(ctx, appToReconcile, condition)
+	}
+
+	if len(domains) == 0 {
+		if meta.FindStatusCondition(appToReconcile.Status.Conditions, typeCertificateReady) == nil {
+			return true, nil
+		}
+
+		meta.RemoveStatusCondition(&appToReconcile.Status.Conditions, typeCertificateReady)
+		if err := r.updateStatus(ctx, appToReconcile); err != nil {
+			log.Error(err, "failed to update application status")
+			return false, err
+		}
+		return true, nil
+	}
+
+	pendingHosts := []string{}
+	for _, domain := range domains {
+		// Certificate Secrets are not cached, see ConfigLabel.
+		secret := &corev1.Secret{}
+		err := r.APIReader.Get(
+			ctx,
+			types.NamespacedName{Namespace: appToReconcile.Namespace, Name: domain.SecretName},
+			secret,
+		)
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Error(err, "failed to get certificate secret", "secret.name", domain.SecretName)
+			return false, err
+		}
+		if err != nil || len(secret.Data[corev1.TLSCertKey]) == 0 {
+			pendingHosts = append(pendingHosts, domain.Host)
+		}
+	}
+
+	condition := metav1.Condition{
+		Type:    typeCertificateReady,
+		Status:  metav1.ConditionTrue,
+		Reason:  "CertificatesReady",
+		Message: fmt.Sprintf("Certificates for %d domains are ready", len(domains)),
+	}
+	if len(pendingHosts) > 0 {
+		condition = metav1.Condition{
+			Type:   typeCertificateReady,
+			Status: metav1.ConditionFalse,
+			Reason: "CertificatesPending",
+			Message: fmt.Sprintf(
+				"Waiting for certificates of: %s",
+				strings.Join(pendingHosts, ", "),
+			),
+		}
+	}
+
+	if err := r.setCertificateCondition(ctx, appToReconcile, condition); err != nil {
+		return false, err
+	}
+
+	return len(pendingHosts) == 0, nil
+}
+
+// setCertificateCondition sets the certificate condition on the status of
+// the application, if it changed.
+func (r *ApplicationReconciler) setCertificateCondition(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+	condition metav1.Condition,
+) error {
+	existing := meta.FindStatusCondition(appToReconcile.Status.Conditions, typeCertificateReady)
+	if existing != nil && existing.Status == condition.Status &&
+		existing.Reason == condition.Reason && existing.Message == condition.Message {
+		return nil
+	}
+
+	meta.SetStatusCondition(&appToReconcile.Status.Conditions, condition)
+	if err := r.updateStatus(ctx, appToReconcile); err != nil {
+		log.FromContext(ctx).Error(err, "failed to update application status")
+		return err
+	}
+
+	return nil
+}
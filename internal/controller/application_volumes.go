@@ -0,0 +1,197 @@
+package controller
+
+import (
+	"context"
+
+	operatorsv1alpha1 "github.com/perfectmak/k4indie/api/v1alpha1"
+	"github.com/perfectmak/k4indie/internal/controller/resolvers"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// reconcileVolumes attempts to create a PersistentVolumeClaim for each
+// volume of the application if it does not exist. And if it does, it tries
+// to update the claim to match the application spec, which only lets its
+// size grow. Claims of removed volumes are retained along with their data,
+// and adopted again if a volume with the same name is added back.
+// Stateful applications don't share their volumes, so they have no claims
+// of their own.
+func (r *ApplicationReconciler) reconcileVolumes(
+	ctx context.Context,
+	req reconcile.Request,
+	appToReconcile *operatorsv1alpha1.Application,
+) (*reconcile.Result, error) {
+	log := log.FromContext(ctx)
+
+	// The replicas of stateful applications get their own claims from the
+	// claim templates of their statefulset instead.
+	volumes := appToReconcile.Spec.Volumes
+	if resolvers.IsStateful(appToReconcile) {
+		volumes = nil
+	}
+
+	for _, volume := range volumes {
+		if err := r.reconcileVolumeClaim(ctx, appToReconcile, volume); err != nil {
+			log.Error(err, "failed to reconcile volume claim", "volume", volume.Name)
+			return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
+		}
+	}
+
+	if err := r.retainStaleVolumeClaims(ctx, appToReconcile, volumes); err != nil {
+		log.Error(err, "failed to retain stale volume claims")
+		return r.setApplicationReconcileError(ctx, req, appToReconcile, log, err)
+	}
+
+	return nil, nil
+}
+
+// retainStaleVolumeClaims orphans the claims of the volumes the application
+// no longer has, so removing or renaming a volume does not delete its data.
+// They can be deleted by hand once their data is no longer needed.
+func (r *ApplicationReconciler) retainStaleVolumeClaims(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+	volumes []operatorsv1alpha1.ApplicationVolume,
+) error {
+	log := log.FromContext(ctx)
+
+	keep := map[string]struct{}{}
+	for _, volume := range volumes {
+		keep[resolvers.VolumeClaimName(appToReconcile.Name, volume.Name)] = struct{}{}
+	}
+
+	claims := &corev1.PersistentVolumeClaimList{}
+	err := r.List(
+		ctx,
+		claims,
+		client.InNamespace(appToReconcile.Namespace),
+		client.MatchingLabels{resolvers.InstanceLabel: appToReconcile.Name},
+	)
+	if err != nil {
+		return err
+	}
+
+	for i := range claims.Items {
+		claim := &claims.Items[i]
+		if _, exists := keep[claim.Name]; exists || !metav1.IsControlledBy(claim, appToReconcile) {
+			continue
+		}
+
+		log.Info("retaining volume claim of removed volume", "claim.name", claim.Name)
+		if err := r.orphanResource(ctx, appToReconcile, claim); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *ApplicationReconciler) reconcileVolumeClaim(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+	volume operatorsv1alpha1.ApplicationVolume,
+) error {
+	log := log.FromContext(ctx).WithValues("volume", volume.Name)
+
+	claim := &corev1.PersistentVolumeClaim{}
+	err := r.Get(
+		ctx,
+		types.NamespacedName{
+			Namespace: appToReconcile.Namespace,
+			Name:      resolvers.VolumeClaimName(appToReconcile.Name, volume.Name),
+		},
+		claim,
+	)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	exists := err == nil
+
+	newClaim, err := r.buildVolumeClaim(appToReconcile, volume)
+	if err != nil {
+		return err
+	}
+
+	drifted := exists && resolvers.IsDrifted(newClaim, claim, newClaim.Spec, claim.Spec)
+	if !exists {
+		log.Info("creating volume claim", "claim.name", newClaim.Name)
+	}
+	if err := r.applyResource(ctx, newClaim); err != nil {
+		return err
+	}
+	if drifted {
+		r.recordDriftCorrected(appToReconcile, "PersistentVolumeClaim", newClaim.Name)
+	}
+
+	return nil
+}
+
+func (r *ApplicationReconciler) buildVolumeClaim(
+	appToReconcile *operatorsv1alpha1.Application,
+	volume operatorsv1alpha1.ApplicationVolume,
+) (*corev1.PersistentVolumeClaim, error) {
+	claim := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      resolvers.VolumeClaimName(appToReconcile.Name, volume.Name),
+			Namespace: appToReconcile.Namespace,
+			Labels: resolvers.MergeDefaultLabels(
+				appToReconcile.Labels,
+				map[string]string{resolvers.InstanceLabel: appToReconcile.Name},
+			),
+		},
+		Spec: resolvers.BuildVolumeClaimSpec(volume),
+	}
+
+	if err := setRenderedHash(claim, claim.Spec); err != nil {
+		return nil, err
+	}
+
+	if err := ctrl.SetControllerReference(appToReconcile, claim, r.Scheme); err != nil {
+		return nil, err
+	}
+
+	return claim, nil
+}
+
+// deleteReplicaVolumeClaims deletes the claims created from the claim
+// templates of the statefulsets of the application. Statefulsets keep the
+// claims of their replicas when they are deleted, and they are not owned by
+// the application, so they are not garbage collected with it.
+func (r *ApplicationReconciler) deleteReplicaVolumeClaims(
+	ctx context.Context,
+	appToReconcile *operatorsv1alpha1.Application,
+) error {
+	log := log.FromContext(ctx)
+
+	claims := &corev1.PersistentVolumeClaimList{}
+	err := r.List(
+		ctx,
+		claims,
+		client.InNamespace(appToReconcile.Namespace),
+		client.MatchingLabels{resolvers.InstanceLabel: appToReconcile.Name},
+		client.HasLabels{resolvers.ProcessLabel},
+	)
+	if err != nil {
+		return err
+	}
+
+	for i := range claims.Items {
+		claim := &claims.Items[i]
+		if metav1.GetControllerOf(claim) != nil {
+			continue
+		}
+
+		log.Info("deleting replica volume claim", "claim.name", claim.Name)
+		if err := r.Delete(ctx, claim); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
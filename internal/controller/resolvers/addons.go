@@ -0,0 +1,228 @@
+package resolvers
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"strconv"
+
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// AddonURLKey is the key of the addon credentials Secret holding the URL
+// applications connect to the addon with.
+const AddonURLKey = "URL"
+
+const (
+	postgresUser     = "app"
+	postgresDatabase = "app"
+	passwordLength   = 32
+	passwordAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+)
+
+// addonKind holds how an addon type is run.
+type addonKind struct {
+	image          string
+	defaultVersion string
+	port           int32
+	dataPath       string
+	passwordKey    string
+	urlEnv         string
+	// user is the id of the user of the official image, which owns the
+	// data of the addon.
+	user int64
+}
+
+var addonKinds = map[v1alpha1.AddonType]addonKind{
+	v1alpha1.AddonPostgres: {
+		image:          "postgres",
+		defaultVersion: "16",
+		port:           5432,
+		dataPath:       "/var/lib/postgresql/data",
+		passwordKey:    "POSTGRES_PASSWORD",
+		urlEnv:         "DATABASE_URL",
+		user:           999,
+	},
+	v1alpha1.AddonRedis: {
+		image:          "redis",
+		defaultVersion: "7",
+		port:           6379,
+		dataPath:       "/data",
+		passwordKey:    "REDIS_PASSWORD",
+		urlEnv:         "REDIS_URL",
+		user:           999,
+	},
+}
+
+// AddonSecretName returns the name of the Secret holding the credentials of
+// an addon.
+func AddonSecretName(addonName string) string {
+	return fmt.Sprintf("%s-credentials", addonName)
+}
+
+// AddonDataClaimName returns the name of the PersistentVolumeClaim holding
+// the data of an addon.
+func AddonDataClaimName(addonName string) string {
+	return fmt.Sprintf("%s-data", addonName)
+}
+
+// AddonLabels returns the labels of the resources of an addon. They only
+// contain values that never change for the addon, so they are safe to use
+// on immutable selectors.
+func AddonLabels(addonName string) map[string]string {
+	return MergeDefaultLabels(map[string]string{AddonLabel: addonName})
+}
+
+// AddonVersion returns the version the addon runs.
+func AddonVersion(addon *v1alpha1.Addon) string {
+	if addon.Spec.Version != "" {
+		return addon.Spec.Version
+	}
+
+	return addonKinds[addon.Spec.Type].defaultVersion
+}
+
+// AddonPort returns the port the addon listens on.
+func AddonPort(addonType v1alpha1.AddonType) int32 {
+	return addonKinds[addonType].port
+}
+
+// AddonURLEnv returns the name of the environment variable holding the URL
+// of an addon referenced by an application.
+func AddonURLEnv(ref v1alpha1.AddonReference, addonType v1alpha1.AddonType) string {
+	if ref.Env != "" {
+		return ref.Env
+	}
+
+	return addonKinds[addonType].urlEnv
+}
+
+// AddonPassword returns the password stored in the credentials of an addon,
+// or an empty string when the credentials have none yet.
+func AddonPassword(addonType v1alpha1.AddonType, credentials map[string][]byte) string {
+	return string(credentials[addonKinds[addonType].passwordKey])
+}
+
+// GeneratePassword returns a random alphanumeric password, which can be
+// used in URLs without escaping.
+func GeneratePassword() (string, error) {
+	password := make([]byte, passwordLength)
+	alphabetSize := big.NewInt(int64(len(passwordAlphabet)))
+	for i := range password {
+		n, err := rand.Int(rand.Reader, alphabetSize)
+		if err != nil {
+			return "", err
+		}
+		password[i] = passwordAlphabet[n.Int64()]
+	}
+
+	return string(password), nil
+}
+
+// BuildAddonCredentials builds the content of the credentials Secret of an
+// addon. It holds the variables configuring the official image of the addon
+// and the URL applications connect with, through the Service of the addon.
+func BuildAddonCredentials(addon *v1alpha1.Addon, password string) map[string][]byte {
+	kind := addonKinds[addon.Spec.Type]
+	host := net.JoinHostPort(
+		fmt.Sprintf("%s.%s.svc", addon.Name, addon.Namespace),
+		strconv.Itoa(int(kind.port)),
+	)
+
+	credentials := map[string][]byte{
+		kind.passwordKey: []byte(password),
+	}
+	addonURL := url.URL{Host: host}
+	switch addon.Spec.Type {
+	case v1alpha1.AddonPostgres:
+		credentials["POSTGRES_USER"] = []byte(postgresUser)
+		credentials["POSTGRES_DB"] = []byte(postgresDatabase)
+		addonURL.Scheme = "postgres"
+		addonURL.User = url.UserPassword(postgresUser, password)
+		addonURL.Path = "/" + postgresDatabase
+	case v1alpha1.AddonRedis:
+		addonURL.Scheme = "redis"
+		addonURL.User = url.UserPassword("", password)
+	}
+	credentials[AddonURLKey] = []byte(addonURL.String())
+
+	return credentials
+}
+
+// BuildAddonPodSecurityContext builds the security context of the pod of an
+// addon, which runs as the user of the official image of its type instead
+// of starting as root. The data volume is owned by the group of the user,
+// so the addon can write to it.
+func BuildAddonPodSecurityContext(addonType v1alpha1.AddonType) *corev1.PodSecurityContext {
+	user := addonKinds[addonType].user
+
+	return &corev1.PodSecurityContext{
+		RunAsNonRoot: &[]bool{true}[0],
+		RunAsUser:    &user,
+		RunAsGroup:   &user,
+		FSGroup:      &user,
+		SeccompProfile: &corev1.SeccompProfile{
+			Type: corev1.SeccompProfileTypeRuntimeDefault,
+		},
+	}
+}
+
+// BuildAddonContainer builds the container running an addon with the
+// official image of its type. The data of the addon is stored in the volume
+// named data.
+func BuildAddonContainer(addon *v1alpha1.Addon, resources corev1.ResourceRequirements) corev1.Container {
+	kind := addonKinds[addon.Spec.Type]
+	probe := &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(int(kind.port))},
+		},
+		PeriodSeconds: 10,
+	}
+
+	container := corev1.Container{
+		Name:  string(addon.Spec.Type),
+		Image: fmt.Sprintf("%s:%s", kind.image, AddonVersion(addon)),
+		EnvFrom: []corev1.EnvFromSource{{
+			SecretRef: &corev1.SecretEnvSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: AddonSecretName(addon.Name)},
+			},
+		}},
+		Ports: []corev1.ContainerPort{{
+			Name:          string(addon.Spec.Type),
+			ContainerPort: kind.port,
+			Protocol:      corev1.ProtocolTCP,
+		}},
+		VolumeMounts: []corev1.VolumeMount{{
+			Name:      "data",
+			MountPath: kind.dataPath,
+		}},
+		Resources:      resources,
+		ReadinessProbe: probe,
+		LivenessProbe:  probe,
+		SecurityContext: &corev1.SecurityContext{
+			AllowPrivilegeEscalation: &[]bool{false}[0],
+			Capabilities: &corev1.Capabilities{
+				Drop: []corev1.Capability{"ALL"},
+			},
+		},
+	}
+
+	switch addon.Spec.Type {
+	case v1alpha1.AddonPostgres:
+		// The data directory is a subdirectory of the volume, since
+		// postgres refuses to initialize a directory that isn't empty,
+		// e.g. with the lost+found directory of a new filesystem.
+		container.Env = []corev1.EnvVar{{Name: "PGDATA", Value: kind.dataPath + "/pgdata"}}
+	case v1alpha1.AddonRedis:
+		container.Command = []string{
+			"sh", "-c",
+			`exec redis-server --appendonly yes --requirepass "$REDIS_PASSWORD"`,
+		}
+	}
+
+	return container
+}
@@ -0,0 +1,97 @@
+package resolvers
+
+import (
+	"encoding/json"
+
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// defaultTargetCPU is the CPU utilization percentage to scale at when no
+// target is configured.
+const defaultTargetCPU = 80
+
+// ProcessesWithAutoscale returns only the processes that are autoscaled.
+func ProcessesWithAutoscale(processes []Process) []Process {
+	result := make([]Process, 0, len(processes))
+
+	for _, process := range processes {
+		if process.Autoscale != nil {
+			result = append(result, process)
+		}
+	}
+
+	return result
+}
+
+// AutoscaleMinReplicas returns the minimum number of replicas of an autoscaler.
+func AutoscaleMinReplicas(autoscale *v1alpha1.Autoscale) int32 {
+	return defaultInt32(autoscale.Min, 1)
+}
+
+// ProcessReplicas returns the replicas of the workload of a process. The
+// replicas of autoscaled processes are left unset, so they are only owned by
+// the autoscaler and the operator never reverts its scaling.
+func ProcessReplicas(process Process) *int32 {
+	if process.Autoscale != nil {
+		return nil
+	}
+
+	replicas := process.Replicas
+	return &replicas
+}
+
+// ManagesReplicas returns true when the field manager applied the replicas
+// of the spec of a workload.
+func ManagesReplicas(entries []metav1.ManagedFieldsEntry, manager string) bool {
+	for _, entry := range entries {
+		if entry.Manager != manager || entry.Operation != metav1.ManagedFieldsOperationApply ||
+			entry.Subresource != "" || entry.FieldsV1 == nil {
+			continue
+		}
+
+		fields := map[string]map[string]interface{}{}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			continue
+		}
+		if _, exists := fields["f:spec"]["f:replicas"]; exists {
+			return true
+		}
+	}
+
+	return false
+}
+
+// BuildAutoscaleMetrics builds the resource utilization metrics the
+// autoscaler of a process scales on.
+func BuildAutoscaleMetrics(autoscale *v1alpha1.Autoscale) []autoscalingv2.MetricSpec {
+	targetCPU := autoscale.TargetCPU
+	if targetCPU == nil && autoscale.TargetMemory == nil {
+		targetCPU = &[]int32{defaultTargetCPU}[0]
+	}
+
+	metrics := []autoscalingv2.MetricSpec{}
+	if targetCPU != nil {
+		metrics = append(metrics, resourceUtilizationMetric(corev1.ResourceCPU, *targetCPU))
+	}
+	if autoscale.TargetMemory != nil {
+		metrics = append(metrics, resourceUtilizationMetric(corev1.ResourceMemory, *autoscale.TargetMemory))
+	}
+
+	return metrics
+}
+
+func resourceUtilizationMetric(name corev1.ResourceName, utilization int32) autoscalingv2.MetricSpec {
+	return autoscalingv2.MetricSpec{
+		Type: autoscalingv2.ResourceMetricSourceType,
+		Resource: &autoscalingv2.ResourceMetricSource{
+			Name: name,
+			Target: autoscalingv2.MetricTarget{
+				Type:               autoscalingv2.UtilizationMetricType,
+				AverageUtilization: &[]int32{utilization}[0],
+			},
+		},
+	}
+}
@@ -0,0 +1,133 @@
+package resolvers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestBuildAutoscaleMetrics(t *testing.T) {
+	utilization := func(name corev1.ResourceName, value int32) autoscalingv2.MetricSpec {
+		return autoscalingv2.MetricSpec{
+			Type: autoscalingv2.ResourceMetricSourceType,
+			Resource: &autoscalingv2.ResourceMetricSource{
+				Name: name,
+				Target: autoscalingv2.MetricTarget{
+					Type:               autoscalingv2.UtilizationMetricType,
+					AverageUtilization: &value,
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		autoscale *v1alpha1.Autoscale
+		want      []autoscalingv2.MetricSpec
+	}{
+		{
+			name:      "should default to cpu",
+			autoscale: &v1alpha1.Autoscale{Max: 3},
+			want: []autoscalingv2.MetricSpec{
+				utilization(corev1.ResourceCPU, 80),
+			},
+		},
+		{
+			name: "should only scale on memory",
+			autoscale: &v1alpha1.Autoscale{
+				Max:          3,
+				TargetMemory: &[]int32{70}[0],
+			},
+			want: []autoscalingv2.MetricSpec{
+				utilization(corev1.ResourceMemory, 70),
+			},
+		},
+		{
+			name: "should scale on cpu and memory",
+			autoscale: &v1alpha1.Autoscale{
+				Max:          3,
+				TargetCPU:    &[]int32{60}[0],
+				TargetMemory: &[]int32{70}[0],
+			},
+			want: []autoscalingv2.MetricSpec{
+				utilization(corev1.ResourceCPU, 60),
+				utilization(corev1.ResourceMemory, 70),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BuildAutoscaleMetrics(tt.autoscale); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BuildAutoscaleMetrics() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessReplicas(t *testing.T) {
+	if got := ProcessReplicas(Process{Replicas: 3}); got == nil || *got != 3 {
+		t.Errorf("ProcessReplicas() = %v, want 3", got)
+	}
+	if got := ProcessReplicas(Process{Replicas: 3, Autoscale: &v1alpha1.Autoscale{Max: 5}}); got != nil {
+		t.Errorf("ProcessReplicas() = %v, want nil for autoscaled processes", *got)
+	}
+}
+
+func TestManagesReplicas(t *testing.T) {
+	fields := func(raw string) *metav1.FieldsV1 {
+		return &metav1.FieldsV1{Raw: []byte(raw)}
+	}
+
+	tests := []struct {
+		name    string
+		entries []metav1.ManagedFieldsEntry
+		want    bool
+	}{
+		{
+			name: "should find replicas applied by the manager",
+			entries: []metav1.ManagedFieldsEntry{{
+				Manager:   "k4indie",
+				Operation: metav1.ManagedFieldsOperationApply,
+				FieldsV1:  fields(`{"f:spec":{"f:replicas":{},"f:template":{}}}`),
+			}},
+			want: true,
+		},
+		{
+			name: "should ignore specs applied without replicas",
+			entries: []metav1.ManagedFieldsEntry{{
+				Manager:   "k4indie",
+				Operation: metav1.ManagedFieldsOperationApply,
+				FieldsV1:  fields(`{"f:spec":{"f:template":{}}}`),
+			}},
+			want: false,
+		},
+		{
+			name: "should ignore replicas scaled by the autoscaler",
+			entries: []metav1.ManagedFieldsEntry{
+				{
+					Manager:   "k4indie",
+					Operation: metav1.ManagedFieldsOperationApply,
+					FieldsV1:  fields(`{"f:spec":{"f:template":{}}}`),
+				},
+				{
+					Manager:     "kube-controller-manager",
+					Operation:   metav1.ManagedFieldsOperationUpdate,
+					Subresource: "scale",
+					FieldsV1:    fields(`{"f:spec":{"f:replicas":{}}}`),
+				},
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ManagesReplicas(tt.entries, "k4indie"); got != tt.want {
+				t.Errorf("ManagesReplicas() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
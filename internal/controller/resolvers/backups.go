@@ -0,0 +1,390 @@
+package resolvers
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// BackupContainerName is the name of the container of the backup pods
+	// reporting the backup in its termination message.
+	BackupContainerName = "backup"
+
+	backupUploaderImage = "amazon/aws-cli:2.15.0"
+	backupVolumePath    = "/backups"
+	backupWorkPath      = "/work"
+	defaultS3Region     = "us-east-1"
+)
+
+// backupScript dumps the database of the addon compressed, stores its
+// checksum along with it, deletes the oldest backups beyond RETAIN from
+// BACKUP_DIR when PRUNE is set, and writes the size and checksum of the
+// backup to RESULT_FILE.
+const backupScript = `set -eu
+file="$BACKUP_DIR/$BACKUP_NAME.sql.gz"
+pg_dump --no-owner --no-acl --compress=9 --dbname="$URL" --file="$file.partial"
+mv "$file.partial" "$file"
+size=$(wc -c < "$file")
+checksum=$(sha256sum "$file" | cut -d ' ' -f 1)
+echo "$checksum" > "$file.sha256"
+if [ -n "${PRUNE:-}" ]; then
+  ls -1t "$BACKUP_DIR"/*.sql.gz | tail -n +$((RETAIN + 1)) | while read -r old; do rm -f "$old" "$old.sha256"; done
+fi
+printf '{"sizeBytes":%d,"checksum":"sha256:%s"}' "$size" "$checksum" > "$RESULT_FILE"
+`
+
+// uploadScript uploads the backup dumped by the backup script to the bucket
+// and reports its result.
+const uploadScript = `set -eu
+aws s3 cp --only-show-errors "$BACKUP_DIR/$BACKUP_NAME.sql.gz" "s3://$BUCKET/$KEY_PREFIX$BACKUP_NAME.sql.gz"
+aws s3 cp --only-show-errors "$BACKUP_DIR/$BACKUP_NAME.sql.gz.sha256" "s3://$BUCKET/$KEY_PREFIX$BACKUP_NAME.sql.gz.sha256"
+cat "$BACKUP_DIR/result.json" > /dev/termination-log
+`
+
+// downloadScript downloads the backup to restore from the bucket, with its
+// checksum unless CHECKSUM is set.
+const downloadScript = `set -eu
+aws s3 cp --only-show-errors "s3://$BUCKET/$KEY_PREFIX$BACKUP_NAME.sql.gz" "$BACKUP_DIR/$BACKUP_NAME.sql.gz"
+if [ -z "${CHECKSUM:-}" ]; then
+  aws s3 cp --only-show-errors "s3://$BUCKET/$KEY_PREFIX$BACKUP_NAME.sql.gz.sha256" "$BACKUP_DIR/$BACKUP_NAME.sql.gz.sha256"
+fi
+`
+
+// restoreScript verifies the checksum of the backup before restoring it into
+// the database of the addon. The checksum defaults to the one stored along
+// with the backup.
+const restoreScript = `set -eu
+file="$BACKUP_DIR/$BACKUP_NAME.sql.gz"
+checksum="${CHECKSUM:-$(cat "$file.sha256")}"
+echo "$checksum  $file" | sha256sum -c -
+gunzip -c "$file" | psql --quiet --set=ON_ERROR_STOP=1 --dbname="$URL"
+`
+
+// AddonBackupCronJobName returns the name of the CronJob backing up an addon.
+func AddonBackupCronJobName(addonName string) string {
+	return fmt.Sprintf("%s-backup", addonName)
+}
+
+// AddonBackupClaimName returns the name of the PersistentVolumeClaim
+// storing the backups of an addon.
+func AddonBackupClaimName(addonName string) string {
+	return fmt.Sprintf("%s-backups", addonName)
+}
+
+// AddonRestoreJobName returns the name of the Job restoring a backup into
+// an addon.
+func AddonRestoreJobName(addonName string) string {
+	return fmt.Sprintf("%s-restore", addonName)
+}
+
+// BackupLocation returns where a backup of the addon is stored.
+func BackupLocation(addon *v1alpha1.Addon, backupName string) string {
+	return backupLocation(addon.Name, addon.Spec.Backup.S3, backupName)
+}
+
+// RestoreBackup is a backup restored into an addon, read from where it is
+// stored.
+type RestoreBackup struct {
+	// Addon is the name of the addon the backup was taken from.
+	Addon string
+	// Name is the name of the backup.
+	Name string
+	// S3 is the bucket the backup was uploaded to, or nil when the backup is
+	// stored on the backup volume of the addon.
+	S3 *v1alpha1.BackupS3Target
+	// Checksum of the backup, or empty to verify the backup against the
+	// checksum stored along with it.
+	Checksum string
+}
+
+// Location returns where the backup is stored.
+func (b RestoreBackup) Location() string {
+	return backupLocation(b.Addon, b.S3, b.Name)
+}
+
+// ResolveRestoreBackup returns the backup a restore reads. The source addon
+// the backup was taken from is nil when it doesn't exist anymore; when it
+// does, it defaults the bucket and checksum of the backup.
+func ResolveRestoreBackup(restore *v1alpha1.AddonRestore, source *v1alpha1.Addon) RestoreBackup {
+	backup := RestoreBackup{
+		Addon:    restore.Addon,
+		Name:     restore.Backup,
+		S3:       restore.S3,
+		Checksum: restore.Checksum,
+	}
+	if source == nil {
+		return backup
+	}
+
+	if backup.S3 == nil && source.Spec.Backup != nil {
+		backup.S3 = source.Spec.Backup.S3
+	}
+	if recorded := FindBackup(source, restore.Backup); backup.Checksum == "" && recorded != nil {
+		backup.Checksum = recorded.Checksum
+	}
+
+	return backup
+}
+
+// BuildBackupPodSpec builds the pod taking a backup of the addon. The
+// backup is named after the Job running the pod, and reported in the
+// termination message of the backup container.
+func BuildBackupPodSpec(addon *v1alpha1.Addon) corev1.PodSpec {
+	backup := addon.Spec.Backup
+	dump := corev1.Container{
+		Name:    BackupContainerName,
+		Image:   fmt.Sprintf("%s:%s", addonKinds[addon.Spec.Type].image, AddonVersion(addon)),
+		Command: []string{"sh", "-c", backupScript},
+		EnvFrom: addonCredentialsEnvFrom(addon.Name),
+		Env: append(backupNameEnv(),
+			corev1.EnvVar{Name: "RETAIN", Value: fmt.Sprint(backup.Retain)},
+		),
+	}
+
+	if backup.S3 == nil {
+		dump.Env = append(dump.Env,
+			corev1.EnvVar{Name: "BACKUP_DIR", Value: backupVolumePath},
+			corev1.EnvVar{Name: "PRUNE", Value: "true"},
+			corev1.EnvVar{Name: "RESULT_FILE", Value: corev1.TerminationMessagePathDefault},
+		)
+		dump.VolumeMounts = []corev1.VolumeMount{{Name: "backups", MountPath: backupVolumePath}}
+
+		return corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers:    []corev1.Container{dump},
+			Volumes: []corev1.Volume{{
+				Name: "backups",
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+						ClaimName: AddonBackupClaimName(addon.Name),
+					},
+				},
+			}},
+		}
+	}
+
+	// The backup is dumped to a scratch volume by an init container, since
+	// the image of the addon can't upload it.
+	dump.Name = "dump"
+	dump.Env = append(dump.Env,
+		corev1.EnvVar{Name: "BACKUP_DIR", Value: backupWorkPath},
+		corev1.EnvVar{Name: "RESULT_FILE", Value: backupWorkPath + "/result.json"},
+	)
+	dump.VolumeMounts = []corev1.VolumeMount{{Name: "work", MountPath: backupWorkPath}}
+
+	upload := s3Container(backup.S3, addon.Name, BackupContainerName, uploadScript)
+	upload.Env = append(upload.Env, backupNameEnv()...)
+
+	return corev1.PodSpec{
+		RestartPolicy:  corev1.RestartPolicyNever,
+		InitContainers: []corev1.Container{dump},
+		Containers:     []corev1.Container{upload},
+		Volumes:        []corev1.Volume{workVolume()},
+	}
+}
+
+// BuildRestorePodSpec builds the pod restoring a backup into the addon. The
+// checksum of the backup is verified before it is restored.
+func BuildRestorePodSpec(addon *v1alpha1.Addon, backup RestoreBackup) corev1.PodSpec {
+	env := []corev1.EnvVar{
+		{Name: "BACKUP_NAME", Value: backup.Name},
+		{Name: "CHECKSUM", Value: strings.TrimPrefix(backup.Checksum, "sha256:")},
+	}
+	restore := corev1.Container{
+		Name:    "restore",
+		Image:   fmt.Sprintf("%s:%s", addonKinds[addon.Spec.Type].image, AddonVersion(addon)),
+		Command: []string{"sh", "-c", restoreScript},
+		EnvFrom: addonCredentialsEnvFrom(addon.Name),
+		Env:     env,
+	}
+
+	if backup.S3 == nil {
+		restore.Env = append(restore.Env, corev1.EnvVar{Name: "BACKUP_DIR", Value: backupVolumePath})
+		restore.VolumeMounts = []corev1.VolumeMount{{Name: "backups", MountPath: backupVolumePath, ReadOnly: true}}
+
+		return corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers:    []corev1.Container{restore},
+			Volumes: []corev1.Volume{{
+				Name: "backups",
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+						ClaimName: AddonBackupClaimName(backup.Addon),
+						ReadOnly:  true,
+					},
+				},
+			}},
+		}
+	}
+
+	restore.Env = append(restore.Env, corev1.EnvVar{Name: "BACKUP_DIR", Value: backupWorkPath})
+	restore.VolumeMounts = []corev1.VolumeMount{{Name: "work", MountPath: backupWorkPath}}
+
+	download := s3Container(backup.S3, backup.Addon, "download", downloadScript)
+	download.Env = append(download.Env, env...)
+
+	return corev1.PodSpec{
+		RestartPolicy:  corev1.RestartPolicyNever,
+		InitContainers: []corev1.Container{download},
+		Containers:     []corev1.Container{restore},
+		Volumes:        []corev1.Volume{workVolume()},
+	}
+}
+
+// ParseBackupResult returns the backup reported by a backup pod in the
+// termination message of its backup container.
+func ParseBackupResult(
+	addon *v1alpha1.Addon,
+	backupName string,
+	pod *corev1.Pod,
+) (v1alpha1.AddonBackupStatus, error) {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name != BackupContainerName {
+			continue
+		}
+		terminated := status.State.Terminated
+		if terminated == nil || terminated.ExitCode != 0 {
+			return v1alpha1.AddonBackupStatus{}, fmt.Errorf("backup container of pod %s did not succeed", pod.Name)
+		}
+
+		backup := v1alpha1.AddonBackupStatus{}
+		if err := json.Unmarshal([]byte(terminated.Message), &backup); err != nil {
+			return v1alpha1.AddonBackupStatus{}, fmt.Errorf("invalid result of backup %s: %w", backupName, err)
+		}
+		if backup.Checksum == "" {
+			return v1alpha1.AddonBackupStatus{}, fmt.Errorf("backup %s reported no checksum", backupName)
+		}
+		backup.Name = backupName
+		backup.Time = terminated.FinishedAt
+		backup.Location = BackupLocation(addon, backupName)
+
+		return backup, nil
+	}
+
+	return v1alpha1.AddonBackupStatus{}, fmt.Errorf("pod %s has no backup container", pod.Name)
+}
+
+// RecordBackup adds the backup to the backups of an addon, unless it is
+// already recorded, and only keeps the most recent retain backups. Backups
+// are sorted from the oldest to the most recent.
+func RecordBackup(
+	backups []v1alpha1.AddonBackupStatus,
+	backup v1alpha1.AddonBackupStatus,
+	retain int32,
+) []v1alpha1.AddonBackupStatus {
+	recorded := make([]v1alpha1.AddonBackupStatus, 0, len(backups)+1)
+	for _, existing := range backups {
+		if existing.Name == backup.Name {
+			return backups
+		}
+		recorded = append(recorded, existing)
+	}
+	recorded = append(recorded, backup)
+
+	sort.SliceStable(recorded, func(i, j int) bool {
+		return recorded[i].Time.Before(&recorded[j].Time)
+	})
+	if retain > 0 && len(recorded) > int(retain) {
+		recorded = recorded[len(recorded)-int(retain):]
+	}
+
+	return recorded
+}
+
+// FindBackup returns the backup with the given name in the status of the
+// addon, or nil when the addon has no such backup.
+func FindBackup(addon *v1alpha1.Addon, name string) *v1alpha1.AddonBackupStatus {
+	for i := range addon.Status.Backups {
+		if addon.Status.Backups[i].Name == name {
+			return &addon.Status.Backups[i]
+		}
+	}
+
+	return nil
+}
+
+func backupLocation(addonName string, target *v1alpha1.BackupS3Target, backupName string) string {
+	if target != nil {
+		return fmt.Sprintf("s3://%s/%s%s.sql.gz", target.Bucket, backupKeyPrefix(target, addonName), backupName)
+	}
+
+	return fmt.Sprintf("pvc://%s/%s.sql.gz", AddonBackupClaimName(addonName), backupName)
+}
+
+func backupKeyPrefix(target *v1alpha1.BackupS3Target, addonName string) string {
+	return path.Join(target.Prefix, addonName) + "/"
+}
+
+// s3Container builds a container running the script with the AWS CLI,
+// configured to access the backups of the addon in the bucket.
+func s3Container(target *v1alpha1.BackupS3Target, addonName string, name string, script string) corev1.Container {
+	region := target.Region
+	if region == "" {
+		region = defaultS3Region
+	}
+	credentialsSecret := corev1.LocalObjectReference{Name: target.SecretName}
+
+	env := []corev1.EnvVar{
+		{Name: "BACKUP_DIR", Value: backupWorkPath},
+		{Name: "BUCKET", Value: target.Bucket},
+		{Name: "KEY_PREFIX", Value: backupKeyPrefix(target, addonName)},
+		{Name: "AWS_DEFAULT_REGION", Value: region},
+		{
+			Name: "AWS_ACCESS_KEY_ID",
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: credentialsSecret,
+				Key:                  "AWS_ACCESS_KEY_ID",
+			}},
+		},
+		{
+			Name: "AWS_SECRET_ACCESS_KEY",
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: credentialsSecret,
+				Key:                  "AWS_SECRET_ACCESS_KEY",
+			}},
+		},
+	}
+	if target.Endpoint != "" {
+		env = append(env, corev1.EnvVar{Name: "AWS_ENDPOINT_URL", Value: target.Endpoint})
+	}
+
+	return corev1.Container{
+		Name:         name,
+		Image:        backupUploaderImage,
+		Command:      []string{"sh", "-c", script},
+		Env:          env,
+		VolumeMounts: []corev1.VolumeMount{{Name: "work", MountPath: backupWorkPath}},
+	}
+}
+
+// backupNameEnv exposes the name of the Job running the backup pod, which
+// names the backup.
+func backupNameEnv() []corev1.EnvVar {
+	return []corev1.EnvVar{{
+		Name: "BACKUP_NAME",
+		ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{
+			FieldPath: "metadata.labels['job-name']",
+		}},
+	}}
+}
+
+func addonCredentialsEnvFrom(addonName string) []corev1.EnvFromSource {
+	return []corev1.EnvFromSource{{
+		SecretRef: &corev1.SecretEnvSource{
+			LocalObjectReference: corev1.LocalObjectReference{Name: AddonSecretName(addonName)},
+		},
+	}}
+}
+
+func workVolume() corev1.Volume {
+	return corev1.Volume{
+		Name:         "work",
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	}
+}
@@ -0,0 +1,358 @@
+package resolvers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func backupAddon(name string, backup *v1alpha1.AddonBackup) *v1alpha1.Addon {
+	return &v1alpha1.Addon{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop"},
+		Spec: v1alpha1.AddonSpec{
+			Type:    v1alpha1.AddonPostgres,
+			Version: "16",
+			Backup:  backup,
+		},
+	}
+}
+
+func TestBackupLocation(t *testing.T) {
+	tests := []struct {
+		name   string
+		backup *v1alpha1.AddonBackup
+		want   string
+	}{
+		{
+			name: "should locate backups on the volume",
+			backup: &v1alpha1.AddonBackup{
+				Volume: &v1alpha1.BackupVolumeTarget{Size: resource.MustParse("5Gi")},
+			},
+			want: "pvc://db-backups/db-backup-28000000.sql.gz",
+		},
+		{
+			name: "should locate backups under the prefix of the bucket",
+			backup: &v1alpha1.AddonBackup{
+				S3: &v1alpha1.BackupS3Target{Bucket: "backups", Prefix: "k4indie/"},
+			},
+			want: "s3://backups/k4indie/db/db-backup-28000000.sql.gz",
+		},
+		{
+			name: "should locate backups without prefix",
+			backup: &v1alpha1.AddonBackup{
+				S3: &v1alpha1.BackupS3Target{Bucket: "backups"},
+			},
+			want: "s3://backups/db/db-backup-28000000.sql.gz",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BackupLocation(backupAddon("db", tt.backup), "db-backup-28000000"); got != tt.want {
+				t.Errorf("BackupLocation() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildBackupPodSpec(t *testing.T) {
+	t.Run("should dump to the backup volume", func(t *testing.T) {
+		addon := backupAddon("db", &v1alpha1.AddonBackup{
+			Retain: 3,
+			Volume: &v1alpha1.BackupVolumeTarget{Size: resource.MustParse("5Gi")},
+		})
+
+		spec := BuildBackupPodSpec(addon)
+		if len(spec.InitContainers) != 0 || len(spec.Containers) != 1 {
+			t.Fatalf("BuildBackupPodSpec() containers = %v, init containers = %v", spec.Containers, spec.InitContainers)
+		}
+		container := spec.Containers[0]
+		if container.Name != BackupContainerName || container.Image != "postgres:16" {
+			t.Errorf("BuildBackupPodSpec() container = %s %s", container.Name, container.Image)
+		}
+		if got := envValue(container.Env, "RETAIN"); got != "3" {
+			t.Errorf("BuildBackupPodSpec() RETAIN = %v, want 3", got)
+		}
+		if got := envValue(container.Env, "PRUNE"); got != "true" {
+			t.Errorf("BuildBackupPodSpec() PRUNE = %v, want true", got)
+		}
+		if got := spec.Volumes[0].PersistentVolumeClaim.ClaimName; got != "db-backups" {
+			t.Errorf("BuildBackupPodSpec() claim = %v, want db-backups", got)
+		}
+		if got := container.EnvFrom[0].SecretRef.Name; got != "db-credentials" {
+			t.Errorf("BuildBackupPodSpec() envFrom = %v, want db-credentials", got)
+		}
+	})
+
+	t.Run("should upload the dump to the bucket", func(t *testing.T) {
+		addon := backupAddon("db", &v1alpha1.AddonBackup{
+			Retain: 7,
+			S3: &v1alpha1.BackupS3Target{
+				Bucket:     "backups",
+				Endpoint:   "http://minio.minio.svc:9000",
+				SecretName: "minio",
+			},
+		})
+
+		spec := BuildBackupPodSpec(addon)
+		if len(spec.InitContainers) != 1 || len(spec.Containers) != 1 {
+			t.Fatalf("BuildBackupPodSpec() containers = %v, init containers = %v", spec.Containers, spec.InitContainers)
+		}
+		if got := envValue(spec.InitContainers[0].Env, "PRUNE"); got != "" {
+			t.Errorf("BuildBackupPodSpec() PRUNE = %v, want unset", got)
+		}
+		upload := spec.Containers[0]
+		if upload.Name != BackupContainerName || upload.Image != backupUploaderImage {
+			t.Errorf("BuildBackupPodSpec() container = %s %s", upload.Name, upload.Image)
+		}
+		want := map[string]string{
+			"BUCKET":             "backups",
+			"KEY_PREFIX":         "db/",
+			"AWS_DEFAULT_REGION": "us-east-1",
+			"AWS_ENDPOINT_URL":   "http://minio.minio.svc:9000",
+		}
+		for name, value := range want {
+			if got := envValue(upload.Env, name); got != value {
+				t.Errorf("BuildBackupPodSpec() %s = %v, want %v", name, got, value)
+			}
+		}
+		if spec.Volumes[0].EmptyDir == nil {
+			t.Errorf("BuildBackupPodSpec() volumes = %v, want an empty dir", spec.Volumes)
+		}
+	})
+}
+
+func TestResolveRestoreBackup(t *testing.T) {
+	bucket := &v1alpha1.BackupS3Target{Bucket: "backups", Prefix: "prod", SecretName: "s3"}
+	otherBucket := &v1alpha1.BackupS3Target{Bucket: "archive", SecretName: "archive"}
+	source := backupAddon("db", &v1alpha1.AddonBackup{S3: bucket})
+	source.Status.Backups = []v1alpha1.AddonBackupStatus{{Name: "db-backup-28000000", Checksum: "sha256:abc"}}
+
+	tests := []struct {
+		name    string
+		restore v1alpha1.AddonRestore
+		source  *v1alpha1.Addon
+		want    RestoreBackup
+	}{
+		{
+			name:    "should read the backup volume when the source addon is deleted",
+			restore: v1alpha1.AddonRestore{Addon: "db", Backup: "db-backup-28000000"},
+			want:    RestoreBackup{Addon: "db", Name: "db-backup-28000000"},
+		},
+		{
+			name:    "should default to the bucket and checksum of the source addon",
+			restore: v1alpha1.AddonRestore{Addon: "db", Backup: "db-backup-28000000"},
+			source:  source,
+			want:    RestoreBackup{Addon: "db", Name: "db-backup-28000000", S3: bucket, Checksum: "sha256:abc"},
+		},
+		{
+			name:    "should leave the checksum of a pruned backup to the stored one",
+			restore: v1alpha1.AddonRestore{Addon: "db", Backup: "db-backup-27000000"},
+			source:  source,
+			want:    RestoreBackup{Addon: "db", Name: "db-backup-27000000", S3: bucket},
+		},
+		{
+			name: "should prefer the bucket and checksum of the restore",
+			restore: v1alpha1.AddonRestore{
+				Addon:    "db",
+				Backup:   "db-backup-28000000",
+				S3:       otherBucket,
+				Checksum: "sha256:def",
+			},
+			source: source,
+			want:   RestoreBackup{Addon: "db", Name: "db-backup-28000000", S3: otherBucket, Checksum: "sha256:def"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ResolveRestoreBackup(&tt.restore, tt.source)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolveRestoreBackup() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildRestorePodSpec(t *testing.T) {
+	t.Run("should restore from the backup volume of the source", func(t *testing.T) {
+		backup := RestoreBackup{Addon: "db", Name: "db-backup-28000000", Checksum: "sha256:abc"}
+
+		spec := BuildRestorePodSpec(backupAddon("db-copy", nil), backup)
+		if len(spec.InitContainers) != 0 {
+			t.Errorf("BuildRestorePodSpec() init containers = %v, want none", spec.InitContainers)
+		}
+		restore := spec.Containers[0]
+		if got := envValue(restore.Env, "CHECKSUM"); got != "abc" {
+			t.Errorf("BuildRestorePodSpec() CHECKSUM = %v, want abc", got)
+		}
+		if got := restore.EnvFrom[0].SecretRef.Name; got != "db-copy-credentials" {
+			t.Errorf("BuildRestorePodSpec() envFrom = %v, want db-copy-credentials", got)
+		}
+		claim := spec.Volumes[0].PersistentVolumeClaim
+		if claim.ClaimName != "db-backups" || !claim.ReadOnly {
+			t.Errorf("BuildRestorePodSpec() claim = %v, want db-backups read only", claim)
+		}
+	})
+
+	t.Run("should download from the bucket", func(t *testing.T) {
+		backup := RestoreBackup{
+			Addon: "db",
+			Name:  "db-backup-28000000",
+			S3:    &v1alpha1.BackupS3Target{Bucket: "backups", Prefix: "prod", SecretName: "s3"},
+		}
+
+		spec := BuildRestorePodSpec(backupAddon("db-copy", nil), backup)
+		if len(spec.InitContainers) != 1 {
+			t.Fatalf("BuildRestorePodSpec() init containers = %v, want a download", spec.InitContainers)
+		}
+		download := spec.InitContainers[0]
+		if got := envValue(download.Env, "KEY_PREFIX"); got != "prod/db/" {
+			t.Errorf("BuildRestorePodSpec() KEY_PREFIX = %v, want prod/db/", got)
+		}
+		if got := envValue(download.Env, "BACKUP_NAME"); got != backup.Name {
+			t.Errorf("BuildRestorePodSpec() BACKUP_NAME = %v, want %v", got, backup.Name)
+		}
+		if got := envValue(download.Env, "CHECKSUM"); got != "" {
+			t.Errorf("BuildRestorePodSpec() CHECKSUM = %v, want the stored checksum", got)
+		}
+	})
+}
+
+func TestParseBackupResult(t *testing.T) {
+	addon := backupAddon("db", &v1alpha1.AddonBackup{
+		S3: &v1alpha1.BackupS3Target{Bucket: "backups"},
+	})
+	finishedAt := metav1.NewTime(time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC))
+	podWithResult := func(exitCode int32, message string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "db-backup-28000000-abcde"},
+			Status: corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name: BackupContainerName,
+					State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+						ExitCode:   exitCode,
+						Message:    message,
+						FinishedAt: finishedAt,
+					}},
+				}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		pod     *corev1.Pod
+		want    v1alpha1.AddonBackupStatus
+		wantErr bool
+	}{
+		{
+			name: "should parse the result",
+			pod:  podWithResult(0, `{"sizeBytes":1024,"checksum":"sha256:abc"}`),
+			want: v1alpha1.AddonBackupStatus{
+				Name:      "db-backup-28000000",
+				Time:      finishedAt,
+				SizeBytes: 1024,
+				Checksum:  "sha256:abc",
+				Location:  "s3://backups/db/db-backup-28000000.sql.gz",
+			},
+		},
+		{
+			name:    "should fail when the backup failed",
+			pod:     podWithResult(1, ""),
+			wantErr: true,
+		},
+		{
+			name:    "should fail without checksum",
+			pod:     podWithResult(0, `{"sizeBytes":1024}`),
+			wantErr: true,
+		},
+		{
+			name:    "should fail on invalid results",
+			pod:     podWithResult(0, "pg_dump: error"),
+			wantErr: true,
+		},
+		{
+			name:    "should fail without backup container",
+			pod:     &corev1.Pod{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseBackupResult(addon, "db-backup-28000000", tt.pod)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBackupResult() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseBackupResult() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordBackup(t *testing.T) {
+	backupAt := func(name string, hour int) v1alpha1.AddonBackupStatus {
+		return v1alpha1.AddonBackupStatus{
+			Name: name,
+			Time: metav1.NewTime(time.Date(2024, 1, 1, hour, 0, 0, 0, time.UTC)),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		backups []v1alpha1.AddonBackupStatus
+		backup  v1alpha1.AddonBackupStatus
+		retain  int32
+		want    []v1alpha1.AddonBackupStatus
+	}{
+		{
+			name:   "should record the first backup",
+			backup: backupAt("a", 1),
+			retain: 2,
+			want:   []v1alpha1.AddonBackupStatus{backupAt("a", 1)},
+		},
+		{
+			name:    "should sort backups by time",
+			backups: []v1alpha1.AddonBackupStatus{backupAt("b", 2)},
+			backup:  backupAt("a", 1),
+			retain:  2,
+			want:    []v1alpha1.AddonBackupStatus{backupAt("a", 1), backupAt("b", 2)},
+		},
+		{
+			name:    "should drop the oldest backups",
+			backups: []v1alpha1.AddonBackupStatus{backupAt("a", 1), backupAt("b", 2)},
+			backup:  backupAt("c", 3),
+			retain:  2,
+			want:    []v1alpha1.AddonBackupStatus{backupAt("b", 2), backupAt("c", 3)},
+		},
+		{
+			name:    "should not record a backup twice",
+			backups: []v1alpha1.AddonBackupStatus{backupAt("a", 1)},
+			backup:  backupAt("a", 1),
+			retain:  2,
+			want:    []v1alpha1.AddonBackupStatus{backupAt("a", 1)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RecordBackup(tt.backups, tt.backup, tt.retain); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RecordBackup() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func envValue(env []corev1.EnvVar, name string) string {
+	for _, envVar := range env {
+		if envVar.Name == name {
+			return envVar.Value
+		}
+	}
+
+	return ""
+}
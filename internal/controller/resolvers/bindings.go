@@ -0,0 +1,107 @@
+package resolvers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// BindingEndpoint is the in-cluster address of the process an application
+// is bound to.
+type BindingEndpoint struct {
+	Host string
+	Port int32
+}
+
+// URL returns the http URL of the endpoint.
+func (e BindingEndpoint) URL() string {
+	return fmt.Sprintf("http://%s:%d", e.Host, e.Port)
+}
+
+// ResolveBindingEndpoint returns the address of the Service of the process
+// the binding selects in the bound application.
+func ResolveBindingEndpoint(
+	target *v1alpha1.Application,
+	binding v1alpha1.ApplicationBinding,
+) (BindingEndpoint, error) {
+	var process *Process
+	for _, candidate := range ProcessesWithEndpoints(ResolveProcesses(target)) {
+		if binding.Process == "" || candidate.Name == binding.Process {
+			process = &candidate
+			break
+		}
+	}
+	if process == nil {
+		if binding.Process != "" {
+			return BindingEndpoint{}, fmt.Errorf(
+				"process %q of application %s/%s has no endpoints", binding.Process, target.Namespace, target.Name,
+			)
+		}
+		return BindingEndpoint{}, fmt.Errorf("application %s/%s has no endpoints", target.Namespace, target.Name)
+	}
+
+	endpoint := BindingEndpoint{
+		Host: fmt.Sprintf("%s.%s.svc", process.ResourceName, target.Namespace),
+		Port: process.Endpoints[0].Port,
+	}
+	if binding.Port == 0 {
+		return endpoint, nil
+	}
+
+	for _, candidate := range process.Endpoints {
+		if candidate.Port == binding.Port {
+			endpoint.Port = binding.Port
+			return endpoint, nil
+		}
+	}
+
+	return BindingEndpoint{}, fmt.Errorf(
+		"process %q of application %s/%s has no endpoint on port %d",
+		process.Name, target.Namespace, target.Name, binding.Port,
+	)
+}
+
+// BuildBindingEnvVars returns the environment variables exposing the
+// address of a bound endpoint.
+func BuildBindingEnvVars(binding v1alpha1.ApplicationBinding, endpoint BindingEndpoint) []corev1.EnvVar {
+	prefix := binding.EnvPrefix()
+
+	return []corev1.EnvVar{
+		{Name: prefix + "_URL", Value: endpoint.URL()},
+		{Name: prefix + "_HOST", Value: endpoint.Host},
+		{Name: prefix + "_PORT", Value: fmt.Sprint(endpoint.Port)},
+	}
+}
+
+// BindingNamespace returns the namespace of the application bound by an
+// application of the given namespace.
+func BindingNamespace(namespace string, binding v1alpha1.ApplicationBinding) string {
+	if binding.Namespace == "" {
+		return namespace
+	}
+
+	return binding.Namespace
+}
+
+// IsBindingAllowed checks if the applications of a namespace may bind to
+// the applications of the target namespace, given the allowed binding
+// namespaces annotation of the target namespace.
+func IsBindingAllowed(namespace string, targetNamespace string, annotation string, annotated bool) bool {
+	if namespace == targetNamespace {
+		return true
+	}
+	if !annotated {
+		return false
+	}
+
+	for _, allowed := range strings.Split(annotation, ",") {
+		allowed = strings.TrimSpace(allowed)
+		if allowed == "*" || allowed == namespace {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,152 @@
+package resolvers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestResolveBindingEndpoint(t *testing.T) {
+	api := &v1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "backend"},
+		Spec: v1alpha1.ApplicationSpec{
+			Processes: map[string]v1alpha1.ApplicationProcess{
+				"worker": {},
+				"web": {Endpoints: v1alpha1.ApplicationEndpoints{
+					{Port: 8080, Domain: "api.example.com"},
+					{Port: 9090},
+				}},
+				"admin": {Endpoints: v1alpha1.ApplicationEndpoints{{Port: 3000}}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		target  *v1alpha1.Application
+		binding v1alpha1.ApplicationBinding
+		want    BindingEndpoint
+		wantErr bool
+	}{
+		{
+			name: "should bind the default process",
+			target: &v1alpha1.Application{
+				ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "default"},
+				Spec: v1alpha1.ApplicationSpec{
+					Endpoints: v1alpha1.ApplicationEndpoints{{Port: 8080}},
+				},
+			},
+			binding: v1alpha1.ApplicationBinding{Name: "shop", Application: "shop"},
+			want:    BindingEndpoint{Host: "shop.default.svc", Port: 8080},
+		},
+		{
+			name:    "should bind the first process with endpoints",
+			target:  api,
+			binding: v1alpha1.ApplicationBinding{Name: "api", Application: "api"},
+			want:    BindingEndpoint{Host: "api-admin.backend.svc", Port: 3000},
+		},
+		{
+			name:    "should bind the selected process and port",
+			target:  api,
+			binding: v1alpha1.ApplicationBinding{Name: "api", Application: "api", Process: "web", Port: 9090},
+			want:    BindingEndpoint{Host: "api-web.backend.svc", Port: 9090},
+		},
+		{
+			name:    "should fail on processes without endpoints",
+			target:  api,
+			binding: v1alpha1.ApplicationBinding{Name: "api", Application: "api", Process: "worker"},
+			wantErr: true,
+		},
+		{
+			name:    "should fail on ports without endpoint",
+			target:  api,
+			binding: v1alpha1.ApplicationBinding{Name: "api", Application: "api", Process: "web", Port: 80},
+			wantErr: true,
+		},
+		{
+			name: "should fail on applications without endpoints",
+			target: &v1alpha1.Application{
+				ObjectMeta: metav1.ObjectMeta{Name: "jobs", Namespace: "default"},
+			},
+			binding: v1alpha1.ApplicationBinding{Name: "jobs", Application: "jobs"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveBindingEndpoint(tt.target, tt.binding)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveBindingEndpoint() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveBindingEndpoint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildBindingEnvVars(t *testing.T) {
+	binding := v1alpha1.ApplicationBinding{Name: "payments-api", Application: "payments"}
+	endpoint := BindingEndpoint{Host: "payments.billing.svc", Port: 8080}
+
+	want := []corev1.EnvVar{
+		{Name: "PAYMENTS_API_URL", Value: "http://payments.billing.svc:8080"},
+		{Name: "PAYMENTS_API_HOST", Value: "payments.billing.svc"},
+		{Name: "PAYMENTS_API_PORT", Value: "8080"},
+	}
+	if got := BuildBindingEnvVars(binding, endpoint); !reflect.DeepEqual(got, want) {
+		t.Errorf("BuildBindingEnvVars() = %v, want %v", got, want)
+	}
+}
+
+func TestIsBindingAllowed(t *testing.T) {
+	tests := []struct {
+		name       string
+		namespace  string
+		annotation string
+		annotated  bool
+		want       bool
+	}{
+		{
+			name:      "should allow the same namespace",
+			namespace: "backend",
+			want:      true,
+		},
+		{
+			name:      "should deny other namespaces without annotation",
+			namespace: "frontend",
+			want:      false,
+		},
+		{
+			name:       "should allow listed namespaces",
+			namespace:  "frontend",
+			annotation: "staging, frontend",
+			annotated:  true,
+			want:       true,
+		},
+		{
+			name:       "should deny unlisted namespaces",
+			namespace:  "frontend",
+			annotation: "staging",
+			annotated:  true,
+			want:       false,
+		},
+		{
+			name:       "should allow all namespaces",
+			namespace:  "frontend",
+			annotation: "*",
+			annotated:  true,
+			want:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsBindingAllowed(tt.namespace, "backend", tt.annotation, tt.annotated); got != tt.want {
+				t.Errorf("IsBindingAllowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
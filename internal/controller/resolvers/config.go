@@ -0,0 +1,91 @@
+package resolvers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ConfigHashAnnotation is set on the pod template of generated workloads
+// so that any change to the referenced config triggers a new rollout.
+const ConfigHashAnnotation = "k4indie.io/config-hash"
+
+// HashConfigData returns a stable hash of the content of the given
+// Secrets and ConfigMaps. The order of the objects and of their keys does
+// not affect the result.
+func HashConfigData(secrets []corev1.Secret, configMaps []corev1.ConfigMap) string {
+	h := sha256.New()
+
+	sort.Slice(secrets, func(i, j int) bool {
+		return secrets[i].Name < secrets[j].Name
+	})
+	for _, secret := range secrets {
+		writeHashEntry(h, "secret", secret.Name)
+		writeHashData(h, secret.Data)
+	}
+
+	sort.Slice(configMaps, func(i, j int) bool {
+		return configMaps[i].Name < configMaps[j].Name
+	})
+	for _, configMap := range configMaps {
+		writeHashEntry(h, "configmap", configMap.Name)
+
+		data := make(map[string][]byte, len(configMap.Data)+len(configMap.BinaryData))
+		for k, v := range configMap.Data {
+			data[k] = []byte(v)
+		}
+		for k, v := range configMap.BinaryData {
+			data[k] = v
+		}
+		writeHashData(h, data)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func writeHashData(h hash.Hash, data map[string][]byte) {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		writeHashEntry(h, k, string(data[k]))
+	}
+}
+
+func writeHashEntry(h hash.Hash, key, value string) {
+	h.Write([]byte(key))
+	h.Write([]byte{0})
+	h.Write([]byte(value))
+	h.Write([]byte{0})
+}
+
+// MergeEnvVars merges lists of environment variables by name. A variable
+// replaces the variable with the same name of an earlier list, at the
+// position of the earlier one, so containers never repeat a name.
+func MergeEnvVars(lists ...[]corev1.EnvVar) []corev1.EnvVar {
+	size := 0
+	for _, envVars := range lists {
+		size += len(envVars)
+	}
+
+	merged := make([]corev1.EnvVar, 0, size)
+	positions := make(map[string]int, size)
+	for _, envVars := range lists {
+		for _, envVar := range envVars {
+			if i, exists := positions[envVar.Name]; exists {
+				merged[i] = envVar
+				continue
+			}
+			positions[envVar.Name] = len(merged)
+			merged = append(merged, envVar)
+		}
+	}
+
+	return merged
+}
@@ -0,0 +1,143 @@
+package resolvers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHashConfigData(t *testing.T) {
+	database := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "database"},
+		Data: map[string][]byte{
+			"url":  []byte("postgres://localhost"),
+			"user": []byte("k4indie"),
+		},
+	}
+	logging := corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "logging"},
+		Data: map[string]string{
+			"level": "info",
+		},
+	}
+	rotatedDatabase := *database.DeepCopy()
+	rotatedDatabase.Data["url"] = []byte("postgres://remote")
+
+	tests := []struct {
+		name      string
+		a         func() string
+		b         func() string
+		wantEqual bool
+	}{
+		{
+			name: "same content has same hash",
+			a: func() string {
+				return HashConfigData([]corev1.Secret{database}, []corev1.ConfigMap{logging})
+			},
+			b: func() string {
+				return HashConfigData([]corev1.Secret{*database.DeepCopy()}, []corev1.ConfigMap{*logging.DeepCopy()})
+			},
+			wantEqual: true,
+		},
+		{
+			name: "changed secret value changes hash",
+			a: func() string {
+				return HashConfigData([]corev1.Secret{database}, []corev1.ConfigMap{logging})
+			},
+			b: func() string {
+				return HashConfigData([]corev1.Secret{rotatedDatabase}, []corev1.ConfigMap{logging})
+			},
+			wantEqual: false,
+		},
+		{
+			name: "object kind is part of the hash",
+			a: func() string {
+				return HashConfigData(nil, []corev1.ConfigMap{{
+					ObjectMeta: metav1.ObjectMeta{Name: "database"},
+					Data:       map[string]string{"url": "postgres://localhost", "user": "k4indie"},
+				}})
+			},
+			b: func() string {
+				return HashConfigData([]corev1.Secret{database}, nil)
+			},
+			wantEqual: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := tt.a(), tt.b()
+			if (a == b) != tt.wantEqual {
+				t.Errorf("HashConfigData() equal = %v, want %v (%s, %s)", a == b, tt.wantEqual, a, b)
+			}
+		})
+	}
+}
+
+func TestMergeEnvVars(t *testing.T) {
+	addonURL := corev1.EnvVar{
+		Name: "DATABASE_URL",
+		ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "db-credentials"},
+			Key:                  "url",
+		}},
+	}
+
+	tests := []struct {
+		name  string
+		lists [][]corev1.EnvVar
+		want  []corev1.EnvVar
+	}{
+		{
+			name:  "no variables",
+			lists: [][]corev1.EnvVar{nil, nil},
+			want:  []corev1.EnvVar{},
+		},
+		{
+			name: "keeps the order of distinct names",
+			lists: [][]corev1.EnvVar{
+				{addonURL},
+				{{Name: "PORT", Value: "8080"}},
+			},
+			want: []corev1.EnvVar{addonURL, {Name: "PORT", Value: "8080"}},
+		},
+		{
+			name: "config var overrides the addon URL with the same name",
+			lists: [][]corev1.EnvVar{
+				{addonURL, {Name: "REDIS_URL", Value: "redis://cache"}},
+				{{Name: "DATABASE_URL", Value: "postgres://external"}, {Name: "PORT", Value: "8080"}},
+			},
+			want: []corev1.EnvVar{
+				{Name: "DATABASE_URL", Value: "postgres://external"},
+				{Name: "REDIS_URL", Value: "redis://cache"},
+				{Name: "PORT", Value: "8080"},
+			},
+		},
+		{
+			name: "config var overrides the bound variable with the same name",
+			lists: [][]corev1.EnvVar{
+				nil,
+				BuildBindingEnvVars(
+					v1alpha1.ApplicationBinding{Name: "api", Application: "api"},
+					BindingEndpoint{Host: "api-web.shop.svc", Port: 8080},
+				),
+				{{Name: "API_URL", Value: "https://api.example.com"}},
+			},
+			want: []corev1.EnvVar{
+				{Name: "API_URL", Value: "https://api.example.com"},
+				{Name: "API_HOST", Value: "api-web.shop.svc"},
+				{Name: "API_PORT", Value: "8080"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MergeEnvVars(tt.lists...); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MergeEnvVars() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package resolvers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ApplicationNamespaceLabel is set on cluster scoped resources to the
+// namespace of the application they belong to.
+const ApplicationNamespaceLabel = "k4indie.io/application-namespace"
+
+// DomainRoute is a path prefix of a domain routed to an application.
+type DomainRoute struct {
+	Domain string
+	Path   string
+}
+
+func (r DomainRoute) String() string {
+	return r.Domain + r.Path
+}
+
+// ResolveDomainRoutes returns the unique domains and paths of the endpoints
+// of the processes, normalized and sorted.
+func ResolveDomainRoutes(processes []Process) []DomainRoute {
+	routes := []DomainRoute{}
+	seen := map[DomainRoute]struct{}{}
+
+	for _, process := range processes {
+		for _, endpoint := range EndpointsWithDomains(&process.Endpoints) {
+			route := DomainRoute{
+				Domain: NormalizeDomain(endpoint.Domain),
+				Path:   routePath(endpoint.DomainPath),
+			}
+			if _, exists := seen[route]; exists {
+				continue
+			}
+			seen[route] = struct{}{}
+			routes = append(routes, route)
+		}
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Domain != routes[j].Domain {
+			return routes[i].Domain < routes[j].Domain
+		}
+		return routes[i].Path < routes[j].Path
+	})
+
+	return routes
+}
+
+// domainHashLength is the length of the hash of the domain suffixed to the
+// names of the objects generated for a domain.
+const domainHashLength = 8
+
+// NormalizeDomain returns the canonical form of a domain, which is case
+// insensitive and may be written with a trailing dot.
+func NormalizeDomain(domain string) string {
+	return strings.ToLower(strings.TrimSuffix(domain, "."))
+}
+
+// domainObjectName returns the name of an object generated for a domain,
+// made of the prefix, a readable form of the domain and a hash of the
+// domain and of the extra values identifying the object. The readable form
+// alone is ambiguous, e.g. for a-b.com and a.b.com, or for *.b.com and
+// wildcard.b.com.
+func domainObjectName(prefix, domain, suffix string, extra ...string) string {
+	domain = NormalizeDomain(domain)
+	h := sha256.New()
+	h.Write([]byte(domain))
+	for _, value := range extra {
+		h.Write([]byte{0})
+		h.Write([]byte(value))
+	}
+	hash := hex.EncodeToString(h.Sum(nil))[:domainHashLength]
+
+	readable := strings.ReplaceAll(domain, "*", "wildcard")
+	readable = strings.ReplaceAll(readable, ".", "-")
+	if prefix != "" {
+		readable = prefix + "-" + readable
+	}
+	maxLength := validation.DNS1123SubdomainMaxLength - len(suffix) - domainHashLength - 1
+	if len(readable) > maxLength {
+		readable = strings.TrimRight(readable[:maxLength], "-")
+	}
+
+	return fmt.Sprintf("%s-%s%s", readable, hash, suffix)
+}
+
+// DomainClaimName returns the name of the DomainClaim of a path of a
+// domain.
+func DomainClaimName(route DomainRoute) string {
+	return domainObjectName("", route.Domain, "", route.Path)
+}
+
+// ClaimedDomainRoute returns the normalized path of the domain claimed by
+// the claim.
+func ClaimedDomainRoute(claim *v1alpha1.DomainClaim) DomainRoute {
+	return DomainRoute{
+		Domain: NormalizeDomain(claim.Spec.Domain),
+		Path:   routePath(claim.Spec.Path),
+	}
+}
+
+// DomainOwner returns the claim owning the domain, which is the oldest
+// claim of any path of the domain, or nil when the domain is not claimed.
+// The applications of other namespaces may only claim paths of a domain
+// owned by a namespace when their namespace is granted the domain.
+func DomainOwner(claims []v1alpha1.DomainClaim, domain string) *v1alpha1.DomainClaim {
+	domain = NormalizeDomain(domain)
+
+	var owner *v1alpha1.DomainClaim
+	for i := range claims {
+		claim := &claims[i]
+		if NormalizeDomain(claim.Spec.Domain) != domain {
+			continue
+		}
+		if owner == nil || claim.CreationTimestamp.Before(&owner.CreationTimestamp) ||
+			(claim.CreationTimestamp.Equal(&owner.CreationTimestamp) && claim.Name < owner.Name) {
+			owner = claim
+		}
+	}
+
+	return owner
+}
+
+// IsDomainGranted checks if the domain itself is one of the allowed
+// domains, which grants it to the namespace even when it is owned by
+// another namespace. Wildcards don't grant the domains they match.
+func IsDomainGranted(domain string, allowed []string) bool {
+	domain = NormalizeDomain(domain)
+	for _, allowedDomain := range allowed {
+		if NormalizeDomain(allowedDomain) == domain {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseAllowedDomains parses the value of the allowed domains annotation of
+// a namespace.
+func ParseAllowedDomains(annotation string) []string {
+	allowed := []string{}
+
+	for _, domain := range strings.Split(annotation, ",") {
+		domain = strings.TrimSpace(domain)
+		if domain != "" {
+			allowed = append(allowed, domain)
+		}
+	}
+
+	return allowed
+}
+
+// IsDomainAllowed checks if the domain matches one of the allowed domains.
+// An allowed wildcard domain, e.g. *.example.com, matches all the
+// subdomains of example.com, including the wildcard domain itself.
+func IsDomainAllowed(domain string, allowed []string) bool {
+	domain = NormalizeDomain(domain)
+	for _, allowedDomain := range allowed {
+		allowedDomain = NormalizeDomain(allowedDomain)
+		if domain == allowedDomain {
+			return true
+		}
+		if strings.HasPrefix(allowedDomain, "*.") &&
+			strings.HasSuffix(domain, allowedDomain[1:]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RemoveEndpointRoutes removes the domains of the endpoints of the
+// application and its processes routing the given routes, so they are not
+// routed. The endpoints themselves are kept, since they are still exposed
+// inside the cluster.
+func RemoveEndpointRoutes(app *v1alpha1.Application, routes []DomainRoute) {
+	if len(routes) == 0 {
+		return
+	}
+
+	removed := map[DomainRoute]struct{}{}
+	for _, route := range routes {
+		removed[route] = struct{}{}
+	}
+
+	removeDomains := func(endpoints v1alpha1.ApplicationEndpoints) {
+		for i := range endpoints {
+			route := DomainRoute{
+				Domain: NormalizeDomain(endpoints[i].Domain),
+				Path:   routePath(endpoints[i].DomainPath),
+			}
+			if _, exists := removed[route]; exists {
+				endpoints[i].Domain = ""
+			}
+		}
+	}
+
+	removeDomains(app.Spec.Endpoints)
+	for _, process := range app.Spec.Processes {
+		removeDomains(process.Endpoints)
+	}
+}
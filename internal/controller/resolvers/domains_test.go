@@ -0,0 +1,233 @@
+package resolvers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestResolveDomainRoutes(t *testing.T) {
+	tests := []struct {
+		name      string
+		processes []Process
+		want      []DomainRoute
+	}{
+		{
+			name:      "no processes",
+			processes: []Process{},
+			want:      []DomainRoute{},
+		},
+		{
+			name: "unique sorted normalized routes",
+			processes: []Process{
+				{Name: "web", Endpoints: v1alpha1.ApplicationEndpoints{
+					{Port: 8080, Domain: "Shop.Example.com", DomainPath: "/"},
+					{Port: 9090},
+				}},
+				{Name: "api", Endpoints: v1alpha1.ApplicationEndpoints{
+					{Port: 3000, Domain: "shop.example.com", DomainPath: "/api"},
+					{Port: 3000, Domain: "shop.example.com."},
+					{Port: 3000, Domain: "api.example.com", DomainPath: "/"},
+				}},
+			},
+			want: []DomainRoute{
+				{Domain: "api.example.com", Path: "/"},
+				{Domain: "shop.example.com", Path: "/"},
+				{Domain: "shop.example.com", Path: "/api"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResolveDomainRoutes(tt.processes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolveDomainRoutes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDomainClaimName(t *testing.T) {
+	root := DomainRoute{Domain: "shop.example.com", Path: "/"}
+	tests := []struct {
+		name  string
+		route DomainRoute
+		equal bool
+	}{
+		{name: "same route", route: DomainRoute{Domain: "shop.example.com", Path: "/"}, equal: true},
+		{name: "case insensitive", route: DomainRoute{Domain: "Shop.Example.com", Path: "/"}, equal: true},
+		{name: "other path", route: DomainRoute{Domain: "shop.example.com", Path: "/api"}, equal: false},
+		{name: "ambiguous readable form", route: DomainRoute{Domain: "shop-example.com", Path: "/"}, equal: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DomainClaimName(tt.route)
+			if errs := validation.IsDNS1123Subdomain(got); len(errs) > 0 {
+				t.Errorf("DomainClaimName() = %v is invalid: %v", got, errs)
+			}
+			if equal := got == DomainClaimName(root); equal != tt.equal {
+				t.Errorf("DomainClaimName() = %v, equal to %v = %v, want %v", got, DomainClaimName(root), equal, tt.equal)
+			}
+		})
+	}
+
+	wildcard := DomainClaimName(DomainRoute{Domain: "*.example.com", Path: "/"})
+	literal := DomainClaimName(DomainRoute{Domain: "wildcard.example.com", Path: "/"})
+	if wildcard == literal {
+		t.Errorf("DomainClaimName() of *.example.com and wildcard.example.com are both %v", wildcard)
+	}
+	if want := "shop-example-com-"; !strings.HasPrefix(DomainClaimName(root), want) {
+		t.Errorf("DomainClaimName() = %v, want prefix %v", DomainClaimName(root), want)
+	}
+}
+
+func TestClaimedDomainRoute(t *testing.T) {
+	claim := &v1alpha1.DomainClaim{Spec: v1alpha1.DomainClaimSpec{Domain: "Shop.Example.com"}}
+	want := DomainRoute{Domain: "shop.example.com", Path: "/"}
+	if got := ClaimedDomainRoute(claim); got != want {
+		t.Errorf("ClaimedDomainRoute() = %v, want %v", got, want)
+	}
+}
+
+func TestDomainOwner(t *testing.T) {
+	claim := func(domain, path, namespace string, created time.Time) v1alpha1.DomainClaim {
+		route := DomainRoute{Domain: domain, Path: path}
+		return v1alpha1.DomainClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: DomainClaimName(route), CreationTimestamp: metav1.NewTime(created)},
+			Spec: v1alpha1.DomainClaimSpec{
+				Domain:         domain,
+				Path:           path,
+				ApplicationRef: v1alpha1.ApplicationReference{Namespace: namespace, Name: "web"},
+			},
+		}
+	}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	shop := claim("example.com", "/", "shop", now)
+	// app B of another namespace claimed a sub-path of the domain after
+	// app A, e.g. racing it or before domains were owned as a whole.
+	hijack := claim("example.com", "/api", "attacker", now.Add(time.Minute))
+	other := claim("other.example.com", "/", "attacker", now.Add(-time.Minute))
+
+	tests := []struct {
+		name   string
+		claims []v1alpha1.DomainClaim
+		domain string
+		want   *v1alpha1.DomainClaim
+	}{
+		{name: "unclaimed domain", claims: []v1alpha1.DomainClaim{other}, domain: "example.com", want: nil},
+		{name: "sub-path claimed after the domain", claims: []v1alpha1.DomainClaim{hijack, shop, other}, domain: "example.com", want: &shop},
+		{name: "case insensitive", claims: []v1alpha1.DomainClaim{hijack, shop}, domain: "Example.com.", want: &shop},
+		{name: "only claim of the domain", claims: []v1alpha1.DomainClaim{hijack, other}, domain: "example.com", want: &hijack},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DomainOwner(tt.claims, tt.domain)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DomainOwner() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsDomainGranted(t *testing.T) {
+	allowed := []string{"shop.example.com", "*.example.org"}
+
+	tests := []struct {
+		domain string
+		want   bool
+	}{
+		{domain: "shop.example.com", want: true},
+		{domain: "Shop.Example.com.", want: true},
+		{domain: "api.example.org", want: false},
+		{domain: "*.example.org", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			if got := IsDomainGranted(tt.domain, allowed); got != tt.want {
+				t.Errorf("IsDomainGranted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAllowedDomains(t *testing.T) {
+	tests := []struct {
+		name       string
+		annotation string
+		want       []string
+	}{
+		{name: "empty", annotation: "", want: []string{}},
+		{
+			name:       "trims spaces and empty entries",
+			annotation: " shop.example.com, *.example.org,,",
+			want:       []string{"shop.example.com", "*.example.org"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseAllowedDomains(tt.annotation); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseAllowedDomains() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsDomainAllowed(t *testing.T) {
+	allowed := []string{"shop.example.com", "*.example.org"}
+
+	tests := []struct {
+		domain string
+		want   bool
+	}{
+		{domain: "shop.example.com", want: true},
+		{domain: "api.example.com", want: false},
+		{domain: "api.example.org", want: true},
+		{domain: "v1.api.example.org", want: true},
+		{domain: "*.example.org", want: true},
+		{domain: "example.org", want: false},
+		{domain: "badexample.org", want: false},
+		{domain: "Shop.Example.com.", want: true},
+		{domain: "API.example.org", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			if got := IsDomainAllowed(tt.domain, allowed); got != tt.want {
+				t.Errorf("IsDomainAllowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoveEndpointRoutes(t *testing.T) {
+	app := &v1alpha1.Application{
+		Spec: v1alpha1.ApplicationSpec{
+			Endpoints: v1alpha1.ApplicationEndpoints{
+				{Port: 8080, Domain: "Shop.Example.com"},
+				{Port: 8080, Domain: "www.example.com"},
+			},
+			Processes: map[string]v1alpha1.ApplicationProcess{
+				"api": {Endpoints: v1alpha1.ApplicationEndpoints{
+					{Port: 3000, Domain: "shop.example.com", DomainPath: "/api"},
+				}},
+			},
+		},
+	}
+
+	RemoveEndpointRoutes(app, []DomainRoute{{Domain: "shop.example.com", Path: "/"}})
+
+	wantEndpoints := v1alpha1.ApplicationEndpoints{
+		{Port: 8080},
+		{Port: 8080, Domain: "www.example.com"},
+	}
+	if !reflect.DeepEqual(app.Spec.Endpoints, wantEndpoints) {
+		t.Errorf("RemoveEndpointRoutes() endpoints = %v, want %v", app.Spec.Endpoints, wantEndpoints)
+	}
+	wantProcessEndpoints := v1alpha1.ApplicationEndpoints{{Port: 3000, Domain: "shop.example.com", DomainPath: "/api"}}
+	if got := app.Spec.Processes["api"].Endpoints; !reflect.DeepEqual(got, wantProcessEndpoints) {
+		t.Errorf("RemoveEndpointRoutes() process endpoints = %v, want %v", got, wantProcessEndpoints)
+	}
+}
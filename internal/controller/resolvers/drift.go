@@ -0,0 +1,55 @@
+package resolvers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RenderedHashAnnotation is set on generated resources to the hash of their
+// rendered state. A resource whose content no longer matches its rendered
+// state while the hash is unchanged was modified outside of the operator.
+const RenderedHashAnnotation = "k4indie.io/rendered-hash"
+
+// HashRendered returns a hash of the rendered labels, annotations and spec
+// of a generated resource.
+func HashRendered(labels, annotations map[string]string, spec interface{}) (string, error) {
+	// maps are marshalled with sorted keys, so the encoding is stable.
+	encoded, err := json.Marshal(spec)
+	if err != nil {
+		return "", err
+	}
+	encodedLabels, err := json.Marshal(labels)
+	if err != nil {
+		return "", err
+	}
+	encodedAnnotations, err := json.Marshal(annotations)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	writeHashEntry(h, "labels", string(encodedLabels))
+	writeHashEntry(h, "annotations", string(encodedAnnotations))
+	writeHashEntry(h, "spec", string(encoded))
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// IsDrifted reports whether the existing resource was modified outside of
+// the operator: it was rendered from the same state as the desired resource,
+// but no longer holds the desired labels, annotations or spec. Fields that
+// are not set on the desired resource, e.g. defaults, are ignored.
+func IsDrifted(desired, existing metav1.Object, desiredSpec, existingSpec interface{}) bool {
+	renderedHash := desired.GetAnnotations()[RenderedHashAnnotation]
+	if existing.GetAnnotations()[RenderedHashAnnotation] != renderedHash {
+		return false
+	}
+
+	return !equality.Semantic.DeepDerivative(desired.GetLabels(), existing.GetLabels()) ||
+		!equality.Semantic.DeepDerivative(desired.GetAnnotations(), existing.GetAnnotations()) ||
+		!equality.Semantic.DeepDerivative(desiredSpec, existingSpec)
+}
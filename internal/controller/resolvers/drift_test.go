@@ -0,0 +1,120 @@
+package resolvers
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHashRendered(t *testing.T) {
+	spec := corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 80}}}
+	base, err := HashRendered(map[string]string{"a": "1"}, nil, spec)
+	if err != nil {
+		t.Fatalf("HashRendered() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		labels      map[string]string
+		annotations map[string]string
+		spec        corev1.ServiceSpec
+		wantEqual   bool
+	}{
+		{
+			name:      "same resource",
+			labels:    map[string]string{"a": "1"},
+			spec:      corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 80}}},
+			wantEqual: true,
+		},
+		{
+			name:      "new label",
+			labels:    map[string]string{"a": "2"},
+			spec:      corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 80}}},
+			wantEqual: false,
+		},
+		{
+			name:        "new annotation",
+			labels:      map[string]string{"a": "1"},
+			annotations: map[string]string{"b": "1"},
+			spec:        corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 80}}},
+			wantEqual:   false,
+		},
+		{
+			name:      "new spec",
+			labels:    map[string]string{"a": "1"},
+			spec:      corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 8080}}},
+			wantEqual: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HashRendered(tt.labels, tt.annotations, tt.spec)
+			if err != nil {
+				t.Fatalf("HashRendered() error = %v", err)
+			}
+			if (got == base) != tt.wantEqual {
+				t.Errorf("HashRendered() = %v, base %v, wantEqual %v", got, base, tt.wantEqual)
+			}
+		})
+	}
+}
+
+func TestIsDrifted(t *testing.T) {
+	service := func(hash string, port int32, clusterIP string) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels:      map[string]string{InstanceLabel: "shop"},
+				Annotations: map[string]string{RenderedHashAnnotation: hash},
+			},
+			Spec: corev1.ServiceSpec{
+				ClusterIP: clusterIP,
+				Ports:     []corev1.ServicePort{{Port: port}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		desired  *corev1.Service
+		existing *corev1.Service
+		want     bool
+	}{
+		{
+			name:     "should not be drifted when unchanged",
+			desired:  service("v1", 80, ""),
+			existing: service("v1", 80, "10.0.0.1"),
+			want:     false,
+		},
+		{
+			name:     "should be drifted when the spec was edited",
+			desired:  service("v1", 80, ""),
+			existing: service("v1", 8080, "10.0.0.1"),
+			want:     true,
+		},
+		{
+			name:    "should be drifted when a label was edited",
+			desired: service("v1", 80, ""),
+			existing: func() *corev1.Service {
+				s := service("v1", 80, "10.0.0.1")
+				s.Labels[InstanceLabel] = "other"
+				return s
+			}(),
+			want: true,
+		},
+		{
+			name:     "should not be drifted when the application changed",
+			desired:  service("v2", 8080, ""),
+			existing: service("v1", 80, "10.0.0.1"),
+			want:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := IsDrifted(tt.desired, tt.existing, tt.desired.Spec, tt.existing.Spec)
+			if got != tt.want {
+				t.Errorf("IsDrifted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
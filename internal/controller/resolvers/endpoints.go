@@ -1,6 +1,9 @@
 package resolvers
 
 import (
+	"fmt"
+	"sort"
+
 	"github.com/perfectmak/k4indie/api/v1alpha1"
 	networkingv1 "k8s.io/api/networking/v1"
 )
@@ -17,32 +20,56 @@ func EndpointsWithDomains(endpoints *v1alpha1.ApplicationEndpoints) []v1alpha1.A
 	return result
 }
 
-func BuildIngressRules(appName string, endpoints []v1alpha1.ApplicationEndpoint) []networkingv1.IngressRule {
-	rules := make([]networkingv1.IngressRule, 0, len(endpoints))
+// ApplicationURL returns the URL of the first domain endpoint of the
+// processes, or an empty string when no endpoint has a domain. The URL uses
+// https when TLS is enabled for the endpoint.
+func ApplicationURL(appTLS *v1alpha1.EndpointTLS, processes []Process) string {
+	for _, process := range processes {
+		for _, endpoint := range EndpointsWithDomains(&process.Endpoints) {
+			tls := endpoint.TLS
+			if tls == nil {
+				tls = appTLS
+			}
 
-	// group by domains
-	groups := map[string][]v1alpha1.ApplicationEndpoint{}
-	for _, endpoint := range endpoints {
-		if _, exists := groups[endpoint.Domain]; !exists {
-			groups[endpoint.Domain] = make([]v1alpha1.ApplicationEndpoint, 0, 5)
-		}
+			scheme := "http"
+			if tls != nil && !tls.Disabled {
+				scheme = "https"
+			}
 
-		groups[endpoint.Domain] = append(groups[endpoint.Domain], endpoint)
+			path := endpoint.DomainPath
+			if path == "/" {
+				path = ""
+			}
+
+			return fmt.Sprintf("%s://%s%s", scheme, endpoint.Domain, path)
+		}
 	}
 
-	// generate rules
-	for domain, groupEndpoints := range groups {
-		paths := make([]networkingv1.HTTPIngressPath, 0, len(groupEndpoints))
+	return ""
+}
+
+// BuildIngressRules builds the ingress rules for the domain endpoints of
+// the given processes, routing each endpoint to its process Service.
+// Rules are sorted by domain so the generated ingress is stable.
+func BuildIngressRules(processes []Process) []networkingv1.IngressRule {
+	// group by domains
+	groups := map[string][]networkingv1.HTTPIngressPath{}
+	domains := make([]string, 0, len(processes))
+	for _, process := range processes {
+		for _, endpoint := range EndpointsWithDomains(&process.Endpoints) {
+			if _, exists := groups[endpoint.Domain]; !exists {
+				groups[endpoint.Domain] = make([]networkingv1.HTTPIngressPath, 0, 5)
+				domains = append(domains, endpoint.Domain)
+			}
 
-		for _, endpoint := range groupEndpoints {
-			paths = append(paths, networkingv1.HTTPIngressPath{
+			groups[endpoint.Domain] = append(groups[endpoint.Domain], networkingv1.HTTPIngressPath{
 				Path: endpoint.DomainPath,
 				PathType: &[]networkingv1.PathType{
 					networkingv1.PathTypePrefix,
 				}[0],
 				Backend: networkingv1.IngressBackend{
 					Service: &networkingv1.IngressServiceBackend{
-						Name: appName,
+						Name: process.ResourceName,
 						Port: networkingv1.ServiceBackendPort{
 							Number: endpoint.Port,
 						},
@@ -50,12 +77,17 @@ func BuildIngressRules(appName string, endpoints []v1alpha1.ApplicationEndpoint)
 				},
 			})
 		}
+	}
+	sort.Strings(domains)
 
+	// generate rules
+	rules := make([]networkingv1.IngressRule, 0, len(domains))
+	for _, domain := range domains {
 		domainRules := networkingv1.IngressRule{
 			Host: domain,
 			IngressRuleValue: networkingv1.IngressRuleValue{
 				HTTP: &networkingv1.HTTPIngressRuleValue{
-					Paths: paths,
+					Paths: groups[domain],
 				},
 			},
 		}
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/perfectmak/k4indie/api/v1alpha1"
+	networkingv1 "k8s.io/api/networking/v1"
 )
 
 func TestEndpointsWithDomains(t *testing.T) {
@@ -65,3 +66,149 @@ func TestEndpointsWithDomains(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildIngressRules(t *testing.T) {
+	pathType := networkingv1.PathTypePrefix
+	backend := func(service string, port int32) networkingv1.IngressBackend {
+		return networkingv1.IngressBackend{
+			Service: &networkingv1.IngressServiceBackend{
+				Name: service,
+				Port: networkingv1.ServiceBackendPort{Number: port},
+			},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		processes []Process
+		want      []networkingv1.IngressRule
+	}{
+		{
+			name:      "should return no rules without processes",
+			processes: []Process{},
+			want:      []networkingv1.IngressRule{},
+		},
+		{
+			name: "should route domains to process services",
+			processes: []Process{
+				{
+					Name:         "web",
+					ResourceName: "shop-web",
+					Endpoints: v1alpha1.ApplicationEndpoints{
+						{Port: 8080, Domain: "shop.example.com", DomainPath: "/"},
+						{Port: 9090},
+					},
+				},
+				{
+					Name:         "api",
+					ResourceName: "shop-api",
+					Endpoints: v1alpha1.ApplicationEndpoints{
+						{Port: 3000, Domain: "shop.example.com", DomainPath: "/api"},
+						{Port: 3000, Domain: "api.example.com", DomainPath: "/"},
+					},
+				},
+				{
+					Name:         "worker",
+					ResourceName: "shop-worker",
+				},
+			},
+			want: []networkingv1.IngressRule{
+				{
+					Host: "api.example.com",
+					IngressRuleValue: networkingv1.IngressRuleValue{
+						HTTP: &networkingv1.HTTPIngressRuleValue{
+							Paths: []networkingv1.HTTPIngressPath{
+								{Path: "/", PathType: &pathType, Backend: backend("shop-api", 3000)},
+							},
+						},
+					},
+				},
+				{
+					Host: "shop.example.com",
+					IngressRuleValue: networkingv1.IngressRuleValue{
+						HTTP: &networkingv1.HTTPIngressRuleValue{
+							Paths: []networkingv1.HTTPIngressPath{
+								{Path: "/", PathType: &pathType, Backend: backend("shop-web", 8080)},
+								{Path: "/api", PathType: &pathType, Backend: backend("shop-api", 3000)},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BuildIngressRules(tt.processes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BuildIngressRules() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplicationURL(t *testing.T) {
+	type args struct {
+		appTLS    *v1alpha1.EndpointTLS
+		processes []Process
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			name: "should return empty url without domains",
+			args: args{
+				processes: []Process{
+					{Name: "worker", Endpoints: v1alpha1.ApplicationEndpoints{{Port: 8080}}},
+				},
+			},
+			want: "",
+		},
+		{
+			name: "should use http without tls",
+			args: args{
+				processes: []Process{
+					{Name: "web", Endpoints: v1alpha1.ApplicationEndpoints{
+						{Port: 8080, Domain: "shop.example.com", DomainPath: "/"},
+					}},
+				},
+			},
+			want: "http://shop.example.com",
+		},
+		{
+			name: "should use https with application tls and keep the path",
+			args: args{
+				appTLS: &v1alpha1.EndpointTLS{Issuer: "letsencrypt"},
+				processes: []Process{
+					{Name: "api", Endpoints: v1alpha1.ApplicationEndpoints{
+						{Port: 3000, Domain: "shop.example.com", DomainPath: "/api"},
+					}},
+					{Name: "web", Endpoints: v1alpha1.ApplicationEndpoints{
+						{Port: 8080, Domain: "www.example.com", DomainPath: "/"},
+					}},
+				},
+			},
+			want: "https://shop.example.com/api",
+		},
+		{
+			name: "should use http when tls is disabled on the endpoint",
+			args: args{
+				appTLS: &v1alpha1.EndpointTLS{Issuer: "letsencrypt"},
+				processes: []Process{
+					{Name: "web", Endpoints: v1alpha1.ApplicationEndpoints{
+						{Port: 8080, Domain: "shop.example.com", TLS: &v1alpha1.EndpointTLS{Disabled: true}},
+					}},
+				},
+			},
+			want: "http://shop.example.com",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ApplicationURL(tt.args.appTLS, tt.args.processes); got != tt.want {
+				t.Errorf("ApplicationURL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,5 +1,11 @@
 package resolvers
 
+import (
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
 func MergeDefaultLabels(labelsList ...map[string]string) map[string]string {
 	result := map[string]string{}
 	defaultLabels := map[string]string{
@@ -16,3 +22,53 @@ func MergeDefaultLabels(labelsList ...map[string]string) map[string]string {
 
 	return result
 }
+
+const (
+	InstanceLabel = "app.kubernetes.io/instance"
+	VersionLabel  = "app.kubernetes.io/version"
+	ProcessLabel  = "k4indie.io/process"
+	// ScheduleLabel is set instead of ProcessLabel on the pods of scheduled
+	// tasks, so they are never selected by the process workloads.
+	ScheduleLabel = "k4indie.io/schedule"
+	// AddonLabel is set instead of InstanceLabel on the resources of an
+	// addon, so they are never mistaken for the resources of an application
+	// with the same name.
+	AddonLabel = "k4indie.io/addon"
+	// RestoreSourceLabel is set on the job restoring a backup from the backup
+	// volume of an addon, to the name of that addon.
+	RestoreSourceLabel = "k4indie.io/restore-from"
+)
+
+// SelectorLabels returns the labels selecting the pods of an application
+// process. They only contain values that never change for the process, so
+// they are safe to use on immutable selectors.
+func SelectorLabels(appName, processName string) map[string]string {
+	return MergeDefaultLabels(map[string]string{
+		InstanceLabel: appName,
+		ProcessLabel:  processName,
+	})
+}
+
+// ScaleSelector returns the label selector of the pods counted in the
+// replicas of the application status. It selects the default process pods
+// for applications without processes, and all the process pods otherwise,
+// leaving out the pods of the release phase and of scheduled tasks.
+func ScaleSelector(app *v1alpha1.Application) string {
+	if len(app.Spec.Processes) == 0 {
+		return labels.SelectorFromSet(SelectorLabels(app.Name, DefaultProcessName)).String()
+	}
+
+	processNames := make([]string, 0, len(app.Spec.Processes))
+	for name := range app.Spec.Processes {
+		processNames = append(processNames, name)
+	}
+	selector := labels.SelectorFromSet(MergeDefaultLabels(map[string]string{InstanceLabel: app.Name}))
+	requirement, err := labels.NewRequirement(ProcessLabel, selection.In, processNames)
+	if err != nil {
+		// A process name that is not a valid label value can't be set on
+		// the pods of the process either.
+		return selector.String()
+	}
+
+	return selector.Add(*requirement).String()
+}
@@ -3,6 +3,9 @@ package resolvers
 import (
 	"reflect"
 	"testing"
+
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestMergeDefaultLabels(t *testing.T) {
@@ -38,3 +41,74 @@ func TestMergeDefaultLabels(t *testing.T) {
 		})
 	}
 }
+
+func TestSelectorLabels(t *testing.T) {
+	type args struct {
+		appName     string
+		processName string
+	}
+	tests := []struct {
+		name string
+		args args
+		want map[string]string
+	}{
+		{
+			name: "should include instance and process",
+			args: args{
+				appName:     "shop",
+				processName: "worker",
+			},
+			want: map[string]string{
+				"app.kubernetes.io/instance":   "shop",
+				"k4indie.io/process":           "worker",
+				"app.kubernetes.io/part-of":    "k4indie-operator",
+				"app.kubernetes.io/created-by": "controller-manager",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SelectorLabels(tt.args.appName, tt.args.processName); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SelectorLabels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScaleSelector(t *testing.T) {
+	tests := []struct {
+		name string
+		app  *v1alpha1.Application
+		want string
+	}{
+		{
+			name: "should select the default process without processes",
+			app: &v1alpha1.Application{
+				ObjectMeta: metav1.ObjectMeta{Name: "shop"},
+			},
+			want: "app.kubernetes.io/created-by=controller-manager,app.kubernetes.io/instance=shop," +
+				"app.kubernetes.io/part-of=k4indie-operator,k4indie.io/process=web",
+		},
+		{
+			name: "should select all processes",
+			app: &v1alpha1.Application{
+				ObjectMeta: metav1.ObjectMeta{Name: "shop"},
+				Spec: v1alpha1.ApplicationSpec{
+					Processes: map[string]v1alpha1.ApplicationProcess{
+						"web":    {},
+						"worker": {},
+					},
+				},
+			},
+			want: "app.kubernetes.io/created-by=controller-manager,app.kubernetes.io/instance=shop," +
+				"app.kubernetes.io/part-of=k4indie-operator,k4indie.io/process in (web,worker)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ScaleSelector(tt.app); got != tt.want {
+				t.Errorf("ScaleSelector() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
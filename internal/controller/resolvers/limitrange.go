@@ -0,0 +1,82 @@
+package resolvers
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+var ErrResourcesNotAllowed = errors.New("resources not allowed")
+
+// ValidateLimitRanges checks that the resources of a container stay within
+// the container limits of the given LimitRanges: the min and max of each
+// resource, and the max ratio between its limit and its request.
+func ValidateLimitRanges(resources corev1.ResourceRequirements, limitRanges []corev1.LimitRange) error {
+	violations := []string{}
+
+	for _, limitRange := range limitRanges {
+		for _, item := range limitRange.Spec.Limits {
+			if item.Type != corev1.LimitTypeContainer {
+				continue
+			}
+
+			for _, name := range sortedNames(item.Min) {
+				min := item.Min[name]
+				if request, exists := resources.Requests[name]; exists && request.Cmp(min) < 0 {
+					violations = append(violations, fmt.Sprintf(
+						"%s request %s is below the minimum %s of %s",
+						name, request.String(), min.String(), limitRange.Name,
+					))
+				}
+			}
+
+			for _, name := range sortedNames(item.Max) {
+				max := item.Max[name]
+				if limit, exists := resources.Limits[name]; exists && limit.Cmp(max) > 0 {
+					violations = append(violations, fmt.Sprintf(
+						"%s limit %s is above the maximum %s of %s",
+						name, limit.String(), max.String(), limitRange.Name,
+					))
+				}
+			}
+
+			for _, name := range sortedNames(item.MaxLimitRequestRatio) {
+				maxRatio := item.MaxLimitRequestRatio[name]
+				limit, hasLimit := resources.Limits[name]
+				request, hasRequest := resources.Requests[name]
+				if !hasLimit || !hasRequest || request.IsZero() {
+					continue
+				}
+
+				ratio := float64(limit.MilliValue()) / float64(request.MilliValue())
+				if ratio > maxRatio.AsApproximateFloat64() {
+					violations = append(violations, fmt.Sprintf(
+						"%s limit to request ratio %.2f is above the maximum %s of %s",
+						name, ratio, maxRatio.String(), limitRange.Name,
+					))
+				}
+			}
+		}
+	}
+
+	if len(violations) > 0 {
+		return fmt.Errorf("%w: %s", ErrResourcesNotAllowed, strings.Join(violations, ", "))
+	}
+
+	return nil
+}
+
+func sortedNames(resources corev1.ResourceList) []corev1.ResourceName {
+	names := make([]corev1.ResourceName, 0, len(resources))
+	for name := range resources {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return names[i] < names[j]
+	})
+
+	return names
+}
@@ -0,0 +1,79 @@
+package resolvers
+
+import (
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateLimitRanges(t *testing.T) {
+	limitRanges := []corev1.LimitRange{{
+		ObjectMeta: metav1.ObjectMeta{Name: "limits"},
+		Spec: corev1.LimitRangeSpec{
+			Limits: []corev1.LimitRangeItem{
+				{
+					Type: corev1.LimitTypePod,
+					Max:  corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("128Mi")},
+				},
+				{
+					Type:                 corev1.LimitTypeContainer,
+					Min:                  corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+					Max:                  corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+					MaxLimitRequestRatio: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4")},
+				},
+			},
+		},
+	}}
+
+	tests := []struct {
+		name      string
+		resources corev1.ResourceRequirements
+		wantErr   error
+	}{
+		{
+			name: "should allow resources within limits",
+			resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("250m"),
+					corev1.ResourceMemory: resource.MustParse("512Mi"),
+				},
+				Limits: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("1"),
+					corev1.ResourceMemory: resource.MustParse("512Mi"),
+				},
+			},
+		},
+		{
+			name: "should reject requests below the minimum",
+			resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("50m")},
+			},
+			wantErr: ErrResourcesNotAllowed,
+		},
+		{
+			name: "should reject limits above the maximum",
+			resources: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")},
+			},
+			wantErr: ErrResourcesNotAllowed,
+		},
+		{
+			name: "should reject limits too far above requests",
+			resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("250m")},
+				Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")},
+			},
+			wantErr: ErrResourcesNotAllowed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateLimitRanges(tt.resources, limitRanges); !errors.Is(err, tt.wantErr) {
+				t.Errorf("ValidateLimitRanges() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package resolvers
+
+import (
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// OperatorConfig is the config the operator applies to all the
+// applications, resolved from the K4IndieConfig of the cluster.
+type OperatorConfig struct {
+	// Labels are added to all the resources generated by the operator.
+	Labels             map[string]string
+	PodSecurityContext *corev1.PodSecurityContext
+	SecurityContext    *corev1.SecurityContext
+	ImagePullPolicy    corev1.PullPolicy
+	// DefaultSize is the RuntimeSize of the applications that don't set one.
+	DefaultSize v1alpha1.RuntimeSizeName
+}
+
+// ResolveOperatorConfig returns the operator config defined by the given
+// K4IndieConfig, with defaults for the fields it does not set. config may be
+// nil when the cluster has no K4IndieConfig.
+func ResolveOperatorConfig(config *v1alpha1.K4IndieConfig) OperatorConfig {
+	resolved := OperatorConfig{
+		Labels: map[string]string{},
+		PodSecurityContext: &corev1.PodSecurityContext{
+			RunAsNonRoot: &[]bool{true}[0],
+			SeccompProfile: &corev1.SeccompProfile{
+				Type: corev1.SeccompProfileTypeRuntimeDefault,
+			},
+		},
+		SecurityContext: &corev1.SecurityContext{
+			RunAsNonRoot:             &[]bool{true}[0],
+			AllowPrivilegeEscalation: &[]bool{false}[0],
+			Capabilities: &corev1.Capabilities{
+				Drop: []corev1.Capability{
+					"ALL",
+				},
+			},
+		},
+		ImagePullPolicy: corev1.PullIfNotPresent,
+		DefaultSize:     v1alpha1.BasicMachineType,
+	}
+	if config == nil {
+		return resolved
+	}
+
+	spec := config.Spec.DeepCopy()
+	if spec.Labels != nil {
+		resolved.Labels = spec.Labels
+	}
+	if spec.PodSecurityContext != nil {
+		resolved.PodSecurityContext = spec.PodSecurityContext
+	}
+	if spec.SecurityContext != nil {
+		resolved.SecurityContext = spec.SecurityContext
+	}
+	if spec.ImagePullPolicy != "" {
+		resolved.ImagePullPolicy = spec.ImagePullPolicy
+	}
+	if spec.DefaultSize != "" {
+		resolved.DefaultSize = spec.DefaultSize
+	}
+
+	return resolved
+}
+
+// ResolveRouterConfig returns the router config defined by the routing of
+// the given K4IndieConfig. Fields it does not set are taken from defaults,
+// which are usually set from the flags of the operator. config may be nil
+// when the cluster has no K4IndieConfig.
+func ResolveRouterConfig(defaults RouterConfig, config *v1alpha1.K4IndieConfig) RouterConfig {
+	resolved := defaults
+	if config == nil || config.Spec.Routing == nil {
+		return resolved
+	}
+
+	routing := config.Spec.Routing
+	if routing.Router != "" {
+		resolved.Kind = routing.Router
+	}
+	if routing.IngressClassName != "" {
+		resolved.IngressClassName = routing.IngressClassName
+	}
+	if routing.Gateway != nil {
+		resolved.GatewayName = routing.Gateway.Name
+		resolved.GatewayNamespace = routing.Gateway.Namespace
+	}
+	if len(routing.TraefikEntryPoints) > 0 {
+		resolved.TraefikEntryPoints = routing.TraefikEntryPoints
+	}
+
+	return resolved
+}
@@ -0,0 +1,115 @@
+package resolvers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestResolveOperatorConfig(t *testing.T) {
+	defaults := ResolveOperatorConfig(nil)
+
+	tests := []struct {
+		name   string
+		config *v1alpha1.K4IndieConfig
+		want   func() OperatorConfig
+	}{
+		{
+			name:   "should default without config",
+			config: nil,
+			want: func() OperatorConfig {
+				return defaults
+			},
+		},
+		{
+			name:   "should default unset fields",
+			config: &v1alpha1.K4IndieConfig{},
+			want: func() OperatorConfig {
+				return defaults
+			},
+		},
+		{
+			name: "should override defaults",
+			config: &v1alpha1.K4IndieConfig{
+				Spec: v1alpha1.K4IndieConfigSpec{
+					Labels:          map[string]string{"example.com/team": "platform"},
+					SecurityContext: &corev1.SecurityContext{RunAsUser: &[]int64{1000}[0]},
+					ImagePullPolicy: corev1.PullAlways,
+					DefaultSize:     v1alpha1.StandardMachineType,
+				},
+			},
+			want: func() OperatorConfig {
+				want := defaults
+				want.Labels = map[string]string{"example.com/team": "platform"}
+				want.SecurityContext = &corev1.SecurityContext{RunAsUser: &[]int64{1000}[0]}
+				want.ImagePullPolicy = corev1.PullAlways
+				want.DefaultSize = v1alpha1.StandardMachineType
+				return want
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResolveOperatorConfig(tt.config); !reflect.DeepEqual(got, tt.want()) {
+				t.Errorf("ResolveOperatorConfig() = %v, want %v", got, tt.want())
+			}
+		})
+	}
+
+	if defaults.ImagePullPolicy != corev1.PullIfNotPresent {
+		t.Errorf("ResolveOperatorConfig() imagePullPolicy = %v, want %v", defaults.ImagePullPolicy, corev1.PullIfNotPresent)
+	}
+	if defaults.DefaultSize != v1alpha1.BasicMachineType {
+		t.Errorf("ResolveOperatorConfig() defaultSize = %v, want %v", defaults.DefaultSize, v1alpha1.BasicMachineType)
+	}
+	if defaults.PodSecurityContext == nil || !*defaults.PodSecurityContext.RunAsNonRoot {
+		t.Errorf("ResolveOperatorConfig() podSecurityContext = %v, want non root", defaults.PodSecurityContext)
+	}
+}
+
+func TestResolveRouterConfig(t *testing.T) {
+	defaults := RouterConfig{Kind: RouterIngress, IngressClassName: "nginx"}
+
+	tests := []struct {
+		name   string
+		config *v1alpha1.K4IndieConfig
+		want   RouterConfig
+	}{
+		{
+			name:   "should use the defaults without config",
+			config: nil,
+			want:   defaults,
+		},
+		{
+			name:   "should use the defaults without routing",
+			config: &v1alpha1.K4IndieConfig{},
+			want:   defaults,
+		},
+		{
+			name: "should override the defaults",
+			config: &v1alpha1.K4IndieConfig{
+				Spec: v1alpha1.K4IndieConfigSpec{
+					Routing: &v1alpha1.RoutingConfig{
+						Router:  RouterGateway,
+						Gateway: &v1alpha1.GatewayReference{Namespace: "gateways", Name: "public"},
+					},
+				},
+			},
+			want: RouterConfig{
+				Kind:             RouterGateway,
+				IngressClassName: "nginx",
+				GatewayName:      "public",
+				GatewayNamespace: "gateways",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResolveRouterConfig(defaults, tt.config); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolveRouterConfig() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
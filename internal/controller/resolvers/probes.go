@@ -0,0 +1,134 @@
+package resolvers
+
+import (
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	defaultStartupTimeoutSeconds = 60
+	defaultPeriodSeconds         = 10
+	defaultTimeoutSeconds        = 1
+	defaultFailureThreshold      = 3
+
+	// startupPeriodSeconds is how often the startup probe runs so that the
+	// application starts receiving traffic soon after it is up.
+	startupPeriodSeconds = 5
+)
+
+// Probes are the probes of a process container.
+type Probes struct {
+	Readiness *corev1.Probe
+	Liveness  *corev1.Probe
+	Startup   *corev1.Probe
+}
+
+// BuildProbes builds the probes of a process from its health check. When
+// no check is configured, the first endpoint port is checked over TCP, and
+// processes without endpoints have no probes.
+func BuildProbes(process Process) (Probes, error) {
+	check := process.HealthCheck
+	if check == nil {
+		check = &v1alpha1.HealthCheck{}
+	}
+	if check.Disabled {
+		return Probes{}, nil
+	}
+
+	handler, err := buildProbeHandler(check, process.Endpoints)
+	if err != nil || handler == nil {
+		return Probes{}, err
+	}
+
+	periodSeconds := defaultInt32(check.PeriodSeconds, defaultPeriodSeconds)
+	timeoutSeconds := defaultInt32(check.TimeoutSeconds, defaultTimeoutSeconds)
+	failureThreshold := defaultInt32(check.FailureThreshold, defaultFailureThreshold)
+	startupTimeoutSeconds := defaultInt32(check.StartupTimeoutSeconds, defaultStartupTimeoutSeconds)
+
+	probe := func(period, failures int32) *corev1.Probe {
+		return &corev1.Probe{
+			ProbeHandler:     *handler.DeepCopy(),
+			PeriodSeconds:    period,
+			TimeoutSeconds:   timeoutSeconds,
+			SuccessThreshold: 1,
+			FailureThreshold: failures,
+		}
+	}
+
+	return Probes{
+		Readiness: probe(periodSeconds, failureThreshold),
+		Liveness:  probe(periodSeconds, failureThreshold),
+		Startup: probe(
+			startupPeriodSeconds,
+			(startupTimeoutSeconds+startupPeriodSeconds-1)/startupPeriodSeconds,
+		),
+	}, nil
+}
+
+func buildProbeHandler(
+	check *v1alpha1.HealthCheck,
+	endpoints v1alpha1.ApplicationEndpoints,
+) (*corev1.ProbeHandler, error) {
+	if check.Exec != nil {
+		return &corev1.ProbeHandler{
+			Exec: &corev1.ExecAction{Command: check.Exec.Command},
+		}, nil
+	}
+
+	port := int32(0)
+	switch {
+	case check.HTTP != nil:
+		port = check.HTTP.Port
+	case check.TCP != nil:
+		port = check.TCP.Port
+	}
+
+	if port == 0 {
+		if len(endpoints) == 0 {
+			return nil, nil
+		}
+		port = endpoints[0].Port
+	} else if !hasEndpointPort(endpoints, port) {
+		return nil, v1alpha1.ErrInvalidHealthCheckPort
+	}
+
+	if check.HTTP != nil {
+		path := check.HTTP.Path
+		if path == "" {
+			path = "/"
+		}
+
+		return &corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path:   path,
+				Port:   intstr.FromInt(int(port)),
+				Scheme: corev1.URISchemeHTTP,
+			},
+		}, nil
+	}
+
+	return &corev1.ProbeHandler{
+		TCPSocket: &corev1.TCPSocketAction{
+			Port: intstr.FromInt(int(port)),
+		},
+	}, nil
+}
+
+func hasEndpointPort(endpoints v1alpha1.ApplicationEndpoints, port int32) bool {
+	for _, endpoint := range endpoints {
+		if endpoint.Port == port {
+			return true
+		}
+	}
+
+	return false
+}
+
+func defaultInt32(value, defaultValue int32) int32 {
+	if value == 0 {
+		return defaultValue
+	}
+
+	return value
+}
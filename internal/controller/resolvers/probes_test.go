@@ -0,0 +1,120 @@
+package resolvers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestBuildProbes(t *testing.T) {
+	probes := func(handler corev1.ProbeHandler, period, failures, startupFailures int32) Probes {
+		return Probes{
+			Readiness: &corev1.Probe{
+				ProbeHandler:     handler,
+				PeriodSeconds:    period,
+				TimeoutSeconds:   1,
+				SuccessThreshold: 1,
+				FailureThreshold: failures,
+			},
+			Liveness: &corev1.Probe{
+				ProbeHandler:     handler,
+				PeriodSeconds:    period,
+				TimeoutSeconds:   1,
+				SuccessThreshold: 1,
+				FailureThreshold: failures,
+			},
+			Startup: &corev1.Probe{
+				ProbeHandler:     handler,
+				PeriodSeconds:    5,
+				TimeoutSeconds:   1,
+				SuccessThreshold: 1,
+				FailureThreshold: startupFailures,
+			},
+		}
+	}
+	endpoints := v1alpha1.ApplicationEndpoints{{Port: 8080}, {Port: 9090}}
+
+	tests := []struct {
+		name    string
+		process Process
+		want    Probes
+		wantErr bool
+	}{
+		{
+			name:    "should not probe processes without endpoints by default",
+			process: Process{},
+			want:    Probes{},
+		},
+		{
+			name:    "should check the first endpoint over tcp by default",
+			process: Process{Endpoints: endpoints},
+			want: probes(corev1.ProbeHandler{
+				TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(8080)},
+			}, 10, 3, 12),
+		},
+		{
+			name: "should check http path with custom timings",
+			process: Process{
+				Endpoints: endpoints,
+				HealthCheck: &v1alpha1.HealthCheck{
+					HTTP:                  &v1alpha1.HTTPHealthCheck{Path: "/healthz", Port: 9090},
+					StartupTimeoutSeconds: 120,
+					PeriodSeconds:         30,
+					FailureThreshold:      5,
+				},
+			},
+			want: probes(corev1.ProbeHandler{
+				HTTPGet: &corev1.HTTPGetAction{
+					Path:   "/healthz",
+					Port:   intstr.FromInt(9090),
+					Scheme: corev1.URISchemeHTTP,
+				},
+			}, 30, 5, 24),
+		},
+		{
+			name: "should check with exec without endpoints",
+			process: Process{
+				HealthCheck: &v1alpha1.HealthCheck{
+					Exec: &v1alpha1.ExecHealthCheck{Command: []string{"./healthcheck"}},
+				},
+			},
+			want: probes(corev1.ProbeHandler{
+				Exec: &corev1.ExecAction{Command: []string{"./healthcheck"}},
+			}, 10, 3, 12),
+		},
+		{
+			name: "should not probe when disabled",
+			process: Process{
+				Endpoints:   endpoints,
+				HealthCheck: &v1alpha1.HealthCheck{Disabled: true},
+			},
+			want: Probes{},
+		},
+		{
+			name: "should reject ports that are not endpoints",
+			process: Process{
+				Endpoints: endpoints,
+				HealthCheck: &v1alpha1.HealthCheck{
+					TCP: &v1alpha1.TCPHealthCheck{Port: 3000},
+				},
+			},
+			want:    Probes{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BuildProbes(tt.process)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("BuildProbes() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BuildProbes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
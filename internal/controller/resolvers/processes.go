@@ -0,0 +1,90 @@
+package resolvers
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+)
+
+// DefaultProcessName is the process type of applications that don't
+// declare any processes.
+const DefaultProcessName = "web"
+
+// Process is a resolved process type of an application with all the
+// application level defaults applied.
+type Process struct {
+	// Name of the process type.
+	Name string
+	// ResourceName is the name of the Deployment or StatefulSet and the
+	// Service generated for this process.
+	ResourceName string
+	Command      []string
+	Replicas     int32
+	Autoscale    *v1alpha1.Autoscale
+	Size         v1alpha1.RuntimeSizeName
+	Endpoints    v1alpha1.ApplicationEndpoints
+	HealthCheck  *v1alpha1.HealthCheck
+}
+
+// ResolveProcesses returns the processes of the application sorted by name.
+// Applications without processes resolve to a single default process named
+// after the application, so existing resources keep their names.
+func ResolveProcesses(app *v1alpha1.Application) []Process {
+	if len(app.Spec.Processes) == 0 {
+		return []Process{{
+			Name:         DefaultProcessName,
+			ResourceName: app.Name,
+			Command:      app.Spec.LaunchCommand,
+			Replicas:     app.Spec.Replicas,
+			Autoscale:    app.Spec.Autoscale,
+			Size:         app.Spec.Runtime.Size,
+			Endpoints:    app.Spec.Endpoints,
+			HealthCheck:  app.Spec.HealthCheck,
+		}}
+	}
+
+	processes := make([]Process, 0, len(app.Spec.Processes))
+	for name, spec := range app.Spec.Processes {
+		process := Process{
+			Name:         name,
+			ResourceName: fmt.Sprintf("%s-%s", app.Name, name),
+			Command:      spec.Command,
+			Replicas:     spec.Replicas,
+			Autoscale:    spec.Autoscale,
+			Size:         spec.Size,
+			Endpoints:    spec.Endpoints,
+			HealthCheck:  spec.HealthCheck,
+		}
+		if len(process.Command) == 0 {
+			process.Command = app.Spec.LaunchCommand
+		}
+		if process.Size == "" {
+			process.Size = app.Spec.Runtime.Size
+		}
+		if process.HealthCheck == nil {
+			process.HealthCheck = app.Spec.HealthCheck
+		}
+
+		processes = append(processes, process)
+	}
+
+	sort.Slice(processes, func(i, j int) bool {
+		return processes[i].Name < processes[j].Name
+	})
+
+	return processes
+}
+
+// ProcessesWithEndpoints returns only the processes that expose endpoints.
+func ProcessesWithEndpoints(processes []Process) []Process {
+	result := make([]Process, 0, len(processes))
+
+	for _, process := range processes {
+		if len(process.Endpoints) > 0 {
+			result = append(result, process)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,86 @@
+package resolvers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestResolveProcesses(t *testing.T) {
+	tests := []struct {
+		name string
+		app  *v1alpha1.Application
+		want []Process
+	}{
+		{
+			name: "should resolve a default process without processes",
+			app: &v1alpha1.Application{
+				ObjectMeta: metav1.ObjectMeta{Name: "shop"},
+				Spec: v1alpha1.ApplicationSpec{
+					Replicas:      2,
+					LaunchCommand: []string{"./server"},
+					Runtime:       v1alpha1.ApplicationRuntime{Size: v1alpha1.BasicMachineType},
+					Endpoints:     v1alpha1.ApplicationEndpoints{{Port: 8080}},
+				},
+			},
+			want: []Process{
+				{
+					Name:         "web",
+					ResourceName: "shop",
+					Command:      []string{"./server"},
+					Replicas:     2,
+					Size:         v1alpha1.BasicMachineType,
+					Endpoints:    v1alpha1.ApplicationEndpoints{{Port: 8080}},
+				},
+			},
+		},
+		{
+			name: "should apply application defaults to processes",
+			app: &v1alpha1.Application{
+				ObjectMeta: metav1.ObjectMeta{Name: "shop"},
+				Spec: v1alpha1.ApplicationSpec{
+					Replicas:      2,
+					LaunchCommand: []string{"./server"},
+					Runtime:       v1alpha1.ApplicationRuntime{Size: v1alpha1.BasicMachineType},
+					Processes: map[string]v1alpha1.ApplicationProcess{
+						"worker": {
+							Command:  []string{"./worker"},
+							Replicas: 3,
+						},
+						"web": {
+							Replicas:  1,
+							Size:      v1alpha1.PerformanceMachineType,
+							Endpoints: v1alpha1.ApplicationEndpoints{{Port: 8080}},
+						},
+					},
+				},
+			},
+			want: []Process{
+				{
+					Name:         "web",
+					ResourceName: "shop-web",
+					Command:      []string{"./server"},
+					Replicas:     1,
+					Size:         v1alpha1.PerformanceMachineType,
+					Endpoints:    v1alpha1.ApplicationEndpoints{{Port: 8080}},
+				},
+				{
+					Name:         "worker",
+					ResourceName: "shop-worker",
+					Command:      []string{"./worker"},
+					Replicas:     3,
+					Size:         v1alpha1.BasicMachineType,
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResolveProcesses(tt.app); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolveProcesses() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package resolvers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ReleaseHashAnnotation is set on the pod template of deployments once the
+// release phase for the release with the given hash succeeded.
+const ReleaseHashAnnotation = "k4indie.io/release-hash"
+
+// ReleaseProcessName is the process name used to label release phase pods.
+const ReleaseProcessName = "release"
+
+// defaultReleaseTimeout is how long the release phase may run when the
+// application does not set a timeout.
+const defaultReleaseTimeout = 30 * time.Minute
+
+// releaseJobSuffixLength is the length of the "-release-" infix and the
+// hash suffix of the release Job names.
+const releaseJobSuffixLength = len("-release-") + 10
+
+// HashRelease returns a hash identifying a release of an application by its
+// image, release command, rendered environment variables and the content of
+// the config it references. A new release phase runs whenever the hash
+// changes.
+func HashRelease(image v1alpha1.RuntimeImage, command []string, env []corev1.EnvVar, configHash string) (string, error) {
+	encodedEnv, err := json.Marshal(env)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	writeHashEntry(h, "image", image.String())
+	for _, arg := range command {
+		writeHashEntry(h, "command", arg)
+	}
+	writeHashEntry(h, "env", string(encodedEnv))
+	writeHashEntry(h, "config", configHash)
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ReleaseJobName returns the name of the Job running the release phase of
+// the release with the given hash. The application name is truncated so the
+// name fits in the job-name label of the release pods.
+func ReleaseJobName(appName string, releaseHash string) string {
+	if maxLength := validation.DNS1123LabelMaxLength - releaseJobSuffixLength; len(appName) > maxLength {
+		appName = strings.TrimRight(appName[:maxLength], "-.")
+	}
+
+	return fmt.Sprintf("%s-release-%s", appName, releaseHash[:10])
+}
+
+// ReleaseDeadlineSeconds returns how long the release phase of the
+// application may run.
+func ReleaseDeadlineSeconds(app *v1alpha1.Application) int64 {
+	timeout := defaultReleaseTimeout
+	if app.Spec.ReleaseTimeout != nil && app.Spec.ReleaseTimeout.Duration > 0 {
+		timeout = app.Spec.ReleaseTimeout.Duration
+	}
+
+	return int64(timeout.Seconds())
+}
+
+// defaultReleaseHistoryLimit is the number of Releases kept when the
+// application does not set a limit.
+const defaultReleaseHistoryLimit = 10
+
+// ReleaseName returns the name of the Release object of an application version.
+func ReleaseName(appName string, version int32) string {
+	return fmt.Sprintf("%s-v%d", appName, version)
+}
+
+// HashReleaseConfig returns a hash of the application config spec combined
+// with the hash of the content it references.
+func HashReleaseConfig(config v1alpha1.ApplicationConfig, contentHash string) (string, error) {
+	// maps are marshalled with sorted keys, so the encoding is stable.
+	encoded, err := json.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	writeHashEntry(h, "config", string(encoded))
+	writeHashEntry(h, "content", contentHash)
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ReleaseTrigger describes what changed between the latest release and a
+// new release with the given image and config hash.
+func ReleaseTrigger(
+	latest *v1alpha1.Release,
+	image v1alpha1.RuntimeImage,
+	configHash string,
+	rollbackTo *int32,
+) string {
+	switch {
+	case rollbackTo != nil:
+		return fmt.Sprintf("Rollback to v%d", *rollbackTo)
+	case latest == nil:
+		return "Initial release"
+	case latest.Spec.Image != image && latest.Spec.ConfigHash != configHash:
+		return fmt.Sprintf("Deploy %s and config change", image.Tag())
+	case latest.Spec.Image != image:
+		return fmt.Sprintf("Deploy %s", image.Tag())
+	default:
+		return "Config change"
+	}
+}
+
+// LastSpecManager returns the name of the field manager that most recently
+// updated the object, ignoring updates of its subresources such as status
+// and updates by the operator itself, e.g. of its finalizer.
+func LastSpecManager(entries []metav1.ManagedFieldsEntry, operatorManager string) string {
+	manager := ""
+	var latest *metav1.Time
+
+	for _, entry := range entries {
+		if entry.Subresource != "" || entry.Time == nil || entry.Manager == operatorManager {
+			continue
+		}
+		if latest == nil || latest.Before(entry.Time) {
+			latest = entry.Time
+			manager = entry.Manager
+		}
+	}
+
+	return manager
+}
+
+// ReleasesToPrune returns the Releases beyond the history limit of the
+// application, oldest first. The Release the application runs and the one
+// it is rolled back to are never pruned.
+func ReleasesToPrune(app *v1alpha1.Application, releases []v1alpha1.Release) []v1alpha1.Release {
+	limit := defaultReleaseHistoryLimit
+	if app.Spec.ReleaseHistoryLimit != nil && *app.Spec.ReleaseHistoryLimit > 0 {
+		limit = int(*app.Spec.ReleaseHistoryLimit)
+	}
+	if len(releases) <= limit {
+		return nil
+	}
+
+	sorted := make([]v1alpha1.Release, len(releases))
+	copy(sorted, releases)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Spec.Version < sorted[j].Spec.Version
+	})
+
+	var pruned []v1alpha1.Release
+	for _, release := range sorted[:len(sorted)-limit] {
+		version := release.Spec.Version
+		if version == app.Status.CurrentRelease ||
+			(app.Spec.RollbackTo != nil && version == *app.Spec.RollbackTo) {
+			continue
+		}
+		pruned = append(pruned, release)
+	}
+
+	return pruned
+}
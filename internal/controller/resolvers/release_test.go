@@ -0,0 +1,303 @@
+package resolvers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHashRelease(t *testing.T) {
+	env := []corev1.EnvVar{{Name: "LOG_LEVEL", Value: "info"}}
+	base, err := HashRelease("shop:v1", []string{"rake", "db:migrate"}, env, "config")
+	if err != nil {
+		t.Fatalf("HashRelease() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		image      v1alpha1.RuntimeImage
+		command    []string
+		env        []corev1.EnvVar
+		configHash string
+		wantEqual  bool
+	}{
+		{
+			name:       "same release",
+			image:      "shop:v1",
+			command:    []string{"rake", "db:migrate"},
+			env:        env,
+			configHash: "config",
+			wantEqual:  true,
+		},
+		{
+			name:       "new image",
+			image:      "shop:v2",
+			command:    []string{"rake", "db:migrate"},
+			env:        env,
+			configHash: "config",
+			wantEqual:  false,
+		},
+		{
+			name:       "new command",
+			image:      "shop:v1",
+			command:    []string{"rake", "db:migrate:status"},
+			env:        env,
+			configHash: "config",
+			wantEqual:  false,
+		},
+		{
+			name:       "new config vars",
+			image:      "shop:v1",
+			command:    []string{"rake", "db:migrate"},
+			env:        []corev1.EnvVar{{Name: "LOG_LEVEL", Value: "debug"}},
+			configHash: "config",
+			wantEqual:  false,
+		},
+		{
+			name:       "new config content",
+			image:      "shop:v1",
+			command:    []string{"rake", "db:migrate"},
+			env:        env,
+			configHash: "rotated",
+			wantEqual:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HashRelease(tt.image, tt.command, tt.env, tt.configHash)
+			if err != nil {
+				t.Fatalf("HashRelease() error = %v", err)
+			}
+			if (got == base) != tt.wantEqual {
+				t.Errorf("HashRelease() = %v, base %v, wantEqual %v", got, base, tt.wantEqual)
+			}
+		})
+	}
+}
+
+func TestReleaseJobName(t *testing.T) {
+	hash := "0123456789abcdef"
+
+	tests := []struct {
+		name    string
+		appName string
+		want    string
+	}{
+		{
+			name:    "should keep short names",
+			appName: "shop",
+			want:    "shop-release-0123456789",
+		},
+		{
+			name:    "should truncate long names",
+			appName: "a-very-long-application-name-that-goes-on-and-on-and-on",
+			want:    "a-very-long-application-name-that-goes-on-an-release-0123456789",
+		},
+		{
+			name:    "should not end the truncated name with a dash",
+			appName: "a-very-long-application-name-that-goes-on-a-b",
+			want:    "a-very-long-application-name-that-goes-on-a-release-0123456789",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ReleaseJobName(tt.appName, hash)
+			if got != tt.want {
+				t.Errorf("ReleaseJobName() = %v, want %v", got, tt.want)
+			}
+			if len(got) > 63 {
+				t.Errorf("ReleaseJobName() = %v is longer than 63 characters", got)
+			}
+		})
+	}
+}
+
+func TestReleaseDeadlineSeconds(t *testing.T) {
+	app := &v1alpha1.Application{}
+	if got := ReleaseDeadlineSeconds(app); got != 1800 {
+		t.Errorf("ReleaseDeadlineSeconds() = %v, want 1800", got)
+	}
+
+	app.Spec.ReleaseTimeout = &metav1.Duration{Duration: 5 * time.Minute}
+	if got := ReleaseDeadlineSeconds(app); got != 300 {
+		t.Errorf("ReleaseDeadlineSeconds() = %v, want 300", got)
+	}
+}
+
+func TestReleaseTrigger(t *testing.T) {
+	latest := &v1alpha1.Release{
+		Spec: v1alpha1.ReleaseSpec{
+			Version:    2,
+			Image:      "shop:v1",
+			ConfigHash: "config",
+		},
+	}
+	rollbackTo := int32(1)
+
+	tests := []struct {
+		name       string
+		latest     *v1alpha1.Release
+		image      v1alpha1.RuntimeImage
+		configHash string
+		rollbackTo *int32
+		want       string
+	}{
+		{
+			name:       "initial release",
+			image:      "shop:v1",
+			configHash: "config",
+			want:       "Initial release",
+		},
+		{
+			name:       "image change",
+			latest:     latest,
+			image:      "shop:v2",
+			configHash: "config",
+			want:       "Deploy v2",
+		},
+		{
+			name:       "config change",
+			latest:     latest,
+			image:      "shop:v1",
+			configHash: "rotated",
+			want:       "Config change",
+		},
+		{
+			name:       "image and config change",
+			latest:     latest,
+			image:      "shop:v2",
+			configHash: "rotated",
+			want:       "Deploy v2 and config change",
+		},
+		{
+			name:       "rollback",
+			latest:     latest,
+			image:      "shop:v0",
+			configHash: "config",
+			rollbackTo: &rollbackTo,
+			want:       "Rollback to v1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ReleaseTrigger(tt.latest, tt.image, tt.configHash, tt.rollbackTo); got != tt.want {
+				t.Errorf("ReleaseTrigger() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLastSpecManager(t *testing.T) {
+	at := func(minute int) *metav1.Time {
+		return &metav1.Time{Time: time.Date(2023, 1, 1, 0, minute, 0, 0, time.UTC)}
+	}
+
+	tests := []struct {
+		name    string
+		entries []metav1.ManagedFieldsEntry
+		want    string
+	}{
+		{
+			name: "no entries",
+			want: "",
+		},
+		{
+			name: "should ignore the operator",
+			entries: []metav1.ManagedFieldsEntry{
+				{Manager: "github-actions", Time: at(5)},
+				{Manager: "k4indie", Time: at(9)},
+			},
+			want: "github-actions",
+		},
+		{
+			name: "latest spec manager wins",
+			entries: []metav1.ManagedFieldsEntry{
+				{Manager: "kubectl-client-side-apply", Time: at(1)},
+				{Manager: "github-actions", Time: at(5)},
+				{Manager: "manager", Subresource: "status", Time: at(9)},
+			},
+			want: "github-actions",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LastSpecManager(tt.entries, "k4indie"); got != tt.want {
+				t.Errorf("LastSpecManager() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReleasesToPrune(t *testing.T) {
+	releases := func(versions ...int32) []v1alpha1.Release {
+		var releases []v1alpha1.Release
+		for _, version := range versions {
+			releases = append(releases, v1alpha1.Release{Spec: v1alpha1.ReleaseSpec{Version: version}})
+		}
+		return releases
+	}
+	versions := func(releases []v1alpha1.Release) []int32 {
+		var versions []int32
+		for _, release := range releases {
+			versions = append(versions, release.Spec.Version)
+		}
+		return versions
+	}
+	limit := int32(2)
+	rollbackTo := int32(1)
+
+	tests := []struct {
+		name     string
+		app      *v1alpha1.Application
+		releases []v1alpha1.Release
+		want     []int32
+	}{
+		{
+			name:     "should keep releases within the limit",
+			app:      &v1alpha1.Application{Spec: v1alpha1.ApplicationSpec{ReleaseHistoryLimit: &limit}},
+			releases: releases(1, 2),
+			want:     nil,
+		},
+		{
+			name:     "should prune the oldest releases",
+			app:      &v1alpha1.Application{Spec: v1alpha1.ApplicationSpec{ReleaseHistoryLimit: &limit}},
+			releases: releases(4, 1, 3, 2),
+			want:     []int32{1, 2},
+		},
+		{
+			name: "should keep the release rolled back to",
+			app: &v1alpha1.Application{
+				Spec: v1alpha1.ApplicationSpec{ReleaseHistoryLimit: &limit, RollbackTo: &rollbackTo},
+			},
+			releases: releases(1, 2, 3, 4),
+			want:     []int32{2},
+		},
+		{
+			name: "should keep the current release",
+			app: &v1alpha1.Application{
+				Spec:   v1alpha1.ApplicationSpec{ReleaseHistoryLimit: &limit},
+				Status: v1alpha1.ApplicationStatus{CurrentRelease: 2},
+			},
+			releases: releases(1, 2, 3, 4),
+			want:     []int32{1},
+		},
+		{
+			name:     "should default the limit",
+			app:      &v1alpha1.Application{},
+			releases: releases(1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11),
+			want:     []int32{1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := versions(ReleasesToPrune(tt.app, tt.releases))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReleasesToPrune() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
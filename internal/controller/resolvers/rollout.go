@@ -0,0 +1,217 @@
+package resolvers
+
+import (
+	"fmt"
+
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// progressDeadlineExceededReason is the reason of the Progressing condition
+// of a Deployment whose rollout did not progress in time.
+const progressDeadlineExceededReason = "ProgressDeadlineExceeded"
+
+// RolloutStatus is the aggregated rollout status of the deployments of an
+// application.
+type RolloutStatus struct {
+	Replicas          int32
+	ReadyReplicas     int32
+	UpdatedReplicas   int32
+	AvailableReplicas int32
+	// Image is the image the deployments roll out. It is empty when the
+	// deployments don't run the same image.
+	Image string
+	// Available is true when all the deployments have minimum availability.
+	Available bool
+	// Progressing is true while any deployment has not finished rolling out.
+	Progressing bool
+	// Degraded is true when any deployment failed to create pods or did not
+	// progress before its deadline.
+	Degraded bool
+	// Message describes the first deployment that is not settled.
+	Message string
+}
+
+// IsComplete returns true when the rollout of the given image finished and
+// all its replicas are available.
+func (s RolloutStatus) IsComplete(image v1alpha1.RuntimeImage) bool {
+	return s.Available && !s.Progressing && !s.Degraded && s.Image == image.String()
+}
+
+// ResolveRolloutStatus aggregates the status of the given deployments,
+// following the same rules as `kubectl rollout status`.
+func ResolveRolloutStatus(deployments []appsv1.Deployment) RolloutStatus {
+	status := RolloutStatus{Available: len(deployments) > 0}
+
+	for i, deployment := range deployments {
+		status.Replicas += deployment.Status.Replicas
+		status.ReadyReplicas += deployment.Status.ReadyReplicas
+		status.UpdatedReplicas += deployment.Status.UpdatedReplicas
+		status.AvailableReplicas += deployment.Status.AvailableReplicas
+
+		image := podTemplateImage(&deployment.Spec.Template)
+		if i == 0 {
+			status.Image = image
+		} else if status.Image != image {
+			status.Image = ""
+		}
+
+		if !isDeploymentConditionTrue(&deployment, appsv1.DeploymentAvailable) {
+			status.Available = false
+		}
+
+		message, degraded := deploymentDegradedMessage(&deployment)
+		if degraded {
+			if !status.Degraded {
+				status.Message = message
+			}
+			status.Degraded = true
+			continue
+		}
+
+		message, settled := deploymentRolloutMessage(&deployment)
+		if !settled {
+			if !status.Progressing && !status.Degraded {
+				status.Message = message
+			}
+			status.Progressing = true
+		}
+	}
+
+	return status
+}
+
+// ResolveStatefulSetRolloutStatus aggregates the status of the given
+// StatefulSets, following the same rules as `kubectl rollout status`.
+// StatefulSets replace their replicas one at a time and have no progress
+// deadline, so they are only available once all their replicas are, and
+// are never reported as degraded.
+func ResolveStatefulSetRolloutStatus(statefulSets []appsv1.StatefulSet) RolloutStatus {
+	status := RolloutStatus{Available: len(statefulSets) > 0}
+
+	for i, statefulSet := range statefulSets {
+		status.Replicas += statefulSet.Status.Replicas
+		status.ReadyReplicas += statefulSet.Status.ReadyReplicas
+		status.UpdatedReplicas += statefulSet.Status.UpdatedReplicas
+		status.AvailableReplicas += statefulSet.Status.AvailableReplicas
+
+		image := podTemplateImage(&statefulSet.Spec.Template)
+		if i == 0 {
+			status.Image = image
+		} else if status.Image != image {
+			status.Image = ""
+		}
+
+		if statefulSet.Status.AvailableReplicas < statefulSetReplicas(&statefulSet) {
+			status.Available = false
+		}
+
+		message, settled := statefulSetRolloutMessage(&statefulSet)
+		if !settled {
+			if !status.Progressing {
+				status.Message = message
+			}
+			status.Progressing = true
+		}
+	}
+
+	return status
+}
+
+// statefulSetRolloutMessage returns the progress of the StatefulSet
+// rollout, and whether the rollout is complete.
+func statefulSetRolloutMessage(statefulSet *appsv1.StatefulSet) (string, bool) {
+	if statefulSet.Status.ObservedGeneration < statefulSet.Generation {
+		return fmt.Sprintf("waiting for statefulset %s spec update to be observed", statefulSet.Name), false
+	}
+
+	replicas := statefulSetReplicas(statefulSet)
+	switch {
+	case statefulSet.Status.ReadyReplicas < replicas:
+		return fmt.Sprintf(
+			"waiting for statefulset %s rollout: %d of %d replicas ready",
+			statefulSet.Name, statefulSet.Status.ReadyReplicas, replicas,
+		), false
+	case statefulSet.Status.UpdateRevision != statefulSet.Status.CurrentRevision:
+		return fmt.Sprintf(
+			"waiting for statefulset %s rollout: %d of %d replicas updated",
+			statefulSet.Name, statefulSet.Status.UpdatedReplicas, replicas,
+		), false
+	}
+
+	return "", true
+}
+
+func statefulSetReplicas(statefulSet *appsv1.StatefulSet) int32 {
+	if statefulSet.Spec.Replicas == nil {
+		return 1
+	}
+
+	return *statefulSet.Spec.Replicas
+}
+
+// deploymentDegradedMessage returns why the deployment is degraded, if it is.
+func deploymentDegradedMessage(deployment *appsv1.Deployment) (string, bool) {
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentReplicaFailure && condition.Status == corev1.ConditionTrue {
+			return fmt.Sprintf("deployment %s failed to create pods: %s", deployment.Name, condition.Message), true
+		}
+		if condition.Type == appsv1.DeploymentProgressing && condition.Reason == progressDeadlineExceededReason {
+			return fmt.Sprintf("deployment %s exceeded its progress deadline", deployment.Name), true
+		}
+	}
+
+	return "", false
+}
+
+// deploymentRolloutMessage returns the progress of the deployment rollout,
+// and whether the rollout is complete.
+func deploymentRolloutMessage(deployment *appsv1.Deployment) (string, bool) {
+	if deployment.Status.ObservedGeneration < deployment.Generation {
+		return fmt.Sprintf("waiting for deployment %s spec update to be observed", deployment.Name), false
+	}
+
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+
+	switch {
+	case deployment.Status.UpdatedReplicas < replicas:
+		return fmt.Sprintf(
+			"waiting for deployment %s rollout: %d of %d new replicas updated",
+			deployment.Name, deployment.Status.UpdatedReplicas, replicas,
+		), false
+	case deployment.Status.Replicas > deployment.Status.UpdatedReplicas:
+		return fmt.Sprintf(
+			"waiting for deployment %s rollout: %d old replicas pending termination",
+			deployment.Name, deployment.Status.Replicas-deployment.Status.UpdatedReplicas,
+		), false
+	case deployment.Status.AvailableReplicas < deployment.Status.UpdatedReplicas:
+		return fmt.Sprintf(
+			"waiting for deployment %s rollout: %d of %d updated replicas available",
+			deployment.Name, deployment.Status.AvailableReplicas, deployment.Status.UpdatedReplicas,
+		), false
+	}
+
+	return "", true
+}
+
+func podTemplateImage(template *corev1.PodTemplateSpec) string {
+	if len(template.Spec.Containers) == 0 {
+		return ""
+	}
+
+	return template.Spec.Containers[0].Image
+}
+
+func isDeploymentConditionTrue(deployment *appsv1.Deployment, conditionType appsv1.DeploymentConditionType) bool {
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == conditionType && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,298 @@
+package resolvers
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestResolveRolloutStatus(t *testing.T) {
+	deployment := func(name string, replicas int32, status appsv1.DeploymentStatus) appsv1.Deployment {
+		return appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Generation: 2},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Image: "shop:v2"}},
+					},
+				},
+			},
+			Status: status,
+		}
+	}
+	available := []appsv1.DeploymentCondition{
+		{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionTrue},
+	}
+
+	tests := []struct {
+		name        string
+		deployments []appsv1.Deployment
+		want        RolloutStatus
+	}{
+		{
+			name:        "should not be available without deployments",
+			deployments: []appsv1.Deployment{},
+			want:        RolloutStatus{},
+		},
+		{
+			name: "should be settled when all replicas are updated and available",
+			deployments: []appsv1.Deployment{
+				deployment("shop-web", 2, appsv1.DeploymentStatus{
+					ObservedGeneration: 2,
+					Replicas:           2,
+					ReadyReplicas:      2,
+					UpdatedReplicas:    2,
+					AvailableReplicas:  2,
+					Conditions:         available,
+				}),
+				deployment("shop-worker", 1, appsv1.DeploymentStatus{
+					ObservedGeneration: 2,
+					Replicas:           1,
+					ReadyReplicas:      1,
+					UpdatedReplicas:    1,
+					AvailableReplicas:  1,
+					Conditions:         available,
+				}),
+			},
+			want: RolloutStatus{
+				Replicas:          3,
+				ReadyReplicas:     3,
+				UpdatedReplicas:   3,
+				AvailableReplicas: 3,
+				Image:             "shop:v2",
+				Available:         true,
+			},
+		},
+		{
+			name: "should be progressing while old replicas are running",
+			deployments: []appsv1.Deployment{
+				deployment("shop-web", 2, appsv1.DeploymentStatus{
+					ObservedGeneration: 2,
+					Replicas:           3,
+					ReadyReplicas:      2,
+					UpdatedReplicas:    2,
+					AvailableReplicas:  2,
+					Conditions:         available,
+				}),
+			},
+			want: RolloutStatus{
+				Replicas:          3,
+				ReadyReplicas:     2,
+				UpdatedReplicas:   2,
+				AvailableReplicas: 2,
+				Image:             "shop:v2",
+				Available:         true,
+				Progressing:       true,
+				Message:           "waiting for deployment shop-web rollout: 1 old replicas pending termination",
+			},
+		},
+		{
+			name: "should be progressing until the spec update is observed",
+			deployments: []appsv1.Deployment{
+				deployment("shop-web", 1, appsv1.DeploymentStatus{
+					ObservedGeneration: 1,
+					Replicas:           1,
+					UpdatedReplicas:    1,
+					AvailableReplicas:  1,
+				}),
+			},
+			want: RolloutStatus{
+				Replicas:          1,
+				UpdatedReplicas:   1,
+				AvailableReplicas: 1,
+				Image:             "shop:v2",
+				Progressing:       true,
+				Message:           "waiting for deployment shop-web spec update to be observed",
+			},
+		},
+		{
+			name: "should be degraded when the progress deadline is exceeded",
+			deployments: []appsv1.Deployment{
+				deployment("shop-web", 2, appsv1.DeploymentStatus{
+					ObservedGeneration: 2,
+					Replicas:           2,
+					UpdatedReplicas:    1,
+				}),
+				deployment("shop-worker", 1, appsv1.DeploymentStatus{
+					ObservedGeneration: 2,
+					Replicas:           1,
+					UpdatedReplicas:    1,
+					Conditions: []appsv1.DeploymentCondition{
+						{
+							Type:   appsv1.DeploymentProgressing,
+							Status: corev1.ConditionFalse,
+							Reason: "ProgressDeadlineExceeded",
+						},
+					},
+				}),
+			},
+			want: RolloutStatus{
+				Replicas:        3,
+				UpdatedReplicas: 2,
+				Image:           "shop:v2",
+				Progressing:     true,
+				Degraded:        true,
+				Message:         "deployment shop-worker exceeded its progress deadline",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResolveRolloutStatus(tt.deployments); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolveRolloutStatus() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveStatefulSetRolloutStatus(t *testing.T) {
+	statefulSet := func(name string, replicas int32, status appsv1.StatefulSetStatus) appsv1.StatefulSet {
+		return appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Generation: 2},
+			Spec: appsv1.StatefulSetSpec{
+				Replicas: &replicas,
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Image: "nats:2.9"}},
+					},
+				},
+			},
+			Status: status,
+		}
+	}
+
+	tests := []struct {
+		name         string
+		statefulSets []appsv1.StatefulSet
+		want         RolloutStatus
+	}{
+		{
+			name:         "should not be available without statefulsets",
+			statefulSets: []appsv1.StatefulSet{},
+			want:         RolloutStatus{},
+		},
+		{
+			name: "should be settled when all replicas are ready at the current revision",
+			statefulSets: []appsv1.StatefulSet{
+				statefulSet("nats", 3, appsv1.StatefulSetStatus{
+					ObservedGeneration: 2,
+					Replicas:           3,
+					ReadyReplicas:      3,
+					UpdatedReplicas:    3,
+					AvailableReplicas:  3,
+					CurrentRevision:    "nats-7d4b9",
+					UpdateRevision:     "nats-7d4b9",
+				}),
+			},
+			want: RolloutStatus{
+				Replicas:          3,
+				ReadyReplicas:     3,
+				UpdatedReplicas:   3,
+				AvailableReplicas: 3,
+				Image:             "nats:2.9",
+				Available:         true,
+			},
+		},
+		{
+			name: "should be progressing while replicas are updated",
+			statefulSets: []appsv1.StatefulSet{
+				statefulSet("nats", 3, appsv1.StatefulSetStatus{
+					ObservedGeneration: 2,
+					Replicas:           3,
+					ReadyReplicas:      3,
+					UpdatedReplicas:    1,
+					AvailableReplicas:  3,
+					CurrentRevision:    "nats-7d4b9",
+					UpdateRevision:     "nats-5f6c8",
+				}),
+			},
+			want: RolloutStatus{
+				Replicas:          3,
+				ReadyReplicas:     3,
+				UpdatedReplicas:   1,
+				AvailableReplicas: 3,
+				Image:             "nats:2.9",
+				Available:         true,
+				Progressing:       true,
+				Message:           "waiting for statefulset nats rollout: 1 of 3 replicas updated",
+			},
+		},
+		{
+			name: "should not be available while replicas are not ready",
+			statefulSets: []appsv1.StatefulSet{
+				statefulSet("nats", 3, appsv1.StatefulSetStatus{
+					ObservedGeneration: 2,
+					Replicas:           3,
+					ReadyReplicas:      2,
+					UpdatedReplicas:    3,
+					AvailableReplicas:  2,
+					CurrentRevision:    "nats-7d4b9",
+					UpdateRevision:     "nats-7d4b9",
+				}),
+			},
+			want: RolloutStatus{
+				Replicas:          3,
+				ReadyReplicas:     2,
+				UpdatedReplicas:   3,
+				AvailableReplicas: 2,
+				Image:             "nats:2.9",
+				Progressing:       true,
+				Message:           "waiting for statefulset nats rollout: 2 of 3 replicas ready",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResolveStatefulSetRolloutStatus(tt.statefulSets); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolveStatefulSetRolloutStatus() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRolloutStatus_IsComplete(t *testing.T) {
+	complete := RolloutStatus{Available: true, Image: "shop:v2"}
+
+	tests := []struct {
+		name   string
+		status RolloutStatus
+		want   bool
+	}{
+		{
+			name:   "should be complete when available with the image",
+			status: complete,
+			want:   true,
+		},
+		{
+			name:   "should not be complete while rolling out another image",
+			status: RolloutStatus{Available: true, Image: "shop:v1"},
+			want:   false,
+		},
+		{
+			name:   "should not be complete while progressing",
+			status: RolloutStatus{Available: true, Progressing: true, Image: "shop:v2"},
+			want:   false,
+		},
+		{
+			name:   "should not be complete when degraded",
+			status: RolloutStatus{Available: true, Degraded: true, Image: "shop:v2"},
+			want:   false,
+		},
+		{
+			name:   "should not be complete when unavailable",
+			status: RolloutStatus{Image: "shop:v2"},
+			want:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.status.IsComplete("shop:v2"); got != tt.want {
+				t.Errorf("IsComplete() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
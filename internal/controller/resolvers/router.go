@@ -0,0 +1,103 @@
+package resolvers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Router kinds that can be selected in the operator config.
+const (
+	RouterIngress = "ingress"
+	RouterGateway = "gateway"
+	RouterTraefik = "traefik"
+)
+
+var ErrInvalidRouter = errors.New("invalid router")
+
+// RouterConfig configures how the domains of applications are routed.
+type RouterConfig struct {
+	// Kind of router, one of RouterIngress, RouterGateway or RouterTraefik.
+	Kind string
+	// IngressClassName is the class of the generated Ingresses. The default
+	// class of the cluster is used when empty.
+	IngressClassName string
+	// GatewayName and GatewayNamespace reference the Gateway the generated
+	// HTTPRoutes attach to.
+	GatewayName      string
+	GatewayNamespace string
+	// TraefikEntryPoints are the entry points of the generated
+	// IngressRoutes. All entry points are used when empty.
+	TraefikEntryPoints []string
+}
+
+// Router builds the objects routing the domains of an application to the
+// Services of its processes.
+type Router interface {
+	// BuildRoutes returns the routing objects of the application. It returns
+	// no objects when the processes have no domains.
+	BuildRoutes(app *v1alpha1.Application, processes []Process) ([]client.Object, error)
+
+	// RouteTypes returns an empty object of each type built by the router.
+	RouteTypes() []client.Object
+
+	// TerminatesTLS reports whether the routes serve the certificates of the
+	// domains. Otherwise TLS is terminated outside of the application, e.g.
+	// by the listeners of a Gateway.
+	TerminatesTLS() bool
+}
+
+// NewRouter returns the router selected by the config.
+func NewRouter(config RouterConfig) (Router, error) {
+	switch config.Kind {
+	case RouterIngress, "":
+		return &IngressRouter{IngressClassName: config.IngressClassName}, nil
+	case RouterGateway:
+		if config.GatewayName == "" {
+			return nil, fmt.Errorf("%w: the gateway router requires a gateway name", ErrInvalidRouter)
+		}
+		return &GatewayRouter{Name: config.GatewayName, Namespace: config.GatewayNamespace}, nil
+	case RouterTraefik:
+		return &TraefikRouter{EntryPoints: config.TraefikEntryPoints}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidRouter, config.Kind)
+	}
+}
+
+// RouteTypes returns an empty object of each type built by any of the
+// routers, so the routing objects left by a previously selected router can
+// be found.
+func RouteTypes() []client.Object {
+	routeTypes := []client.Object{}
+	for _, router := range []Router{&IngressRouter{}, &GatewayRouter{}, &TraefikRouter{}} {
+		routeTypes = append(routeTypes, router.RouteTypes()...)
+	}
+
+	return routeTypes
+}
+
+// RouteName returns the name of the routing object of a domain of an
+// application, for routers that generate an object per domain.
+func RouteName(appName, domain string) string {
+	return domainObjectName(appName, domain, "")
+}
+
+// routeLabels returns the labels of the routing objects of an application.
+func routeLabels(app *v1alpha1.Application) map[string]string {
+	return MergeDefaultLabels(
+		app.Labels,
+		map[string]string{InstanceLabel: app.Name},
+	)
+}
+
+// routePath returns the path prefix routed for an ingress path, which is
+// the whole domain when the path is empty.
+func routePath(path string) string {
+	if path == "" {
+		return "/"
+	}
+
+	return path
+}
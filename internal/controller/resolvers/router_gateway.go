@@ -0,0 +1,88 @@
+package resolvers
+
+import (
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// HTTPRouteGVK is the Gateway API HTTPRoute kind. Gateway API objects are
+// handled as unstructured objects, so the operator does not depend on the
+// Gateway API module.
+var HTTPRouteGVK = schema.GroupVersionKind{
+	Group:   "gateway.networking.k8s.io",
+	Version: "v1beta1",
+	Kind:    "HTTPRoute",
+}
+
+// GatewayRouter routes each domain of an application with a Gateway API
+// HTTPRoute attached to a shared Gateway. TLS is terminated by the
+// listeners of the Gateway.
+type GatewayRouter struct {
+	Name      string
+	Namespace string
+}
+
+var _ Router = &GatewayRouter{}
+
+func (r *GatewayRouter) BuildRoutes(app *v1alpha1.Application, processes []Process) ([]client.Object, error) {
+	routes := []client.Object{}
+
+	parentRef := map[string]interface{}{
+		"group": HTTPRouteGVK.Group,
+		"kind":  "Gateway",
+		"name":  r.Name,
+	}
+	if r.Namespace != "" {
+		parentRef["namespace"] = r.Namespace
+	}
+
+	for _, ingressRule := range BuildIngressRules(processes) {
+		rules := []interface{}{}
+		for _, path := range ingressRule.HTTP.Paths {
+			rules = append(rules, map[string]interface{}{
+				"matches": []interface{}{
+					map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":  "PathPrefix",
+							"value": routePath(path.Path),
+						},
+					},
+				},
+				"backendRefs": []interface{}{
+					map[string]interface{}{
+						"name": path.Backend.Service.Name,
+						"port": int64(path.Backend.Service.Port.Number),
+					},
+				},
+			})
+		}
+
+		route := &unstructured.Unstructured{}
+		route.SetGroupVersionKind(HTTPRouteGVK)
+		route.SetName(RouteName(app.Name, ingressRule.Host))
+		route.SetNamespace(app.Namespace)
+		route.SetLabels(routeLabels(app))
+		route.Object["spec"] = map[string]interface{}{
+			"parentRefs": []interface{}{parentRef},
+			"hostnames":  []interface{}{ingressRule.Host},
+			"rules":      rules,
+		}
+
+		routes = append(routes, route)
+	}
+
+	return routes, nil
+}
+
+func (r *GatewayRouter) RouteTypes() []client.Object {
+	route := &unstructured.Unstructured{}
+	route.SetGroupVersionKind(HTTPRouteGVK)
+
+	return []client.Object{route}
+}
+
+func (r *GatewayRouter) TerminatesTLS() bool {
+	return false
+}
@@ -0,0 +1,88 @@
+package resolvers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestGatewayRouterBuildRoutes(t *testing.T) {
+	app := &v1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "default"},
+	}
+	processes := []Process{
+		{
+			Name:         "web",
+			ResourceName: "shop",
+			Endpoints: v1alpha1.ApplicationEndpoints{
+				{Port: 80, Domain: "shop.example.com", DomainPath: "/"},
+				{Port: 80, Domain: "shop.example.com", DomainPath: "/admin"},
+			},
+		},
+		{
+			Name:         "api",
+			ResourceName: "shop-api",
+			Endpoints:    v1alpha1.ApplicationEndpoints{{Port: 3000, Domain: "api.example.com", DomainPath: "/"}},
+		},
+	}
+
+	router := &GatewayRouter{Name: "public", Namespace: "gateways"}
+	routes, err := router.BuildRoutes(app, processes)
+	if err != nil {
+		t.Fatalf("BuildRoutes() error = %v", err)
+	}
+	if len(routes) != 2 {
+		t.Fatalf("BuildRoutes() = %d routes, want 2", len(routes))
+	}
+
+	route, ok := routes[1].(*unstructured.Unstructured)
+	if !ok {
+		t.Fatalf("BuildRoutes() = %T, want *unstructured.Unstructured", routes[1])
+	}
+	if route.GroupVersionKind() != HTTPRouteGVK {
+		t.Errorf("BuildRoutes() kind = %v, want %v", route.GroupVersionKind(), HTTPRouteGVK)
+	}
+	if route.GetName() != "shop-shop-example-com-951623a2" {
+		t.Errorf("BuildRoutes() name = %v, want shop-shop-example-com-951623a2", route.GetName())
+	}
+
+	want := map[string]interface{}{
+		"parentRefs": []interface{}{
+			map[string]interface{}{
+				"group":     "gateway.networking.k8s.io",
+				"kind":      "Gateway",
+				"name":      "public",
+				"namespace": "gateways",
+			},
+		},
+		"hostnames": []interface{}{"shop.example.com"},
+		"rules": []interface{}{
+			map[string]interface{}{
+				"matches": []interface{}{
+					map[string]interface{}{"path": map[string]interface{}{"type": "PathPrefix", "value": "/"}},
+				},
+				"backendRefs": []interface{}{
+					map[string]interface{}{"name": "shop", "port": int64(80)},
+				},
+			},
+			map[string]interface{}{
+				"matches": []interface{}{
+					map[string]interface{}{"path": map[string]interface{}{"type": "PathPrefix", "value": "/admin"}},
+				},
+				"backendRefs": []interface{}{
+					map[string]interface{}{"name": "shop", "port": int64(80)},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(route.Object["spec"], want) {
+		t.Errorf("BuildRoutes() spec = %v, want %v", route.Object["spec"], want)
+	}
+
+	if router.TerminatesTLS() {
+		t.Errorf("TerminatesTLS() = true, want false")
+	}
+}
@@ -0,0 +1,52 @@
+package resolvers
+
+import (
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// IngressRouter routes the domains of an application with a single
+// networking/v1 Ingress. Certificates are requested from cert-manager with
+// the ingress annotations.
+type IngressRouter struct {
+	IngressClassName string
+}
+
+var _ Router = &IngressRouter{}
+
+func (r *IngressRouter) BuildRoutes(app *v1alpha1.Application, processes []Process) ([]client.Object, error) {
+	rules := BuildIngressRules(processes)
+	if len(rules) == 0 {
+		return []client.Object{}, nil
+	}
+
+	domainsTLS := ResolveDomainTLS(app.Name, app.Spec.TLS, processes)
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        app.Name,
+			Namespace:   app.Namespace,
+			Labels:      routeLabels(app),
+			Annotations: TLSIssuerAnnotations(domainsTLS),
+		},
+		Spec: networkingv1.IngressSpec{
+			TLS:   BuildIngressTLS(domainsTLS),
+			Rules: rules,
+		},
+	}
+	if r.IngressClassName != "" {
+		ingressClassName := r.IngressClassName
+		ingress.Spec.IngressClassName = &ingressClassName
+	}
+
+	return []client.Object{ingress}, nil
+}
+
+func (r *IngressRouter) RouteTypes() []client.Object {
+	return []client.Object{&networkingv1.Ingress{}}
+}
+
+func (r *IngressRouter) TerminatesTLS() bool {
+	return true
+}
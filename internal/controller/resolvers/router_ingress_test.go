@@ -0,0 +1,88 @@
+package resolvers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIngressRouterBuildRoutes(t *testing.T) {
+	app := &v1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "default"},
+		Spec: v1alpha1.ApplicationSpec{
+			TLS: &v1alpha1.EndpointTLS{Issuer: "letsencrypt"},
+		},
+	}
+
+	tests := []struct {
+		name             string
+		ingressClassName string
+		processes        []Process
+		wantRoutes       int
+		wantClassName    *string
+	}{
+		{
+			name:       "should not build an ingress without domains",
+			processes:  []Process{{Name: "web", ResourceName: "shop", Endpoints: v1alpha1.ApplicationEndpoints{{Port: 80}}}},
+			wantRoutes: 0,
+		},
+		{
+			name: "should use the default ingress class",
+			processes: []Process{{
+				Name:         "web",
+				ResourceName: "shop",
+				Endpoints:    v1alpha1.ApplicationEndpoints{{Port: 80, Domain: "shop.example.com", DomainPath: "/"}},
+			}},
+			wantRoutes: 1,
+		},
+		{
+			name:             "should set the ingress class",
+			ingressClassName: "nginx",
+			processes: []Process{{
+				Name:         "web",
+				ResourceName: "shop",
+				Endpoints:    v1alpha1.ApplicationEndpoints{{Port: 80, Domain: "shop.example.com", DomainPath: "/"}},
+			}},
+			wantRoutes:    1,
+			wantClassName: &[]string{"nginx"}[0],
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := &IngressRouter{IngressClassName: tt.ingressClassName}
+			routes, err := router.BuildRoutes(app, tt.processes)
+			if err != nil {
+				t.Fatalf("BuildRoutes() error = %v", err)
+			}
+			if len(routes) != tt.wantRoutes {
+				t.Fatalf("BuildRoutes() = %d routes, want %d", len(routes), tt.wantRoutes)
+			}
+			if tt.wantRoutes == 0 {
+				return
+			}
+
+			ingress, ok := routes[0].(*networkingv1.Ingress)
+			if !ok {
+				t.Fatalf("BuildRoutes() = %T, want *networkingv1.Ingress", routes[0])
+			}
+			if ingress.Name != "shop" || ingress.Namespace != "default" {
+				t.Errorf("BuildRoutes() name = %s/%s, want default/shop", ingress.Namespace, ingress.Name)
+			}
+			if !reflect.DeepEqual(ingress.Spec.IngressClassName, tt.wantClassName) {
+				t.Errorf("BuildRoutes() ingressClassName = %v, want %v", ingress.Spec.IngressClassName, tt.wantClassName)
+			}
+			if _, exists := ingress.Labels["kubernetes.io/ingress.class"]; exists {
+				t.Errorf("BuildRoutes() labels = %v, want no ingress class label", ingress.Labels)
+			}
+			if ingress.Annotations[ClusterIssuerAnnotation] != "letsencrypt" {
+				t.Errorf("BuildRoutes() annotations = %v, want cluster issuer", ingress.Annotations)
+			}
+			if len(ingress.Spec.TLS) != 1 {
+				t.Errorf("BuildRoutes() tls = %v, want 1 entry", ingress.Spec.TLS)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package resolvers
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+func TestNewRouter(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  RouterConfig
+		want    Router
+		wantErr error
+	}{
+		{
+			name:   "should default to the ingress router",
+			config: RouterConfig{},
+			want:   &IngressRouter{},
+		},
+		{
+			name:   "should configure the ingress class",
+			config: RouterConfig{Kind: RouterIngress, IngressClassName: "nginx"},
+			want:   &IngressRouter{IngressClassName: "nginx"},
+		},
+		{
+			name:   "should configure the gateway",
+			config: RouterConfig{Kind: RouterGateway, GatewayName: "public", GatewayNamespace: "gateways"},
+			want:   &GatewayRouter{Name: "public", Namespace: "gateways"},
+		},
+		{
+			name:    "should require a gateway name",
+			config:  RouterConfig{Kind: RouterGateway},
+			wantErr: ErrInvalidRouter,
+		},
+		{
+			name:   "should configure the traefik entry points",
+			config: RouterConfig{Kind: RouterTraefik, TraefikEntryPoints: []string{"websecure"}},
+			want:   &TraefikRouter{EntryPoints: []string{"websecure"}},
+		},
+		{
+			name:    "should reject unknown routers",
+			config:  RouterConfig{Kind: "nginx"},
+			wantErr: ErrInvalidRouter,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewRouter(tt.config)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewRouter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewRouter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouteName(t *testing.T) {
+	tests := []struct {
+		name   string
+		domain string
+		want   string
+	}{
+		{
+			name:   "should replace dots",
+			domain: "shop.example.com",
+			want:   "shop-shop-example-com-951623a2",
+		},
+		{
+			name:   "should replace wildcards",
+			domain: "*.Example.com",
+			want:   "shop-wildcard-example-com-47287a8f",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RouteName("shop", tt.domain); got != tt.want {
+				t.Errorf("RouteName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if RouteName("shop", "a-b.com") == RouteName("shop", "a.b.com") {
+		t.Errorf("RouteName() of a-b.com and a.b.com are both %v", RouteName("shop", "a.b.com"))
+	}
+}
+
+func TestRouteTypes(t *testing.T) {
+	kinds := []string{}
+	for _, routeType := range RouteTypes() {
+		kind := routeType.GetObjectKind().GroupVersionKind().Kind
+		if _, isIngress := routeType.(*networkingv1.Ingress); isIngress {
+			kind = "Ingress"
+		}
+		kinds = append(kinds, kind)
+	}
+
+	want := []string{"Ingress", "HTTPRoute", "IngressRoute", "Certificate"}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Errorf("RouteTypes() kinds = %v, want %v", kinds, want)
+	}
+}
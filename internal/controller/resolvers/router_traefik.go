@@ -0,0 +1,130 @@
+package resolvers
+
+import (
+	"fmt"
+
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	// IngressRouteGVK is the Traefik IngressRoute kind.
+	IngressRouteGVK = schema.GroupVersionKind{
+		Group:   "traefik.io",
+		Version: "v1alpha1",
+		Kind:    "IngressRoute",
+	}
+
+	// CertificateGVK is the cert-manager Certificate kind.
+	CertificateGVK = schema.GroupVersionKind{
+		Group:   "cert-manager.io",
+		Version: "v1",
+		Kind:    "Certificate",
+	}
+)
+
+// TraefikRouter routes each domain of an application with a Traefik
+// IngressRoute. cert-manager does not watch IngressRoutes, so Certificates
+// are generated for the domains that have an issuer.
+type TraefikRouter struct {
+	EntryPoints []string
+}
+
+var _ Router = &TraefikRouter{}
+
+func (r *TraefikRouter) BuildRoutes(app *v1alpha1.Application, processes []Process) ([]client.Object, error) {
+	objects := []client.Object{}
+
+	domainsTLS := map[string]DomainTLS{}
+	for _, domain := range ResolveDomainTLS(app.Name, app.Spec.TLS, processes) {
+		domainsTLS[domain.Host] = domain
+	}
+
+	for _, ingressRule := range BuildIngressRules(processes) {
+		routes := []interface{}{}
+		for _, path := range ingressRule.HTTP.Paths {
+			routes = append(routes, map[string]interface{}{
+				"kind":  "Rule",
+				"match": fmt.Sprintf("Host(`%s`) && PathPrefix(`%s`)", ingressRule.Host, routePath(path.Path)),
+				"services": []interface{}{
+					map[string]interface{}{
+						"name": path.Backend.Service.Name,
+						"port": int64(path.Backend.Service.Port.Number),
+					},
+				},
+			})
+		}
+
+		spec := map[string]interface{}{
+			"routes": routes,
+		}
+		if len(r.EntryPoints) > 0 {
+			entryPoints := make([]interface{}, 0, len(r.EntryPoints))
+			for _, entryPoint := range r.EntryPoints {
+				entryPoints = append(entryPoints, entryPoint)
+			}
+			spec["entryPoints"] = entryPoints
+		}
+
+		domainTLS, hasTLS := domainsTLS[ingressRule.Host]
+		if hasTLS {
+			spec["tls"] = map[string]interface{}{
+				"secretName": domainTLS.SecretName,
+			}
+		}
+
+		route := &unstructured.Unstructured{}
+		route.SetGroupVersionKind(IngressRouteGVK)
+		route.SetName(RouteName(app.Name, ingressRule.Host))
+		route.SetNamespace(app.Namespace)
+		route.SetLabels(routeLabels(app))
+		route.Object["spec"] = spec
+		objects = append(objects, route)
+
+		if hasTLS && domainTLS.Issuer != "" {
+			objects = append(objects, buildCertificate(app, domainTLS))
+		}
+	}
+
+	return objects, nil
+}
+
+func (r *TraefikRouter) RouteTypes() []client.Object {
+	route := &unstructured.Unstructured{}
+	route.SetGroupVersionKind(IngressRouteGVK)
+	certificate := &unstructured.Unstructured{}
+	certificate.SetGroupVersionKind(CertificateGVK)
+
+	return []client.Object{route, certificate}
+}
+
+func (r *TraefikRouter) TerminatesTLS() bool {
+	return true
+}
+
+// buildCertificate builds the cert-manager Certificate of a domain.
+func buildCertificate(app *v1alpha1.Application, domain DomainTLS) *unstructured.Unstructured {
+	issuerKind := domain.IssuerKind
+	if issuerKind == "" {
+		issuerKind = "ClusterIssuer"
+	}
+
+	certificate := &unstructured.Unstructured{}
+	certificate.SetGroupVersionKind(CertificateGVK)
+	certificate.SetName(domain.SecretName)
+	certificate.SetNamespace(app.Namespace)
+	certificate.SetLabels(routeLabels(app))
+	certificate.Object["spec"] = map[string]interface{}{
+		"secretName": domain.SecretName,
+		"dnsNames":   []interface{}{domain.Host},
+		"issuerRef": map[string]interface{}{
+			"group": CertificateGVK.Group,
+			"kind":  issuerKind,
+			"name":  domain.Issuer,
+		},
+	}
+
+	return certificate
+}
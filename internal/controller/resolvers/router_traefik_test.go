@@ -0,0 +1,117 @@
+package resolvers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestTraefikRouterBuildRoutes(t *testing.T) {
+	tests := []struct {
+		name      string
+		appTLS    *v1alpha1.EndpointTLS
+		processes []Process
+		want      []map[string]interface{}
+	}{
+		{
+			name: "should route domains without tls",
+			processes: []Process{{
+				Name:         "web",
+				ResourceName: "shop",
+				Endpoints:    v1alpha1.ApplicationEndpoints{{Port: 80, Domain: "shop.example.com", DomainPath: "/"}},
+			}},
+			want: []map[string]interface{}{
+				{
+					"apiVersion": "traefik.io/v1alpha1",
+					"kind":       "IngressRoute",
+					"spec": map[string]interface{}{
+						"entryPoints": []interface{}{"websecure"},
+						"routes": []interface{}{
+							map[string]interface{}{
+								"kind":  "Rule",
+								"match": "Host(`shop.example.com`) && PathPrefix(`/`)",
+								"services": []interface{}{
+									map[string]interface{}{"name": "shop", "port": int64(80)},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:   "should issue certificates of tls domains",
+			appTLS: &v1alpha1.EndpointTLS{Issuer: "letsencrypt"},
+			processes: []Process{{
+				Name:         "web",
+				ResourceName: "shop",
+				Endpoints:    v1alpha1.ApplicationEndpoints{{Port: 80, Domain: "shop.example.com", DomainPath: "/"}},
+			}},
+			want: []map[string]interface{}{
+				{
+					"apiVersion": "traefik.io/v1alpha1",
+					"kind":       "IngressRoute",
+					"spec": map[string]interface{}{
+						"entryPoints": []interface{}{"websecure"},
+						"routes": []interface{}{
+							map[string]interface{}{
+								"kind":  "Rule",
+								"match": "Host(`shop.example.com`) && PathPrefix(`/`)",
+								"services": []interface{}{
+									map[string]interface{}{"name": "shop", "port": int64(80)},
+								},
+							},
+						},
+						"tls": map[string]interface{}{"secretName": "shop-shop-example-com-951623a2-tls"},
+					},
+				},
+				{
+					"apiVersion": "cert-manager.io/v1",
+					"kind":       "Certificate",
+					"spec": map[string]interface{}{
+						"secretName": "shop-shop-example-com-951623a2-tls",
+						"dnsNames":   []interface{}{"shop.example.com"},
+						"issuerRef": map[string]interface{}{
+							"group": "cert-manager.io",
+							"kind":  "ClusterIssuer",
+							"name":  "letsencrypt",
+						},
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &v1alpha1.Application{
+				ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "default"},
+				Spec:       v1alpha1.ApplicationSpec{TLS: tt.appTLS},
+			}
+			router := &TraefikRouter{EntryPoints: []string{"websecure"}}
+
+			routes, err := router.BuildRoutes(app, tt.processes)
+			if err != nil {
+				t.Fatalf("BuildRoutes() error = %v", err)
+			}
+
+			got := make([]map[string]interface{}, 0, len(routes))
+			for _, route := range routes {
+				obj, ok := route.(*unstructured.Unstructured)
+				if !ok {
+					t.Fatalf("BuildRoutes() = %T, want *unstructured.Unstructured", route)
+				}
+				got = append(got, map[string]interface{}{
+					"apiVersion": obj.GetAPIVersion(),
+					"kind":       obj.GetKind(),
+					"spec":       obj.Object["spec"],
+				})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BuildRoutes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
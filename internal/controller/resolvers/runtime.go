@@ -3,46 +3,79 @@ package resolvers
 import (
 	"github.com/perfectmak/k4indie/api/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/resource"
 )
 
-func getCpuAndMemoryForRuntimeSize(size v1alpha1.RuntimeSize) (cpu, memory string, err error) {
-	switch size {
-	case v1alpha1.BasicMachineType:
-		cpu = "256m"
-		memory = "256Mi"
-	case v1alpha1.Basic2xMachineType:
-		cpu = "500m"
-		memory = "256Mi"
-	case v1alpha1.StandardMachineType:
-		cpu = "500m"
-		memory = "512Mi"
-	case v1alpha1.Standard2xMachineType:
-		cpu = "1"
-		memory = "1Gi"
-	case v1alpha1.PerformanceMachineType:
-		cpu = "2"
-		memory = "2Gi"
-	default:
-		err = v1alpha1.ErrInvalidRuntimeSize
-	}
-	return
-}
+// GetResourcesForRuntimeSize returns the resource requirements of the pods
+// running on the runtime size. Resources without a limit are limited to
+// their requests.
+func GetResourcesForRuntimeSize(size *v1alpha1.RuntimeSize) corev1.ResourceRequirements {
+	requests := corev1.ResourceList{
+		corev1.ResourceCPU:    size.Spec.Requests.CPU.DeepCopy(),
+		corev1.ResourceMemory: size.Spec.Requests.Memory.DeepCopy(),
+	}
+	if size.Spec.Requests.EphemeralStorage != nil {
+		requests[corev1.ResourceEphemeralStorage] = size.Spec.Requests.EphemeralStorage.DeepCopy()
+	}
 
-func GetResourcesForRuntimeSize(size v1alpha1.RuntimeSize) (corev1.ResourceRequirements, error) {
-	cpu, memory, err := getCpuAndMemoryForRuntimeSize(size)
-	if err != nil {
-		return corev1.ResourceRequirements{}, err
+	limits := requests.DeepCopy()
+	if size.Spec.Limits != nil {
+		if size.Spec.Limits.CPU != nil {
+			limits[corev1.ResourceCPU] = size.Spec.Limits.CPU.DeepCopy()
+		}
+		if size.Spec.Limits.Memory != nil {
+			limits[corev1.ResourceMemory] = size.Spec.Limits.Memory.DeepCopy()
+		}
+		if size.Spec.Limits.EphemeralStorage != nil {
+			limits[corev1.ResourceEphemeralStorage] = size.Spec.Limits.EphemeralStorage.DeepCopy()
+		}
 	}
 
 	return corev1.ResourceRequirements{
-		Limits: corev1.ResourceList{
-			corev1.ResourceCPU:    resource.MustParse(cpu),
-			corev1.ResourceMemory: resource.MustParse(memory),
-		},
-		Requests: corev1.ResourceList{
-			corev1.ResourceCPU:    resource.MustParse(cpu),
-			corev1.ResourceMemory: resource.MustParse(memory),
-		},
-	}, nil
+		Limits:   limits,
+		Requests: requests,
+	}
+}
+
+// ApplyResourceOverrides returns the resources with the overridden requests
+// and limits replaced. A request raised above its limit raises the limit
+// too, unless the limit is overridden as well, since sizes limit resources
+// to their requests by default. Likewise, a limit lowered below its request
+// lowers the request, unless the request is overridden as well.
+func ApplyResourceOverrides(
+	resources corev1.ResourceRequirements,
+	overrides *corev1.ResourceRequirements,
+) corev1.ResourceRequirements {
+	result := *resources.DeepCopy()
+	if overrides == nil {
+		return result
+	}
+	if result.Requests == nil {
+		result.Requests = corev1.ResourceList{}
+	}
+	if result.Limits == nil {
+		result.Limits = corev1.ResourceList{}
+	}
+
+	for name, quantity := range overrides.Requests {
+		result.Requests[name] = quantity.DeepCopy()
+
+		if _, overridden := overrides.Limits[name]; overridden {
+			continue
+		}
+		if limit, exists := result.Limits[name]; exists && limit.Cmp(quantity) < 0 {
+			result.Limits[name] = quantity.DeepCopy()
+		}
+	}
+	for name, quantity := range overrides.Limits {
+		result.Limits[name] = quantity.DeepCopy()
+
+		if _, overridden := overrides.Requests[name]; overridden {
+			continue
+		}
+		if request, exists := result.Requests[name]; exists && request.Cmp(quantity) > 0 {
+			result.Requests[name] = quantity.DeepCopy()
+		}
+	}
+
+	return result
 }
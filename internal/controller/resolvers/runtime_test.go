@@ -10,19 +10,23 @@ import (
 )
 
 func TestGetResourcesForRuntimeSize(t *testing.T) {
-	type args struct {
-		size v1alpha1.RuntimeSize
+	quantity := func(value string) *resource.Quantity {
+		q := resource.MustParse(value)
+		return &q
 	}
+
 	tests := []struct {
-		name    string
-		args    args
-		want    corev1.ResourceRequirements
-		wantErr bool
+		name string
+		spec v1alpha1.RuntimeSizeSpec
+		want corev1.ResourceRequirements
 	}{
 		{
-			name: "basic",
-			args: args{
-				size: v1alpha1.BasicMachineType,
+			name: "should limit resources to requests",
+			spec: v1alpha1.RuntimeSizeSpec{
+				Requests: v1alpha1.RuntimeSizeResources{
+					CPU:    resource.MustParse("256m"),
+					Memory: resource.MustParse("256Mi"),
+				},
 			},
 			want: corev1.ResourceRequirements{
 				Limits: corev1.ResourceList{
@@ -34,27 +38,124 @@ func TestGetResourcesForRuntimeSize(t *testing.T) {
 					corev1.ResourceMemory: resource.MustParse("256Mi"),
 				},
 			},
-			wantErr: false,
 		},
 		{
-			name: "incorrect size",
-			args: args{
-				size: "incorrect",
+			name: "should let resources burst up to their limits",
+			spec: v1alpha1.RuntimeSizeSpec{
+				Requests: v1alpha1.RuntimeSizeResources{
+					CPU:              resource.MustParse("250m"),
+					Memory:           resource.MustParse("512Mi"),
+					EphemeralStorage: quantity("1Gi"),
+				},
+				Limits: &v1alpha1.RuntimeSizeLimits{
+					CPU: quantity("1"),
+				},
+			},
+			want: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{
+					corev1.ResourceCPU:              resource.MustParse("1"),
+					corev1.ResourceMemory:           resource.MustParse("512Mi"),
+					corev1.ResourceEphemeralStorage: resource.MustParse("1Gi"),
+				},
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:              resource.MustParse("250m"),
+					corev1.ResourceMemory:           resource.MustParse("512Mi"),
+					corev1.ResourceEphemeralStorage: resource.MustParse("1Gi"),
+				},
 			},
-			want:    corev1.ResourceRequirements{},
-			wantErr: true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := GetResourcesForRuntimeSize(tt.args.size)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("GetResourcesForRuntimeSize() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
-			if !reflect.DeepEqual(got, tt.want) {
+			size := &v1alpha1.RuntimeSize{Spec: tt.spec}
+			if got := GetResourcesForRuntimeSize(size); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("GetResourcesForRuntimeSize() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestApplyResourceOverrides(t *testing.T) {
+	size := corev1.ResourceRequirements{
+		Limits: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("500m"),
+			corev1.ResourceMemory: resource.MustParse("256Mi"),
+		},
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("500m"),
+			corev1.ResourceMemory: resource.MustParse("256Mi"),
+		},
+	}
+
+	tests := []struct {
+		name      string
+		overrides *corev1.ResourceRequirements
+		want      corev1.ResourceRequirements
+	}{
+		{
+			name:      "should keep the size without overrides",
+			overrides: nil,
+			want:      size,
+		},
+		{
+			name: "should raise the limit along with the request",
+			overrides: &corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("556Mi")},
+			},
+			want: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("500m"),
+					corev1.ResourceMemory: resource.MustParse("556Mi"),
+				},
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("500m"),
+					corev1.ResourceMemory: resource.MustParse("556Mi"),
+				},
+			},
+		},
+		{
+			name: "should override limits and extend resources",
+			overrides: &corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:              resource.MustParse("250m"),
+					corev1.ResourceEphemeralStorage: resource.MustParse("1Gi"),
+				},
+				Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+			},
+			want: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("1"),
+					corev1.ResourceMemory: resource.MustParse("256Mi"),
+				},
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:              resource.MustParse("250m"),
+					corev1.ResourceMemory:           resource.MustParse("256Mi"),
+					corev1.ResourceEphemeralStorage: resource.MustParse("1Gi"),
+				},
+			},
+		},
+		{
+			name: "should lower the request along with the limit",
+			overrides: &corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("128Mi")},
+			},
+			want: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("500m"),
+					corev1.ResourceMemory: resource.MustParse("128Mi"),
+				},
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("500m"),
+					corev1.ResourceMemory: resource.MustParse("128Mi"),
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ApplyResourceOverrides(size, tt.overrides); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ApplyResourceOverrides() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package resolvers
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Schedule is a resolved scheduled task of an application with all the
+// application level defaults applied.
+type Schedule struct {
+	// Name of the scheduled task.
+	Name string
+	// ResourceName is the name of the CronJob generated for this task.
+	ResourceName      string
+	Schedule          string
+	Command           []string
+	Size              v1alpha1.RuntimeSizeName
+	ConcurrencyPolicy batchv1.ConcurrencyPolicy
+	TimeZone          *string
+}
+
+// ResolveSchedules returns the scheduled tasks of the application sorted by
+// name.
+func ResolveSchedules(app *v1alpha1.Application) []Schedule {
+	schedules := make([]Schedule, 0, len(app.Spec.Schedules))
+	for name, spec := range app.Spec.Schedules {
+		schedule := Schedule{
+			Name:              name,
+			ResourceName:      fmt.Sprintf("%s-%s", app.Name, name),
+			Schedule:          spec.Schedule,
+			Command:           spec.Command,
+			Size:              spec.Size,
+			ConcurrencyPolicy: spec.ConcurrencyPolicy,
+			TimeZone:          spec.TimeZone,
+		}
+		if schedule.Size == "" {
+			schedule.Size = app.Spec.Runtime.Size
+		}
+		if schedule.ConcurrencyPolicy == "" {
+			schedule.ConcurrencyPolicy = batchv1.ForbidConcurrent
+		}
+
+		schedules = append(schedules, schedule)
+	}
+
+	sort.Slice(schedules, func(i, j int) bool {
+		return schedules[i].Name < schedules[j].Name
+	})
+
+	return schedules
+}
+
+// ScheduleLabels returns the labels of the pods of a scheduled task.
+func ScheduleLabels(appName, scheduleName string) map[string]string {
+	return MergeDefaultLabels(map[string]string{
+		InstanceLabel: appName,
+		ScheduleLabel: scheduleName,
+	})
+}
+
+// ResolveScheduleStatus returns the status of a scheduled task from its
+// CronJob and the Jobs it created. The last failure is taken from the most
+// recent failed Job, since CronJobs only report their successful runs.
+func ResolveScheduleStatus(name string, cronJob *batchv1.CronJob, jobs []batchv1.Job) v1alpha1.ScheduleStatus {
+	status := v1alpha1.ScheduleStatus{
+		Name:               name,
+		LastScheduleTime:   cronJob.Status.LastScheduleTime,
+		LastSuccessfulTime: cronJob.Status.LastSuccessfulTime,
+	}
+
+	for _, job := range jobs {
+		for _, condition := range job.Status.Conditions {
+			if condition.Type != batchv1.JobFailed || condition.Status != corev1.ConditionTrue {
+				continue
+			}
+			if status.LastFailureTime != nil && !status.LastFailureTime.Before(&condition.LastTransitionTime) {
+				continue
+			}
+
+			failureTime := condition.LastTransitionTime
+			status.LastFailureTime = &failureTime
+			status.LastFailureMessage = fmt.Sprintf("job %s failed: %s", job.Name, condition.Message)
+		}
+	}
+
+	return status
+}
+
+// IsScheduleFailing reports whether the last run of a scheduled task failed.
+func IsScheduleFailing(status v1alpha1.ScheduleStatus) bool {
+	if status.LastFailureTime == nil {
+		return false
+	}
+	if status.LastSuccessfulTime == nil {
+		return true
+	}
+
+	return status.LastSuccessfulTime.Before(status.LastFailureTime)
+}
@@ -0,0 +1,125 @@
+package resolvers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestResolveSchedules(t *testing.T) {
+	timeZone := "Europe/Berlin"
+	app := &v1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "shop"},
+		Spec: v1alpha1.ApplicationSpec{
+			Runtime: v1alpha1.ApplicationRuntime{Size: v1alpha1.BasicMachineType},
+			Schedules: map[string]v1alpha1.ApplicationSchedule{
+				"digest": {
+					Schedule: "0 3 * * *",
+					Command:  []string{"send-digest"},
+					TimeZone: &timeZone,
+				},
+				"cleanup": {
+					Schedule:          "*/5 * * * *",
+					Command:           []string{"cleanup"},
+					Size:              v1alpha1.PerformanceMachineType,
+					ConcurrencyPolicy: batchv1.ReplaceConcurrent,
+				},
+			},
+		},
+	}
+
+	want := []Schedule{
+		{
+			Name:              "cleanup",
+			ResourceName:      "shop-cleanup",
+			Schedule:          "*/5 * * * *",
+			Command:           []string{"cleanup"},
+			Size:              v1alpha1.PerformanceMachineType,
+			ConcurrencyPolicy: batchv1.ReplaceConcurrent,
+		},
+		{
+			Name:              "digest",
+			ResourceName:      "shop-digest",
+			Schedule:          "0 3 * * *",
+			Command:           []string{"send-digest"},
+			Size:              v1alpha1.BasicMachineType,
+			ConcurrencyPolicy: batchv1.ForbidConcurrent,
+			TimeZone:          &timeZone,
+		},
+	}
+	if got := ResolveSchedules(app); !reflect.DeepEqual(got, want) {
+		t.Errorf("ResolveSchedules() = %v, want %v", got, want)
+	}
+}
+
+func TestResolveScheduleStatus(t *testing.T) {
+	now := time.Now()
+	at := func(minutes int) metav1.Time {
+		return metav1.NewTime(now.Add(time.Duration(minutes) * time.Minute))
+	}
+	failedJob := func(name string, minutes int) batchv1.Job {
+		return batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: batchv1.JobStatus{
+				Conditions: []batchv1.JobCondition{{
+					Type:               batchv1.JobFailed,
+					Status:             corev1.ConditionTrue,
+					Message:            "BackoffLimitExceeded",
+					LastTransitionTime: at(minutes),
+				}},
+			},
+		}
+	}
+	lastSchedule := at(-5)
+	lastSuccess := at(-10)
+
+	tests := []struct {
+		name        string
+		jobs        []batchv1.Job
+		wantMessage string
+		wantFailing bool
+	}{
+		{
+			name: "should not fail without failed jobs",
+			jobs: []batchv1.Job{},
+		},
+		{
+			name:        "should report the most recent failure",
+			jobs:        []batchv1.Job{failedJob("shop-digest-1", -4), failedJob("shop-digest-2", -1)},
+			wantMessage: "job shop-digest-2 failed: BackoffLimitExceeded",
+			wantFailing: true,
+		},
+		{
+			name:        "should recover after a successful run",
+			jobs:        []batchv1.Job{failedJob("shop-digest-1", -20)},
+			wantMessage: "job shop-digest-1 failed: BackoffLimitExceeded",
+			wantFailing: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cronJob := &batchv1.CronJob{
+				Status: batchv1.CronJobStatus{
+					LastScheduleTime:   &lastSchedule,
+					LastSuccessfulTime: &lastSuccess,
+				},
+			}
+
+			got := ResolveScheduleStatus("digest", cronJob, tt.jobs)
+			if got.Name != "digest" || got.LastScheduleTime != &lastSchedule || got.LastSuccessfulTime != &lastSuccess {
+				t.Errorf("ResolveScheduleStatus() = %v, want times of the cronjob", got)
+			}
+			if got.LastFailureMessage != tt.wantMessage {
+				t.Errorf("ResolveScheduleStatus() message = %v, want %v", got.LastFailureMessage, tt.wantMessage)
+			}
+			if failing := IsScheduleFailing(got); failing != tt.wantFailing {
+				t.Errorf("IsScheduleFailing() = %v, want %v", failing, tt.wantFailing)
+			}
+		})
+	}
+}
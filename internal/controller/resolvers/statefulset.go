@@ -0,0 +1,96 @@
+package resolvers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// IsStateful reports whether the processes of the application run in
+// StatefulSets instead of Deployments.
+func IsStateful(app *v1alpha1.Application) bool {
+	return app.Spec.Workload == v1alpha1.WorkloadStateful
+}
+
+// HeadlessServiceName returns the name of the headless Service giving the
+// replicas of a stateful process their stable network identity.
+func HeadlessServiceName(resourceName string) string {
+	return fmt.Sprintf("%s-headless", resourceName)
+}
+
+// BuildVolumeClaimTemplates builds the claim templates of a StatefulSet, so
+// each replica gets its own copy of the volumes of the application. The
+// claims are named after the volume, the StatefulSet and the replica.
+func BuildVolumeClaimTemplates(
+	volumes []v1alpha1.ApplicationVolume,
+	labels map[string]string,
+) []corev1.PersistentVolumeClaim {
+	if len(volumes) == 0 {
+		return nil
+	}
+
+	templates := make([]corev1.PersistentVolumeClaim, 0, len(volumes))
+	for _, volume := range volumes {
+		templates = append(templates, corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   volume.Name,
+				Labels: labels,
+			},
+			Spec: BuildVolumeClaimSpec(volume),
+		})
+	}
+
+	return templates
+}
+
+// ReplicaVolumeClaimVolume returns the volume a claim of a replica of the
+// StatefulSet named resourceName was created for, from the name of the
+// claim.
+func ReplicaVolumeClaimVolume(
+	claimName, resourceName string,
+	volumes []v1alpha1.ApplicationVolume,
+) (v1alpha1.ApplicationVolume, bool) {
+	for _, volume := range volumes {
+		prefix := fmt.Sprintf("%s-%s-", volume.Name, resourceName)
+		if !strings.HasPrefix(claimName, prefix) {
+			continue
+		}
+		if _, err := strconv.ParseUint(strings.TrimPrefix(claimName, prefix), 10, 32); err != nil {
+			continue
+		}
+
+		return volume, true
+	}
+
+	return v1alpha1.ApplicationVolume{}, false
+}
+
+// IsStatefulSetRecreateRequired reports whether the existing StatefulSet
+// differs from the desired one in fields that can't be updated, so it has to
+// be recreated. Fields that are not set on the desired StatefulSet, e.g.
+// defaults, are ignored.
+func IsStatefulSetRecreateRequired(desired, existing *appsv1.StatefulSet) bool {
+	if !equality.Semantic.DeepEqual(desired.Spec.Selector, existing.Spec.Selector) ||
+		desired.Spec.ServiceName != existing.Spec.ServiceName ||
+		desired.Spec.PodManagementPolicy != existing.Spec.PodManagementPolicy ||
+		len(desired.Spec.VolumeClaimTemplates) != len(existing.Spec.VolumeClaimTemplates) {
+		return true
+	}
+
+	for i, template := range desired.Spec.VolumeClaimTemplates {
+		existingTemplate := existing.Spec.VolumeClaimTemplates[i]
+		if template.Name != existingTemplate.Name ||
+			!equality.Semantic.DeepDerivative(template.Labels, existingTemplate.Labels) ||
+			!equality.Semantic.DeepDerivative(template.Spec, existingTemplate.Spec) {
+			return true
+		}
+	}
+
+	return false
+}
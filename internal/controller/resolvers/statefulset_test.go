@@ -0,0 +1,102 @@
+package resolvers
+
+import (
+	"testing"
+
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIsStatefulSetRecreateRequired(t *testing.T) {
+	volumes := []v1alpha1.ApplicationVolume{
+		{Name: "data", MountPath: "/data", Size: resource.MustParse("1Gi")},
+	}
+	statefulSet := func(volumes []v1alpha1.ApplicationVolume) *appsv1.StatefulSet {
+		return &appsv1.StatefulSet{
+			Spec: appsv1.StatefulSetSpec{
+				Selector:             &metav1.LabelSelector{MatchLabels: SelectorLabels("nats", "web")},
+				ServiceName:          HeadlessServiceName("nats"),
+				PodManagementPolicy:  appsv1.ParallelPodManagement,
+				VolumeClaimTemplates: BuildVolumeClaimTemplates(volumes, map[string]string{InstanceLabel: "nats"}),
+			},
+		}
+	}
+	// The API server defaults the claim templates and reports their status.
+	defaulted := statefulSet(volumes)
+	volumeMode := corev1.PersistentVolumeFilesystem
+	defaulted.Spec.VolumeClaimTemplates[0].Spec.VolumeMode = &volumeMode
+	defaulted.Spec.VolumeClaimTemplates[0].Status.Phase = corev1.ClaimPending
+
+	tests := []struct {
+		name     string
+		desired  *appsv1.StatefulSet
+		existing *appsv1.StatefulSet
+		want     bool
+	}{
+		{
+			name:     "should not recreate with defaulted claim templates",
+			desired:  statefulSet(volumes),
+			existing: defaulted,
+			want:     false,
+		},
+		{
+			name:     "should recreate when a volume grows",
+			desired:  statefulSet([]v1alpha1.ApplicationVolume{{Name: "data", MountPath: "/data", Size: resource.MustParse("2Gi")}}),
+			existing: defaulted,
+			want:     true,
+		},
+		{
+			name:     "should recreate when a volume is added",
+			desired:  statefulSet(append(volumes, v1alpha1.ApplicationVolume{Name: "logs", MountPath: "/logs", Size: resource.MustParse("1Gi")})),
+			existing: defaulted,
+			want:     true,
+		},
+		{
+			name: "should recreate with another service",
+			desired: func() *appsv1.StatefulSet {
+				s := statefulSet(volumes)
+				s.Spec.ServiceName = HeadlessServiceName("nats-web")
+				return s
+			}(),
+			existing: defaulted,
+			want:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsStatefulSetRecreateRequired(tt.desired, tt.existing); got != tt.want {
+				t.Errorf("IsStatefulSetRecreateRequired() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplicaVolumeClaimVolume(t *testing.T) {
+	volumes := []v1alpha1.ApplicationVolume{
+		{Name: "data", MountPath: "/data", Size: resource.MustParse("1Gi")},
+		{Name: "data-cache", MountPath: "/cache", Size: resource.MustParse("2Gi")},
+	}
+
+	tests := []struct {
+		claimName string
+		want      string
+		wantFound bool
+	}{
+		{claimName: "data-nats-0", want: "data", wantFound: true},
+		{claimName: "data-cache-nats-12", want: "data-cache", wantFound: true},
+		{claimName: "data-nats-api-0", wantFound: false},
+		{claimName: "data-nats-", wantFound: false},
+		{claimName: "uploads-nats-0", wantFound: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.claimName, func(t *testing.T) {
+			got, found := ReplicaVolumeClaimVolume(tt.claimName, "nats", volumes)
+			if found != tt.wantFound || got.Name != tt.want {
+				t.Errorf("ReplicaVolumeClaimVolume() = %v, %v, want %v, %v", got.Name, found, tt.want, tt.wantFound)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package resolvers
+
+import (
+	"sort"
+
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+const (
+	ClusterIssuerAnnotation = "cert-manager.io/cluster-issuer"
+	IssuerAnnotation        = "cert-manager.io/issuer"
+
+	IssuerKindIssuer = "Issuer"
+)
+
+// DomainTLS is the resolved TLS setting of a domain.
+type DomainTLS struct {
+	Host string
+	// SecretName is the Secret holding the certificate of the domain.
+	SecretName string
+	// Issuer is the cert-manager issuer of the certificate. It is empty
+	// when the certificate is provided in an existing Secret.
+	Issuer     string
+	IssuerKind string
+}
+
+// ResolveDomainTLS returns the TLS settings of all the domains of the
+// processes, sorted by host. Endpoint settings override the application
+// settings, and for domains with multiple endpoints the first endpoint
+// with TLS settings wins.
+func ResolveDomainTLS(appName string, appTLS *v1alpha1.EndpointTLS, processes []Process) []DomainTLS {
+	domains := []DomainTLS{}
+	seen := map[string]struct{}{}
+
+	for _, process := range processes {
+		for _, endpoint := range EndpointsWithDomains(&process.Endpoints) {
+			tls := endpoint.TLS
+			if tls == nil {
+				tls = appTLS
+			}
+			if tls == nil || tls.Disabled {
+				continue
+			}
+			if _, exists := seen[endpoint.Domain]; exists {
+				continue
+			}
+			seen[endpoint.Domain] = struct{}{}
+
+			domain := DomainTLS{
+				Host:       endpoint.Domain,
+				SecretName: tls.SecretName,
+			}
+			if domain.SecretName == "" {
+				domain.SecretName = TLSSecretName(appName, endpoint.Domain)
+				domain.Issuer = tls.Issuer
+				domain.IssuerKind = tls.IssuerKind
+			}
+
+			domains = append(domains, domain)
+		}
+	}
+
+	sort.Slice(domains, func(i, j int) bool {
+		return domains[i].Host < domains[j].Host
+	})
+
+	return domains
+}
+
+// TLSSecretName returns the name of the Secret that holds the certificate
+// issued for a domain of an application.
+func TLSSecretName(appName, domain string) string {
+	return domainObjectName(appName, domain, "-tls")
+}
+
+// BuildIngressTLS builds the ingress TLS section of the domains.
+func BuildIngressTLS(domains []DomainTLS) []networkingv1.IngressTLS {
+	result := make([]networkingv1.IngressTLS, 0, len(domains))
+
+	for _, domain := range domains {
+		result = append(result, networkingv1.IngressTLS{
+			Hosts:      []string{domain.Host},
+			SecretName: domain.SecretName,
+		})
+	}
+
+	return result
+}
+
+// TLSIssuerAnnotations returns the cert-manager annotations requesting the
+// certificates of the domains. An ingress only supports a single issuer, so
+// applications using different issuers for their domains are rejected by
+// the webhook, and the issuer of the first domain that has one is used.
+func TLSIssuerAnnotations(domains []DomainTLS) map[string]string {
+	for _, domain := range domains {
+		if domain.Issuer == "" {
+			continue
+		}
+
+		if domain.IssuerKind == IssuerKindIssuer {
+			return map[string]string{IssuerAnnotation: domain.Issuer}
+		}
+		return map[string]string{ClusterIssuerAnnotation: domain.Issuer}
+	}
+
+	return map[string]string{}
+}
+
+// IssuedTLSSecretNames returns the names of the Secrets holding certificates
+// issued by cert-manager for the domains. Secrets provided by the user are
+// not included.
+func IssuedTLSSecretNames(domains []DomainTLS) []string {
+	names := make([]string, 0, len(domains))
+
+	for _, domain := range domains {
+		if domain.Issuer != "" {
+			names = append(names, domain.SecretName)
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,184 @@
+package resolvers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+)
+
+func TestResolveDomainTLS(t *testing.T) {
+	letsEncrypt := &v1alpha1.EndpointTLS{Issuer: "letsencrypt", IssuerKind: "ClusterIssuer"}
+
+	tests := []struct {
+		name      string
+		appTLS    *v1alpha1.EndpointTLS
+		processes []Process
+		want      []DomainTLS
+	}{
+		{
+			name: "should not enable tls by default",
+			processes: []Process{{
+				Endpoints: v1alpha1.ApplicationEndpoints{{Port: 80, Domain: "shop.example.com"}},
+			}},
+			want: []DomainTLS{},
+		},
+		{
+			name:   "should apply application tls to all domains",
+			appTLS: letsEncrypt,
+			processes: []Process{
+				{
+					Endpoints: v1alpha1.ApplicationEndpoints{
+						{Port: 80, Domain: "shop.example.com"},
+						{Port: 80, Domain: "shop.example.com", DomainPath: "/admin"},
+						{Port: 9090},
+					},
+				},
+				{
+					Endpoints: v1alpha1.ApplicationEndpoints{{Port: 3000, Domain: "*.api.example.com"}},
+				},
+			},
+			want: []DomainTLS{
+				{
+					Host:       "*.api.example.com",
+					SecretName: "shop-wildcard-api-example-com-3347b0b0-tls",
+					Issuer:     "letsencrypt",
+					IssuerKind: "ClusterIssuer",
+				},
+				{
+					Host:       "shop.example.com",
+					SecretName: "shop-shop-example-com-951623a2-tls",
+					Issuer:     "letsencrypt",
+					IssuerKind: "ClusterIssuer",
+				},
+			},
+		},
+		{
+			name:   "should let endpoints override application tls",
+			appTLS: letsEncrypt,
+			processes: []Process{{
+				Endpoints: v1alpha1.ApplicationEndpoints{
+					{Port: 80, Domain: "internal.example.com", TLS: &v1alpha1.EndpointTLS{Disabled: true}},
+					{Port: 80, Domain: "shop.example.com", TLS: &v1alpha1.EndpointTLS{SecretName: "shop-cert"}},
+				},
+			}},
+			want: []DomainTLS{
+				{
+					Host:       "shop.example.com",
+					SecretName: "shop-cert",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResolveDomainTLS("shop", tt.appTLS, tt.processes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolveDomainTLS() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTLSSecretName(t *testing.T) {
+	tests := []struct {
+		name   string
+		domain string
+		want   string
+	}{
+		{
+			name:   "should name the secret after the domain",
+			domain: "shop.example.com",
+			want:   "shop-shop-example-com-951623a2-tls",
+		},
+		{
+			name:   "should ignore the case of the domain",
+			domain: "Shop.Example.com",
+			want:   "shop-shop-example-com-951623a2-tls",
+		},
+		{
+			name:   "should not collide with a dashed domain",
+			domain: "shop-example.com",
+			want:   "shop-shop-example-com-fc7d9eba-tls",
+		},
+		{
+			name:   "should not collide with a wildcard domain",
+			domain: "wildcard.example.com",
+			want:   "shop-wildcard-example-com-b80722aa-tls",
+		},
+		{
+			name:   "should name wildcard domains",
+			domain: "*.example.com",
+			want:   "shop-wildcard-example-com-47287a8f-tls",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TLSSecretName("shop", tt.domain); got != tt.want {
+				t.Errorf("TLSSecretName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTLSIssuerAnnotations(t *testing.T) {
+	tests := []struct {
+		name    string
+		domains []DomainTLS
+		want    map[string]string
+	}{
+		{
+			name:    "no issuer",
+			domains: []DomainTLS{{Host: "shop.example.com", SecretName: "shop-cert"}},
+			want:    map[string]string{},
+		},
+		{
+			name: "cluster issuer",
+			domains: []DomainTLS{
+				{Host: "shop.example.com", SecretName: "shop-cert"},
+				{Host: "api.example.com", Issuer: "letsencrypt", IssuerKind: "ClusterIssuer"},
+			},
+			want: map[string]string{"cert-manager.io/cluster-issuer": "letsencrypt"},
+		},
+		{
+			name:    "namespaced issuer",
+			domains: []DomainTLS{{Host: "shop.example.com", Issuer: "ca", IssuerKind: "Issuer"}},
+			want:    map[string]string{"cert-manager.io/issuer": "ca"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TLSIssuerAnnotations(tt.domains); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TLSIssuerAnnotations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIssuedTLSSecretNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		domains []DomainTLS
+		want    []string
+	}{
+		{
+			name:    "no domains",
+			domains: []DomainTLS{},
+			want:    []string{},
+		},
+		{
+			name: "only issued certificates",
+			domains: []DomainTLS{
+				{Host: "api.example.com", SecretName: "shop-api-example-com-tls", Issuer: "letsencrypt"},
+				{Host: "shop.example.com", SecretName: "shop-cert"},
+			},
+			want: []string{"shop-api-example-com-tls"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IssuedTLSSecretNames(tt.domains); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("IssuedTLSSecretNames() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
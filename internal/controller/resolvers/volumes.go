@@ -0,0 +1,91 @@
+package resolvers
+
+import (
+	"fmt"
+
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// VolumeClaimName returns the name of the PersistentVolumeClaim of a volume
+// of an application.
+func VolumeClaimName(appName, volumeName string) string {
+	return fmt.Sprintf("%s-%s", appName, volumeName)
+}
+
+// BuildVolumeClaimSpec builds the PersistentVolumeClaim spec of a volume.
+func BuildVolumeClaimSpec(volume v1alpha1.ApplicationVolume) corev1.PersistentVolumeClaimSpec {
+	return corev1.PersistentVolumeClaimSpec{
+		AccessModes:      []corev1.PersistentVolumeAccessMode{volumeAccessMode(volume)},
+		StorageClassName: volume.StorageClassName,
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceStorage: volume.Size.DeepCopy(),
+			},
+		},
+	}
+}
+
+// BuildVolumes returns the pod volumes and the container mounts of the
+// volumes of an application.
+func BuildVolumes(appName string, volumes []v1alpha1.ApplicationVolume) ([]corev1.Volume, []corev1.VolumeMount) {
+	if len(volumes) == 0 {
+		return nil, nil
+	}
+
+	podVolumes := make([]corev1.Volume, 0, len(volumes))
+	mounts := make([]corev1.VolumeMount, 0, len(volumes))
+	for _, volume := range volumes {
+		podVolumes = append(podVolumes, corev1.Volume{
+			Name: volume.Name,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: VolumeClaimName(appName, volume.Name),
+					ReadOnly:  volumeAccessMode(volume) == corev1.ReadOnlyMany,
+				},
+			},
+		})
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      volume.Name,
+			MountPath: volume.MountPath,
+			ReadOnly:  volumeAccessMode(volume) == corev1.ReadOnlyMany,
+		})
+	}
+
+	return podVolumes, mounts
+}
+
+// BuildDeploymentStrategy returns the rollout strategy of the deployments
+// mounting the volumes. Volumes that can only be mounted by the pods of a
+// single node would block a rolling update whose new pods are scheduled on
+// another node, so the pods are recreated instead.
+func BuildDeploymentStrategy(volumes []v1alpha1.ApplicationVolume) appsv1.DeploymentStrategy {
+	for _, volume := range volumes {
+		switch volumeAccessMode(volume) {
+		case corev1.ReadWriteOnce, corev1.ReadWriteOncePod:
+			return appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}
+		}
+	}
+
+	// The rolling update parameters are the defaults of the API server. They
+	// are set explicitly so they are removed when switching to Recreate.
+	maxUnavailable := intstr.FromString("25%")
+	maxSurge := intstr.FromString("25%")
+	return appsv1.DeploymentStrategy{
+		Type: appsv1.RollingUpdateDeploymentStrategyType,
+		RollingUpdate: &appsv1.RollingUpdateDeployment{
+			MaxUnavailable: &maxUnavailable,
+			MaxSurge:       &maxSurge,
+		},
+	}
+}
+
+func volumeAccessMode(volume v1alpha1.ApplicationVolume) corev1.PersistentVolumeAccessMode {
+	if volume.AccessMode == "" {
+		return corev1.ReadWriteOnce
+	}
+
+	return volume.AccessMode
+}
@@ -0,0 +1,102 @@
+package resolvers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/perfectmak/k4indie/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestBuildVolumeClaimSpec(t *testing.T) {
+	storageClassName := "do-block-storage"
+	volume := v1alpha1.ApplicationVolume{
+		Name:             "data",
+		MountPath:        "/data",
+		Size:             resource.MustParse("5Gi"),
+		StorageClassName: &storageClassName,
+	}
+
+	want := corev1.PersistentVolumeClaimSpec{
+		AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+		StorageClassName: &storageClassName,
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceStorage: resource.MustParse("5Gi"),
+			},
+		},
+	}
+	if got := BuildVolumeClaimSpec(volume); !reflect.DeepEqual(got, want) {
+		t.Errorf("BuildVolumeClaimSpec() = %v, want %v", got, want)
+	}
+}
+
+func TestBuildVolumes(t *testing.T) {
+	volumes := []v1alpha1.ApplicationVolume{
+		{Name: "data", MountPath: "/data", Size: resource.MustParse("1Gi")},
+		{Name: "assets", MountPath: "/assets", Size: resource.MustParse("1Gi"), AccessMode: corev1.ReadOnlyMany},
+	}
+
+	wantVolumes := []corev1.Volume{
+		{
+			Name: "data",
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "shop-data"},
+			},
+		},
+		{
+			Name: "assets",
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "shop-assets", ReadOnly: true},
+			},
+		},
+	}
+	wantMounts := []corev1.VolumeMount{
+		{Name: "data", MountPath: "/data"},
+		{Name: "assets", MountPath: "/assets", ReadOnly: true},
+	}
+
+	gotVolumes, gotMounts := BuildVolumes("shop", volumes)
+	if !reflect.DeepEqual(gotVolumes, wantVolumes) {
+		t.Errorf("BuildVolumes() volumes = %v, want %v", gotVolumes, wantVolumes)
+	}
+	if !reflect.DeepEqual(gotMounts, wantMounts) {
+		t.Errorf("BuildVolumes() mounts = %v, want %v", gotMounts, wantMounts)
+	}
+}
+
+func TestBuildDeploymentStrategy(t *testing.T) {
+	tests := []struct {
+		name    string
+		volumes []v1alpha1.ApplicationVolume
+		want    appsv1.DeploymentStrategyType
+	}{
+		{
+			name: "should roll out without volumes",
+			want: appsv1.RollingUpdateDeploymentStrategyType,
+		},
+		{
+			name:    "should roll out with shared volumes",
+			volumes: []v1alpha1.ApplicationVolume{{Name: "uploads", AccessMode: corev1.ReadWriteMany}},
+			want:    appsv1.RollingUpdateDeploymentStrategyType,
+		},
+		{
+			name:    "should recreate with single node volumes",
+			volumes: []v1alpha1.ApplicationVolume{{Name: "uploads", AccessMode: corev1.ReadWriteMany}, {Name: "data"}},
+			want:    appsv1.RecreateDeploymentStrategyType,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := BuildDeploymentStrategy(tt.volumes)
+			if got.Type != tt.want {
+				t.Errorf("BuildDeploymentStrategy() = %v, want %v", got.Type, tt.want)
+			}
+			if (got.RollingUpdate != nil) != (tt.want == appsv1.RollingUpdateDeploymentStrategyType) {
+				t.Errorf("BuildDeploymentStrategy() rollingUpdate = %v, want only for rolling updates", got.RollingUpdate)
+			}
+		})
+	}
+}